@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+func appsCommand() *cobra.Command {
+	appsCmd := &cobra.Command{
+		Use:   "apps",
+		Short: "Manage apps on devices",
+	}
+
+	installCmd := &cobra.Command{
+		Use:   "install <app-file>",
+		Short: "Upload and install an app file on a device",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			udid, _ := cmd.Flags().GetString("device")
+			if udid == "" {
+				return fmt.Errorf("Please provide the device UDID via the --device flag")
+			}
+
+			appFile := args[0]
+			if _, err := os.Stat(appFile); err != nil {
+				return fmt.Errorf("Failed to access app file `%s` - %s", appFile, err)
+			}
+
+			client, err := hubClientFor(cmd)
+			if err != nil {
+				return err
+			}
+
+			_, err = client.uploadFile(fmt.Sprintf("/device/%s/uploadAndInstallApp", udid), appFile)
+			if err != nil {
+				return err
+			}
+			return printMessage(cmd, fmt.Sprintf("Installed `%s` on device `%s`", filepath.Base(appFile), udid))
+		},
+	}
+	installCmd.Flags().String("device", "", "UDID of the device to install the app on")
+
+	appsCmd.AddCommand(installCmd)
+	return appsCmd
+}
@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// Commands returns the admin CLI commands that talk to a running hub over its REST API
+func Commands() []*cobra.Command {
+	commands := []*cobra.Command{
+		devicesCommand(),
+		providersCommand(),
+		usersCommand(),
+		logsCommand(),
+		appsCommand(),
+	}
+
+	for _, command := range commands {
+		command.PersistentFlags().String("hub", "", "The address of the GADS hub instance, can also be set with GADS_HUB")
+		command.PersistentFlags().String("token", "", "Hub session token, can also be set with GADS_TOKEN")
+		command.PersistentFlags().String("username", "", "Username to authenticate with if no token is provided, can also be set with GADS_USERNAME")
+		command.PersistentFlags().String("password", "", "Password to authenticate with if no token is provided, can also be set with GADS_PASSWORD")
+		command.PersistentFlags().StringP("output", "o", "table", "Output format - table or json")
+	}
+
+	return commands
+}
+
+// Create a hub client for the command being executed
+// Usage is not printed for errors after this point since they are not caused by wrong arguments
+func hubClientFor(cmd *cobra.Command) (*HubClient, error) {
+	cmd.SilenceUsage = true
+	return newHubClient(cmd.Flags())
+}
@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// HubClient is a thin wrapper around the hub REST API used by the admin CLI commands
+type HubClient struct {
+	Address    string
+	Token      string
+	httpClient *http.Client
+}
+
+// Create a hub client from the persistent CLI flags, falling back to environment variables
+// If no token is provided but username and password are, a new session is created on the hub
+func newHubClient(flags *pflag.FlagSet) (*HubClient, error) {
+	hubAddress := flagOrEnv(flags, "hub", "GADS_HUB")
+	token := flagOrEnv(flags, "token", "GADS_TOKEN")
+	username := flagOrEnv(flags, "username", "GADS_USERNAME")
+	password := flagOrEnv(flags, "password", "GADS_PASSWORD")
+
+	if hubAddress == "" {
+		return nil, fmt.Errorf("Please provide the GADS hub address via the --hub flag or GADS_HUB env variable, e.g. --hub=http://192.168.1.6:10000")
+	}
+	if !strings.HasPrefix(hubAddress, "http://") && !strings.HasPrefix(hubAddress, "https://") {
+		hubAddress = "http://" + hubAddress
+	}
+
+	client := &HubClient{
+		Address: strings.TrimSuffix(hubAddress, "/"),
+		Token:   token,
+		httpClient: &http.Client{
+			Timeout: 5 * time.Minute,
+		},
+	}
+
+	if client.Token == "" {
+		if username == "" || password == "" {
+			return nil, fmt.Errorf("Please provide an auth token via --token/GADS_TOKEN or credentials via --username/--password (GADS_USERNAME/GADS_PASSWORD)")
+		}
+		err := client.login(username, password)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return client, nil
+}
+
+func flagOrEnv(flags *pflag.FlagSet, flagName, envName string) string {
+	value, _ := flags.GetString(flagName)
+	if value == "" {
+		value = os.Getenv(envName)
+	}
+	return value
+}
+
+func (c *HubClient) login(username, password string) error {
+	var loginResponse struct {
+		SessionID string `json:"sessionID"`
+	}
+	err := c.sendJSON(http.MethodPost, "/authenticate", map[string]string{"username": username, "password": password}, &loginResponse)
+	if err != nil {
+		return fmt.Errorf("Failed to authenticate with the hub - %s", err)
+	}
+	c.Token = loginResponse.SessionID
+	return nil
+}
+
+// Execute a request against the hub and return the response body
+// Any non-2xx response is returned as an error with the message provided by the hub if available
+func (c *HubClient) do(method, path string, body io.Reader, contentType string) ([]byte, error) {
+	req, err := http.NewRequest(method, c.Address+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create request - %s", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.Token != "" {
+		req.Header.Set("X-Auth-Token", c.Token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to execute request to `%s` - %s", req.URL, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read response body - %s", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var errorResponse struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(respBody, &errorResponse) == nil && errorResponse.Error != "" {
+			return nil, fmt.Errorf("%s %s returned %v - %s", method, path, resp.StatusCode, errorResponse.Error)
+		}
+		return nil, fmt.Errorf("%s %s returned %v - %s", method, path, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	return respBody, nil
+}
+
+func (c *HubClient) getJSON(path string, out interface{}) error {
+	respBody, err := c.do(http.MethodGet, path, nil, "")
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+// Send a JSON payload and optionally unmarshal the response in `out`
+func (c *HubClient) sendJSON(method, path string, payload interface{}, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		payloadJSON, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("Failed to marshal request payload - %s", err)
+		}
+		body = bytes.NewReader(payloadJSON)
+	}
+
+	respBody, err := c.do(method, path, body, "application/json")
+	if err != nil {
+		return err
+	}
+	if out != nil && len(respBody) > 0 {
+		return json.Unmarshal(respBody, out)
+	}
+	return nil
+}
+
+// Upload a file as multipart form data in the `file` field
+func (c *HubClient) uploadFile(path, filePath string) ([]byte, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open file `%s` - %s", filePath, err)
+	}
+	defer file.Close()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filepath.Base(filePath))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create form file - %s", err)
+	}
+	if _, err = io.Copy(part, file); err != nil {
+		return nil, fmt.Errorf("Failed to read file `%s` - %s", filePath, err)
+	}
+	writer.Close()
+
+	return c.do(http.MethodPost, path, &body, writer.FormDataContentType())
+}
@@ -0,0 +1,263 @@
+package cli
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"GADS/common/models"
+
+	"github.com/spf13/cobra"
+)
+
+type adminDeviceData struct {
+	Devices   []*models.Device `json:"devices"`
+	Providers []string         `json:"providers"`
+}
+
+func devicesCommand() *cobra.Command {
+	devicesCmd := &cobra.Command{
+		Use:   "devices",
+		Short: "Manage devices through the hub",
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List all devices registered in the hub",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := hubClientFor(cmd)
+			if err != nil {
+				return err
+			}
+
+			var deviceData adminDeviceData
+			err = client.getJSON("/admin/devices", &deviceData)
+			if err != nil {
+				return err
+			}
+
+			var rows [][]string
+			for _, device := range deviceData.Devices {
+				rows = append(rows, deviceRow(device))
+			}
+			return printOutput(cmd, deviceData.Devices, deviceHeaders, rows)
+		},
+	}
+
+	getCmd := &cobra.Command{
+		Use:   "get <udid>",
+		Short: "Get a device by UDID",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := hubClientFor(cmd)
+			if err != nil {
+				return err
+			}
+
+			device, err := getDevice(client, args[0])
+			if err != nil {
+				return err
+			}
+
+			// Try to get the live device data from its provider, if it fails we just show the DB data
+			var liveDevice models.Device
+			if client.getJSON(fmt.Sprintf("/device/%s/info", device.UDID), &liveDevice) == nil {
+				liveDevice.Usage = device.Usage
+				liveDevice.Tags = device.Tags
+				device = &liveDevice
+			}
+
+			headers := slices.Concat(deviceHeaders, []string{"CONNECTED", "STATE", "QUARANTINED"})
+			row := append(deviceRow(device), fmt.Sprintf("%v", device.Connected), device.ProviderState, fmt.Sprintf("%v", device.Quarantined))
+			return printOutput(cmd, device, headers, [][]string{row})
+		},
+	}
+
+	updateCmd := &cobra.Command{
+		Use:   "update <udid>",
+		Short: "Update the name, usage, tags or provider of a device",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := hubClientFor(cmd)
+			if err != nil {
+				return err
+			}
+
+			// The hub replaces some of the device values on update so we start from the current device data
+			device, err := getDevice(client, args[0])
+			if err != nil {
+				return err
+			}
+
+			if cmd.Flags().Changed("name") {
+				device.Name, _ = cmd.Flags().GetString("name")
+			}
+			if cmd.Flags().Changed("usage") {
+				device.Usage, _ = cmd.Flags().GetString("usage")
+			}
+			if cmd.Flags().Changed("provider") {
+				device.Provider, _ = cmd.Flags().GetString("provider")
+			}
+			if cmd.Flags().Changed("tags") {
+				device.Tags, _ = cmd.Flags().GetStringSlice("tags")
+				if device.Tags == nil {
+					device.Tags = []string{}
+				}
+			}
+
+			err = client.sendJSON(http.MethodPut, "/admin/device", device, nil)
+			if err != nil {
+				return err
+			}
+			return printMessage(cmd, fmt.Sprintf("Updated device `%s`", device.UDID))
+		},
+	}
+	updateCmd.Flags().String("name", "", "New name of the device")
+	updateCmd.Flags().String("usage", "", "New usage of the device - enabled, automation, control or disabled")
+	updateCmd.Flags().String("provider", "", "Nickname of the provider the device is assigned to")
+	updateCmd.Flags().StringSlice("tags", nil, "Comma separated list of tags, replaces the current tags")
+
+	resetCmd := &cobra.Command{
+		Use:   "reset <udid>",
+		Short: "Reset the setup of a live device on its provider",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := hubClientFor(cmd)
+			if err != nil {
+				return err
+			}
+
+			_, err = client.do(http.MethodPost, fmt.Sprintf("/device/%s/reset", args[0]), nil, "")
+			if err != nil {
+				return err
+			}
+			return printMessage(cmd, fmt.Sprintf("Initiated setup reset on device `%s`", args[0]))
+		},
+	}
+
+	pendingCmd := &cobra.Command{
+		Use:   "pending",
+		Short: "List the devices registered by their providers that wait for approval",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := hubClientFor(cmd)
+			if err != nil {
+				return err
+			}
+
+			var pendingDevices []*models.Device
+			err = client.getJSON("/admin/devices/pending", &pendingDevices)
+			if err != nil {
+				return err
+			}
+
+			var rows [][]string
+			for _, device := range pendingDevices {
+				rows = append(rows, deviceRow(device))
+			}
+			return printOutput(cmd, pendingDevices, deviceHeaders, rows)
+		},
+	}
+
+	approveCmd := &cobra.Command{
+		Use:   "approve <udid>",
+		Short: "Approve a pending device so its provider sets it up",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := hubClientFor(cmd)
+			if err != nil {
+				return err
+			}
+
+			body := make(map[string]interface{})
+			if cmd.Flags().Changed("usage") {
+				body["usage"], _ = cmd.Flags().GetString("usage")
+			}
+			if cmd.Flags().Changed("name") {
+				body["name"], _ = cmd.Flags().GetString("name")
+			}
+			if cmd.Flags().Changed("tags") {
+				body["tags"], _ = cmd.Flags().GetStringSlice("tags")
+			}
+
+			err = client.sendJSON(http.MethodPost, fmt.Sprintf("/admin/devices/%s/approve", args[0]), body, nil)
+			if err != nil {
+				return err
+			}
+			return printMessage(cmd, fmt.Sprintf("Approved device `%s`", args[0]))
+		},
+	}
+	approveCmd.Flags().String("usage", "enabled", "Usage of the approved device - enabled, automation or control")
+	approveCmd.Flags().String("name", "", "Name of the device, the name detected by the provider is kept if not provided")
+	approveCmd.Flags().StringSlice("tags", nil, "Comma separated list of tags")
+
+	rejectCmd := &cobra.Command{
+		Use:   "reject <udid>",
+		Short: "Reject a pending device, it is kept disabled so it is not registered again",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := hubClientFor(cmd)
+			if err != nil {
+				return err
+			}
+
+			err = client.sendJSON(http.MethodPost, fmt.Sprintf("/admin/devices/%s/reject", args[0]), nil, nil)
+			if err != nil {
+				return err
+			}
+			return printMessage(cmd, fmt.Sprintf("Rejected device `%s`", args[0]))
+		},
+	}
+
+	clearQuarantineCmd := &cobra.Command{
+		Use:   "clear-quarantine <udid>",
+		Short: "Clear the quarantine of a device whose setup kept failing so its provider sets it up again",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := hubClientFor(cmd)
+			if err != nil {
+				return err
+			}
+
+			err = client.sendJSON(http.MethodPost, fmt.Sprintf("/admin/devices/%s/clear-quarantine", args[0]), nil, nil)
+			if err != nil {
+				return err
+			}
+			return printMessage(cmd, fmt.Sprintf("Cleared quarantine of device `%s`", args[0]))
+		},
+	}
+
+	devicesCmd.AddCommand(listCmd, getCmd, updateCmd, resetCmd, pendingCmd, approveCmd, rejectCmd, clearQuarantineCmd)
+	return devicesCmd
+}
+
+var deviceHeaders = []string{"UDID", "NAME", "OS", "VERSION", "PROVIDER", "USAGE", "TAGS"}
+
+func deviceRow(device *models.Device) []string {
+	return []string{
+		device.UDID,
+		device.Name,
+		device.OS,
+		device.OSVersion,
+		device.Provider,
+		device.Usage,
+		strings.Join(device.Tags, ","),
+	}
+}
+
+func getDevice(client *HubClient, udid string) (*models.Device, error) {
+	var deviceData adminDeviceData
+	err := client.getJSON("/admin/devices", &deviceData)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, device := range deviceData.Devices {
+		if device.UDID == udid {
+			return device, nil
+		}
+	}
+	return nil, fmt.Errorf("Device with udid `%s` does not exist", udid)
+}
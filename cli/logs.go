@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"GADS/common/models"
+
+	"github.com/spf13/cobra"
+)
+
+func logsCommand() *cobra.Command {
+	logsCmd := &cobra.Command{
+		Use:   "logs",
+		Short: "Read logs stored by the hub",
+	}
+
+	appiumCmd := &cobra.Command{
+		Use:   "appium",
+		Short: "Get the Appium logs of a device, optionally filtered by session",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			udid, _ := cmd.Flags().GetString("device")
+			sessionID, _ := cmd.Flags().GetString("session")
+			limit, _ := cmd.Flags().GetInt("limit")
+			if udid == "" {
+				return fmt.Errorf("Please provide the device UDID via the --device flag")
+			}
+
+			client, err := hubClientFor(cmd)
+			if err != nil {
+				return err
+			}
+
+			query := url.Values{}
+			query.Set("collection", udid)
+			path := "/appium-logs"
+			if sessionID != "" {
+				query.Set("session", sessionID)
+				path = "/appium-session-logs"
+			} else {
+				query.Set("logLimit", fmt.Sprintf("%v", limit))
+			}
+
+			var logs []models.AppiumLog
+			err = client.getJSON(path+"?"+query.Encode(), &logs)
+			if err != nil {
+				return err
+			}
+
+			// The hub returns the newest logs first, print them in chronological order
+			var rows [][]string
+			for i := len(logs) - 1; i >= 0; i-- {
+				rows = append(rows, []string{
+					time.UnixMilli(logs[i].SystemTS).Format(time.RFC3339),
+					logs[i].Type,
+					logs[i].SessionID,
+					logs[i].Message,
+				})
+			}
+			return printOutput(cmd, logs, []string{"TIME", "TYPE", "SESSION", "MESSAGE"}, rows)
+		},
+	}
+	appiumCmd.Flags().String("device", "", "UDID of the device to get logs for")
+	appiumCmd.Flags().String("session", "", "Appium session ID to filter the logs by")
+	appiumCmd.Flags().Int("limit", 100, "Maximum number of log lines to get when not filtering by session, up to 1000")
+
+	logsCmd.AddCommand(appiumCmd)
+	return logsCmd
+}
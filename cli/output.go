@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// Print the raw data as JSON or a table built from the provided headers and rows depending on the --output flag
+func printOutput(cmd *cobra.Command, data interface{}, headers []string, rows [][]string) error {
+	output, _ := cmd.Flags().GetString("output")
+
+	switch output {
+	case "json":
+		jsonData, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return fmt.Errorf("Failed to marshal output to json - %s", err)
+		}
+		fmt.Println(string(jsonData))
+	case "table", "":
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, strings.Join(headers, "\t"))
+		for _, row := range rows {
+			fmt.Fprintln(writer, strings.Join(row, "\t"))
+		}
+		writer.Flush()
+	default:
+		return fmt.Errorf("Unsupported output format `%s`, use `table` or `json`", output)
+	}
+	return nil
+}
+
+// Print a plain message, or the message wrapped in a JSON object if JSON output is requested
+func printMessage(cmd *cobra.Command, message string) error {
+	output, _ := cmd.Flags().GetString("output")
+	if output == "json" {
+		return printOutput(cmd, map[string]string{"message": message}, nil, nil)
+	}
+	fmt.Println(message)
+	return nil
+}
@@ -0,0 +1,182 @@
+package cli
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"GADS/common/models"
+
+	"github.com/spf13/cobra"
+)
+
+func providersCommand() *cobra.Command {
+	providersCmd := &cobra.Command{
+		Use:   "providers",
+		Short: "Manage provider configurations through the hub",
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List all provider configurations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := hubClientFor(cmd)
+			if err != nil {
+				return err
+			}
+
+			var providers []models.Provider
+			err = client.getJSON("/admin/providers", &providers)
+			if err != nil {
+				return err
+			}
+
+			var rows [][]string
+			for _, provider := range providers {
+				lastUpdated := "never"
+				if provider.LastUpdatedTimestamp != 0 {
+					lastUpdated = time.UnixMilli(provider.LastUpdatedTimestamp).Format(time.RFC3339)
+				}
+				rows = append(rows, []string{
+					provider.Nickname,
+					provider.OS,
+					provider.HostAddress,
+					fmt.Sprintf("%v", provider.Port),
+					fmt.Sprintf("%v", provider.ProvideAndroid),
+					fmt.Sprintf("%v", provider.ProvideIOS),
+					fmt.Sprintf("%v", len(provider.ProvidedDevices)),
+					lastUpdated,
+				})
+			}
+			return printOutput(cmd, providers, []string{"NICKNAME", "OS", "HOST", "PORT", "ANDROID", "IOS", "DEVICES", "LAST UPDATED"}, rows)
+		},
+	}
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the liveness status of all providers",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := hubClientFor(cmd)
+			if err != nil {
+				return err
+			}
+
+			var statuses []models.ProviderLiveness
+			err = client.getJSON("/admin/providers/status", &statuses)
+			if err != nil {
+				return err
+			}
+
+			var rows [][]string
+			for _, status := range statuses {
+				lastSeen := "never"
+				if status.LastSeen != 0 {
+					lastSeen = time.UnixMilli(status.LastSeen).Format(time.RFC3339)
+				}
+				rows = append(rows, []string{
+					status.Nickname,
+					string(status.Status),
+					time.UnixMilli(status.StatusSince).Format(time.RFC3339),
+					lastSeen,
+					fmt.Sprintf("%v/%v", status.ConnectedCount, status.DevicesCount),
+				})
+			}
+			return printOutput(cmd, statuses, []string{"NICKNAME", "STATUS", "SINCE", "LAST SEEN", "CONNECTED"}, rows)
+		},
+	}
+
+	addCmd := &cobra.Command{
+		Use:   "add <nickname>",
+		Short: "Add a new provider configuration",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := hubClientFor(cmd)
+			if err != nil {
+				return err
+			}
+
+			flags := cmd.Flags()
+			provider := models.Provider{Nickname: args[0]}
+			provider.OS, _ = flags.GetString("os")
+			provider.HostAddress, _ = flags.GetString("host-address")
+			provider.Port, _ = flags.GetInt("port")
+			provider.ProvideAndroid, _ = flags.GetBool("provide-android")
+			provider.ProvideIOS, _ = flags.GetBool("provide-ios")
+			provider.WdaBundleID, _ = flags.GetString("wda-bundle-id")
+			provider.WdaRepoPath, _ = flags.GetString("wda-repo-path")
+			provider.SupervisionPassword, _ = flags.GetString("supervision-password")
+			provider.SeleniumGrid, _ = flags.GetString("selenium-grid")
+			provider.UseSeleniumGrid = provider.SeleniumGrid != ""
+			provider.UseGadsIosStream, _ = flags.GetBool("use-gads-ios-stream")
+			provider.UseCustomWDA, _ = flags.GetBool("use-custom-wda")
+			provider.NetworkDevices, _ = flags.GetStringSlice("network-devices")
+
+			err = client.sendJSON(http.MethodPost, "/admin/providers/add", provider, nil)
+			if err != nil {
+				return err
+			}
+			return printMessage(cmd, fmt.Sprintf("Added provider `%s`", provider.Nickname))
+		},
+	}
+	addCmd.Flags().String("os", "", "OS of the provider host - linux, darwin or windows")
+	addCmd.Flags().String("host-address", "", "IP address of the provider host")
+	addCmd.Flags().Int("port", 0, "Port on which the provider will run")
+	addCmd.Flags().Bool("provide-android", false, "Provide Android devices")
+	addCmd.Flags().Bool("provide-ios", false, "Provide iOS devices")
+	addCmd.Flags().String("wda-bundle-id", "", "WebDriverAgent bundle ID, required for iOS on Linux/Windows")
+	addCmd.Flags().String("wda-repo-path", "", "Path to the WebDriverAgent repo, required for iOS on macOS")
+	addCmd.Flags().String("supervision-password", "", "Password of the supervision.p12 file if devices are supervised")
+	addCmd.Flags().String("selenium-grid", "", "Selenium Grid address to register device nodes to, enables Selenium Grid usage")
+	addCmd.Flags().Bool("use-gads-ios-stream", false, "Use the GADS iOS stream instead of the WebDriverAgent stream")
+	addCmd.Flags().Bool("use-custom-wda", false, "The provider uses the custom GADS WebDriverAgent")
+	addCmd.Flags().StringSlice("network-devices", nil, "Comma separated host:port targets of network-attached Android devices to keep connected with adb")
+
+	deleteCmd := &cobra.Command{
+		Use:   "delete <nickname>",
+		Short: "Delete a provider configuration",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := hubClientFor(cmd)
+			if err != nil {
+				return err
+			}
+
+			_, err = client.do(http.MethodDelete, fmt.Sprintf("/admin/providers/%s", args[0]), nil, "")
+			if err != nil {
+				return err
+			}
+			return printMessage(cmd, fmt.Sprintf("Deleted provider `%s`", args[0]))
+		},
+	}
+
+	emulatorsCmd := &cobra.Command{
+		Use:   "emulators",
+		Short: "Start and stop the configured emulators of a provider",
+	}
+	for _, action := range []string{"start", "stop"} {
+		emulatorsCmd.AddCommand(&cobra.Command{
+			Use:   fmt.Sprintf("%s <nickname> <avd>", action),
+			Short: fmt.Sprintf("%s a configured emulator on a connected provider", strings.ToUpper(action[:1])+action[1:]),
+			Args:  cobra.ExactArgs(2),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				client, err := hubClientFor(cmd)
+				if err != nil {
+					return err
+				}
+
+				var response map[string]string
+				err = client.sendJSON(http.MethodPost, fmt.Sprintf("/admin/providers/%s/emulators/%s/%s", args[0], args[1], action), nil, &response)
+				if err != nil {
+					return err
+				}
+				return printMessage(cmd, response["message"])
+			},
+		})
+	}
+
+	providersCmd.AddCommand(listCmd, statusCmd, addCmd, deleteCmd, emulatorsCmd)
+	return providersCmd
+}
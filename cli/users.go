@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"fmt"
+	"net/http"
+
+	"GADS/common/models"
+
+	"github.com/spf13/cobra"
+)
+
+func usersCommand() *cobra.Command {
+	usersCmd := &cobra.Command{
+		Use:   "users",
+		Short: "Manage hub users",
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List all users",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := hubClientFor(cmd)
+			if err != nil {
+				return err
+			}
+
+			var users []models.User
+			err = client.getJSON("/admin/users", &users)
+			if err != nil {
+				return err
+			}
+
+			var rows [][]string
+			for _, user := range users {
+				rows = append(rows, []string{user.Username, user.Role})
+			}
+			return printOutput(cmd, users, []string{"USERNAME", "ROLE"}, rows)
+		},
+	}
+
+	addCmd := &cobra.Command{
+		Use:   "add <username>",
+		Short: "Add a new user",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			userPassword, _ := cmd.Flags().GetString("user-password")
+			role, _ := cmd.Flags().GetString("role")
+			if userPassword == "" {
+				return fmt.Errorf("Please provide the new user password via the --user-password flag")
+			}
+
+			client, err := hubClientFor(cmd)
+			if err != nil {
+				return err
+			}
+
+			user := models.User{Username: args[0], Password: userPassword, Role: role}
+			err = client.sendJSON(http.MethodPost, "/admin/user", user, nil)
+			if err != nil {
+				return err
+			}
+			return printMessage(cmd, fmt.Sprintf("Added user `%s`", user.Username))
+		},
+	}
+	addCmd.Flags().String("user-password", "", "Password of the new user")
+	addCmd.Flags().String("role", "user", "Role of the new user - admin or user")
+
+	deleteCmd := &cobra.Command{
+		Use:   "delete <username>",
+		Short: "Delete a user",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if args[0] == "admin" {
+				return fmt.Errorf("The default `admin` user cannot be deleted")
+			}
+
+			client, err := hubClientFor(cmd)
+			if err != nil {
+				return err
+			}
+
+			_, err = client.do(http.MethodDelete, fmt.Sprintf("/admin/user/%s", args[0]), nil, "")
+			if err != nil {
+				return err
+			}
+			return printMessage(cmd, fmt.Sprintf("Deleted user `%s`", args[0]))
+		},
+	}
+
+	passwdCmd := &cobra.Command{
+		Use:   "passwd <username>",
+		Short: "Change the password of a user",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			userPassword, _ := cmd.Flags().GetString("user-password")
+			if userPassword == "" {
+				return fmt.Errorf("Please provide the new password via the --user-password flag")
+			}
+
+			client, err := hubClientFor(cmd)
+			if err != nil {
+				return err
+			}
+
+			user := models.User{Username: args[0], Password: userPassword}
+			err = client.sendJSON(http.MethodPut, "/admin/user", user, nil)
+			if err != nil {
+				return err
+			}
+			return printMessage(cmd, fmt.Sprintf("Changed password of user `%s`", user.Username))
+		},
+	}
+	passwdCmd.Flags().String("user-password", "", "The new password of the user")
+
+	usersCmd.AddCommand(listCmd, addCmd, deleteCmd, passwdCmd)
+	return usersCmd
+}
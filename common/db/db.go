@@ -216,6 +216,19 @@ func AddOrUpdateProvider(provider models.Provider) error {
 	return nil
 }
 
+func SetProviderHubToken(nickname, token string) error {
+	update := bson.M{
+		"$set": bson.M{"hub_token": token},
+	}
+	coll := mongoClient.Database("gads").Collection("providers")
+	filter := bson.D{{Key: "nickname", Value: nickname}}
+	_, err := coll.UpdateOne(mongoClientCtx, filter, update)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
 func GetDBDevices() []models.Device {
 	var dbDevices []models.Device
 	// Access the database and collection
@@ -326,6 +339,19 @@ func UpsertDeviceDB(device *models.Device) error {
 	return nil
 }
 
+func SetDeviceTypeDB(udid, deviceType string) error {
+	update := bson.M{
+		"$set": bson.M{"device_type": deviceType},
+	}
+	coll := mongoClient.Database("gads").Collection("new_devices")
+	filter := bson.D{{Key: "udid", Value: udid}}
+	_, err := coll.UpdateOne(mongoClientCtx, filter, update)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
 func DeleteDeviceDB(udid string) error {
 	coll := mongoClient.Database("gads").Collection("new_devices")
 	filter := bson.M{"udid": udid}
@@ -433,3 +459,163 @@ func UploadFileGridFS(file io.Reader, fileName string, force bool) error {
 		return nil
 	}
 }
+
+func GetWebhooks() []models.Webhook {
+	var webhooks []models.Webhook
+	collection := mongoClient.Database("gads").Collection("webhooks")
+
+	cursor, err := collection.Find(mongoClientCtx, bson.D{{}}, nil)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"event": "get_db_webhooks",
+		}).Error(fmt.Sprintf("Could not get db cursor when trying to get webhooks from db - %s", err))
+		return webhooks
+	}
+	defer cursor.Close(mongoClientCtx)
+
+	if err := cursor.All(mongoClientCtx, &webhooks); err != nil {
+		log.WithFields(log.Fields{
+			"event": "get_db_webhooks",
+		}).Error(fmt.Sprintf("Could not get webhooks from db cursor - %s", err))
+	}
+
+	return webhooks
+}
+
+func AddOrUpdateWebhook(webhook models.Webhook) error {
+	update := bson.M{
+		"$set": webhook,
+	}
+	coll := mongoClient.Database("gads").Collection("webhooks")
+	filter := bson.D{{Key: "name", Value: webhook.Name}}
+	opts := options.Update().SetUpsert(true)
+	_, err := coll.UpdateOne(mongoClientCtx, filter, update, opts)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func DeleteWebhookDB(name string) error {
+	coll := mongoClient.Database("gads").Collection("webhooks")
+	filter := bson.M{"name": name}
+
+	result, err := coll.DeleteOne(mongoClientCtx, filter)
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func AddWebhookDelivery(delivery models.WebhookDelivery) error {
+	coll := mongoClient.Database("gads").Collection("webhook_deliveries")
+	_, err := coll.InsertOne(mongoClientCtx, delivery)
+	return err
+}
+
+// Get the latest webhook deliveries, newest first, optionally only for a single webhook
+func GetWebhookDeliveries(webhookName string, limit int64) ([]models.WebhookDelivery, error) {
+	deliveries := []models.WebhookDelivery{}
+	coll := mongoClient.Database("gads").Collection("webhook_deliveries")
+
+	filter := bson.D{}
+	if webhookName != "" {
+		filter = bson.D{{Key: "webhook", Value: webhookName}}
+	}
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "ts", Value: -1}})
+	findOptions.SetLimit(limit)
+
+	cursor, err := coll.Find(mongoClientCtx, filter, findOptions)
+	if err != nil {
+		return deliveries, err
+	}
+	defer cursor.Close(mongoClientCtx)
+
+	err = cursor.All(mongoClientCtx, &deliveries)
+	return deliveries, err
+}
+
+func GetMaintenanceWindows() []models.MaintenanceWindow {
+	windows := []models.MaintenanceWindow{}
+	collection := mongoClient.Database("gads").Collection("maintenance_windows")
+
+	cursor, err := collection.Find(mongoClientCtx, bson.D{{}}, nil)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"event": "get_db_maintenance_windows",
+		}).Error(fmt.Sprintf("Could not get db cursor when trying to get maintenance windows from db - %s", err))
+		return windows
+	}
+	defer cursor.Close(mongoClientCtx)
+
+	if err := cursor.All(mongoClientCtx, &windows); err != nil {
+		log.WithFields(log.Fields{
+			"event": "get_db_maintenance_windows",
+		}).Error(fmt.Sprintf("Could not get maintenance windows from db cursor - %s", err))
+	}
+
+	return windows
+}
+
+func AddOrUpdateMaintenanceWindow(window models.MaintenanceWindow) error {
+	update := bson.M{
+		"$set": window,
+	}
+	coll := mongoClient.Database("gads").Collection("maintenance_windows")
+	filter := bson.D{{Key: "name", Value: window.Name}}
+	opts := options.Update().SetUpsert(true)
+	_, err := coll.UpdateOne(mongoClientCtx, filter, update, opts)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func DeleteMaintenanceWindowDB(name string) error {
+	coll := mongoClient.Database("gads").Collection("maintenance_windows")
+	filter := bson.M{"name": name}
+
+	result, err := coll.DeleteOne(mongoClientCtx, filter)
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func AddMaintenanceRun(run models.MaintenanceRun) error {
+	coll := mongoClient.Database("gads").Collection("maintenance_runs")
+	_, err := coll.InsertOne(mongoClientCtx, run)
+	return err
+}
+
+// Get the latest device maintenance runs, newest first, optionally only for a single device
+func GetMaintenanceRuns(udid string, limit int64) ([]models.MaintenanceRun, error) {
+	runs := []models.MaintenanceRun{}
+	coll := mongoClient.Database("gads").Collection("maintenance_runs")
+
+	filter := bson.D{}
+	if udid != "" {
+		filter = bson.D{{Key: "udid", Value: udid}}
+	}
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "started_at", Value: -1}})
+	findOptions.SetLimit(limit)
+
+	cursor, err := coll.Find(mongoClientCtx, filter, findOptions)
+	if err != nil {
+		return runs, err
+	}
+	defer cursor.Close(mongoClientCtx)
+
+	err = cursor.All(mongoClientCtx, &runs)
+	return runs, err
+}
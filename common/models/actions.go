@@ -0,0 +1,120 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"unicode/utf8"
+)
+
+// Raw W3C WebDriver action sequences as accepted by the `actions` endpoint of Appium and WebDriverAgent
+type W3CActions struct {
+	Actions []W3CActionSequence `json:"actions"`
+}
+
+type W3CActionSequence struct {
+	Type       string                  `json:"type"` // pointer, key or none
+	ID         string                  `json:"id"`
+	Parameters *DeviceActionParameters `json:"parameters,omitempty"`
+	Actions    []W3CAction             `json:"actions"`
+}
+
+// Coordinates are pointers so a `0` coordinate is still sent
+type W3CAction struct {
+	Type     string   `json:"type"`
+	Duration int      `json:"duration,omitempty"`
+	X        *float64 `json:"x,omitempty"`
+	Y        *float64 `json:"y,omitempty"`
+	Origin   string   `json:"origin,omitempty"`
+	Button   int      `json:"button,omitempty"`
+	Value    string   `json:"value,omitempty"`
+}
+
+// Limits that keep a single request from occupying a device for too long
+const (
+	MaxW3CActionSequences = 10
+	MaxW3CActions         = 2000
+	MaxW3CActionDuration  = 60000
+	MaxW3CTotalDuration   = 60000
+)
+
+var w3cActionTypes = map[string][]string{
+	"pointer": {"pause", "pointerMove", "pointerDown", "pointerUp", "pointerCancel"},
+	"key":     {"pause", "keyDown", "keyUp"},
+	"none":    {"pause"},
+}
+
+// Validate action sequences before they are sent to a device
+// Element origins are not accepted because the remote control has no element references
+func ValidateW3CActions(actions W3CActions) error {
+	if len(actions.Actions) == 0 {
+		return fmt.Errorf("missing `actions`")
+	}
+	if len(actions.Actions) > MaxW3CActionSequences {
+		return fmt.Errorf("too many action sequences %d, at most %d are allowed", len(actions.Actions), MaxW3CActionSequences)
+	}
+
+	var ids []string
+	total := 0
+	// The actions at the same index of every sequence run together as a tick that lasts as long as its longest action
+	var tickDurations []int
+	for i, sequence := range actions.Actions {
+		if sequence.ID == "" {
+			return fmt.Errorf("actions[%d]: missing `id`", i)
+		}
+		if slices.Contains(ids, sequence.ID) {
+			return fmt.Errorf("actions[%d]: duplicate id `%s`", i, sequence.ID)
+		}
+		ids = append(ids, sequence.ID)
+
+		allowedTypes, ok := w3cActionTypes[sequence.Type]
+		if !ok {
+			return fmt.Errorf("actions[%d]: invalid type `%s` - should be pointer, key or none", i, sequence.Type)
+		}
+		if sequence.Type == "pointer" && sequence.Parameters != nil && !slices.Contains([]string{"touch", "mouse", "pen"}, sequence.Parameters.PointerType) {
+			return fmt.Errorf("actions[%d]: invalid pointerType `%s` - should be touch, mouse or pen", i, sequence.Parameters.PointerType)
+		}
+
+		total += len(sequence.Actions)
+		if total > MaxW3CActions {
+			return fmt.Errorf("too many actions, at most %d are allowed", MaxW3CActions)
+		}
+		for j, action := range sequence.Actions {
+			if !slices.Contains(allowedTypes, action.Type) {
+				return fmt.Errorf("actions[%d].actions[%d]: invalid type `%s` for a %s sequence", i, j, action.Type, sequence.Type)
+			}
+			if action.Duration < 0 || action.Duration > MaxW3CActionDuration {
+				return fmt.Errorf("actions[%d].actions[%d]: duration should be between 0 and %d", i, j, MaxW3CActionDuration)
+			}
+			if j == len(tickDurations) {
+				tickDurations = append(tickDurations, 0)
+			}
+			tickDurations[j] = max(tickDurations[j], action.Duration)
+			switch action.Type {
+			case "pointerMove":
+				if action.X == nil || action.Y == nil {
+					return fmt.Errorf("actions[%d].actions[%d]: pointerMove needs `x` and `y`", i, j)
+				}
+				if action.Origin != "" && action.Origin != "viewport" && action.Origin != "pointer" {
+					return fmt.Errorf("actions[%d].actions[%d]: invalid origin `%s` - should be viewport or pointer", i, j, action.Origin)
+				}
+			case "pointerDown", "pointerUp":
+				if action.Button < 0 {
+					return fmt.Errorf("actions[%d].actions[%d]: invalid button %d", i, j, action.Button)
+				}
+			case "keyDown", "keyUp":
+				if utf8.RuneCountInString(action.Value) != 1 {
+					return fmt.Errorf("actions[%d].actions[%d]: `value` should be a single key", i, j)
+				}
+			}
+		}
+	}
+
+	totalDuration := 0
+	for _, duration := range tickDurations {
+		totalDuration += duration
+	}
+	if totalDuration > MaxW3CTotalDuration {
+		return fmt.Errorf("actions take %dms, at most %dms are allowed", totalDuration, MaxW3CTotalDuration)
+	}
+	return nil
+}
@@ -6,6 +6,51 @@ type ActionData struct {
 	EndX       float64 `json:"endX,omitempty"`
 	EndY       float64 `json:"endY,omitempty"`
 	TextToType string  `json:"text,omitempty"`
+	Duration   int     `json:"duration,omitempty"` // milliseconds the pointer moves for swipes and drags
+}
+
+// Hardware keys that can be pressed on the devices, not every key is available on every OS
+type HardwareKeyData struct {
+	Key string `json:"key"`
+}
+
+const (
+	HardwareKeyHome       = "home"
+	HardwareKeyBack       = "back" // Android only
+	HardwareKeyAppSwitch  = "app_switch"
+	HardwareKeyVolumeUp   = "volume_up"
+	HardwareKeyVolumeDown = "volume_down"
+	HardwareKeyPower      = "power"
+)
+
+var HardwareKeys = []string{HardwareKeyHome, HardwareKeyBack, HardwareKeyAppSwitch, HardwareKeyVolumeUp, HardwareKeyVolumeDown, HardwareKeyPower}
+
+type OrientationData struct {
+	Orientation string `json:"orientation"` // portrait or landscape
+}
+
+const (
+	OrientationPortrait  = "portrait"
+	OrientationLandscape = "landscape"
+)
+
+// Clipboard content to set on a device, images are base64 encoded PNGs
+type ClipboardData struct {
+	Content     string `json:"content"`
+	ContentType string `json:"contentType,omitempty"` // plaintext by default or image
+}
+
+const (
+	ClipboardPlaintext = "plaintext"
+	ClipboardImage     = "image" // iOS only
+)
+
+// Two finger gesture around a center point, the fingers move between the center and `distance` apart
+type PinchData struct {
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+	Distance float64 `json:"distance"`
+	Duration int     `json:"duration,omitempty"` // milliseconds
 }
 
 type DeviceAction struct {
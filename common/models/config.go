@@ -1,6 +1,14 @@
 package models
 
-import "github.com/danielpaulus/go-ios/ios/tunnel"
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/danielpaulus/go-ios/ios/tunnel"
+)
 
 type Provider struct {
 	OS                     string                   `json:"os" bson:"os"`
@@ -20,13 +28,316 @@ type Provider struct {
 	WebDriverBinary        string                   `json:"-" bson:"-"`
 	UseGadsIosStream       bool                     `json:"use_gads_ios_stream" bson:"use_gads_ios_stream"`
 	UseCustomWDA           bool                     `json:"use_custom_wda" bson:"use_custom_wda"`
+	Emulators              []ProviderEmulator       `json:"emulators" bson:"emulators"`                         // Android virtual devices the provider can start and stop on demand
+	NetworkDevices         []string                 `json:"network_devices" bson:"network_devices"`             // `host:port` targets of network-attached Android devices the provider keeps connected with `adb connect`
+	AutoRegisterDevices    bool                     `json:"auto_register_devices" bson:"auto_register_devices"` // create `pending` DB records for connected devices that are not registered yet
+	PortRanges             map[string]string        `json:"port_ranges" bson:"port_ranges"`                     // `start-end` host port ranges per purpose - appium, stream, wda, tunnel, grid_node
+	Hooks                  *ProviderHooks           `json:"hooks" bson:"hooks"`                                 // hooks run on the devices around their setup and Appium sessions
+	CleanupPolicies        []CleanupPolicy          `json:"cleanup_policies" bson:"cleanup_policies"`           // cleanup of the devices after their Appium sessions
+	TelemetryThresholds    *TelemetryThresholds     `json:"telemetry_thresholds" bson:"telemetry_thresholds"`   // limits that take devices out of rotation, telemetry is only collected without them
 	HubAddress             string                   `json:"hub_address" bson:"-"`
+	HubToken               string                   `json:"-" bson:"hub_token,omitempty"` // shared token the provider uses to authenticate its hub connection
 	GoIOSPairRecordManager tunnel.PairRecordManager `json:"-" bson:"-"`
+	FakeDevices            int                      `json:"-" bson:"-"` // number of simulated devices to provide with the fake device driver
+}
+
+// Android virtual device started by the provider, headless and without saving its state on exit
+type ProviderEmulator struct {
+	AVD       string `json:"avd" bson:"avd" yaml:"avd"`                                              // name of the AVD on the provider host
+	Port      int    `json:"port" bson:"port" yaml:"port"`                                           // console port of the emulator, the device UDID is `emulator-<port>`
+	Snapshot  string `json:"snapshot,omitempty" bson:"snapshot,omitempty" yaml:"snapshot,omitempty"` // snapshot to boot from, the Quick Boot snapshot is used if empty
+	AutoStart bool   `json:"auto_start" bson:"auto_start" yaml:"auto_start"`                         // start the emulator together with the provider
+}
+
+func (e ProviderEmulator) UDID() string {
+	return fmt.Sprintf("emulator-%d", e.Port)
+}
+
+// Validate a provider emulators list - the AVD names and ports should be unique and ports should be valid emulator console ports
+func ValidateProviderEmulators(emulators []ProviderEmulator) error {
+	avds := make(map[string]bool)
+	ports := make(map[int]bool)
+	for i, emulator := range emulators {
+		if emulator.AVD == "" {
+			return fmt.Errorf("emulators[%d]: missing `avd`", i)
+		}
+		if avds[emulator.AVD] {
+			return fmt.Errorf("emulators[%d]: duplicate avd `%s`", i, emulator.AVD)
+		}
+		avds[emulator.AVD] = true
+
+		if emulator.Port < 5554 || emulator.Port > 5682 || emulator.Port%2 != 0 {
+			return fmt.Errorf("emulator `%s`: invalid `port` %d - should be an even number between 5554 and 5682", emulator.AVD, emulator.Port)
+		}
+		if ports[emulator.Port] {
+			return fmt.Errorf("emulator `%s`: duplicate port %d", emulator.AVD, emulator.Port)
+		}
+		ports[emulator.Port] = true
+	}
+	return nil
+}
+
+// Validate the `host:port` targets of network-attached devices of a provider
+func ValidateProviderNetworkDevices(targets []string) error {
+	seen := make(map[string]bool)
+	for i, target := range targets {
+		host, port, err := net.SplitHostPort(target)
+		if err != nil || host == "" {
+			return fmt.Errorf("network_devices[%d]: invalid target `%s` - should be `host:port`", i, target)
+		}
+		portInt, err := strconv.Atoi(port)
+		if err != nil || portInt <= 0 || portInt > 65535 {
+			return fmt.Errorf("network_devices[%d]: invalid port in target `%s`", i, target)
+		}
+		if seen[target] {
+			return fmt.Errorf("network_devices[%d]: duplicate target `%s`", i, target)
+		}
+		seen[target] = true
+	}
+	return nil
+}
+
+// Purposes of the host ports a provider allocates for its devices
+const (
+	PortPurposeAppium   = "appium"    // Appium servers
+	PortPurposeStream   = "stream"    // device video streams, including the WebDriverAgent stream of iOS devices
+	PortPurposeWDA      = "wda"       // WebDriverAgent servers of iOS devices
+	PortPurposeTunnel   = "tunnel"    // go-ios userspace tunnels of iOS devices
+	PortPurposeGridNode = "grid_node" // Selenium Grid relay nodes
+)
+
+var PortPurposes = []string{PortPurposeAppium, PortPurposeStream, PortPurposeWDA, PortPurposeTunnel, PortPurposeGridNode}
+
+// Parse a `start-end` port range, a single port is a range of one port
+func ParsePortRange(value string) (int, int, error) {
+	startValue, endValue, found := strings.Cut(value, "-")
+	if !found {
+		endValue = startValue
+	}
+	start, err := strconv.Atoi(strings.TrimSpace(startValue))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid port range `%s` - should be `start-end`", value)
+	}
+	end, err := strconv.Atoi(strings.TrimSpace(endValue))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid port range `%s` - should be `start-end`", value)
+	}
+	if start < 1 || end > 65535 || start > end {
+		return 0, 0, fmt.Errorf("invalid port range `%s` - ports should be between 1 and 65535 and start should not be after end", value)
+	}
+	return start, end, nil
+}
+
+// Validate the port ranges of a provider - only known purposes, valid ranges and no overlaps so each port has a single purpose
+func ValidateProviderPortRanges(portRanges map[string]string) error {
+	type portRange struct {
+		purpose    string
+		start, end int
+	}
+	var ranges []portRange
+	for _, purpose := range PortPurposes {
+		value, ok := portRanges[purpose]
+		if !ok {
+			continue
+		}
+		start, end, err := ParsePortRange(value)
+		if err != nil {
+			return fmt.Errorf("port_ranges.%s: %s", purpose, err)
+		}
+		ranges = append(ranges, portRange{purpose, start, end})
+	}
+	for purpose := range portRanges {
+		if !slices.Contains(PortPurposes, purpose) {
+			return fmt.Errorf("port_ranges: unknown purpose `%s` - should be one of %s", purpose, strings.Join(PortPurposes, ", "))
+		}
+	}
+	for i, a := range ranges {
+		for _, b := range ranges[i+1:] {
+			if a.start <= b.end && b.start <= a.end {
+				return fmt.Errorf("port_ranges: `%s` and `%s` ranges overlap", a.purpose, b.purpose)
+			}
+		}
+	}
+	return nil
+}
+
+// Hooks a provider runs on its devices, hooks of each stage run in order
+type ProviderHooks struct {
+	PreSetup    []DeviceHook `json:"pre_setup,omitempty" bson:"pre_setup,omitempty" yaml:"pre_setup,omitempty"`          // before the device setup starts, the device ports are not allocated yet
+	PostSetup   []DeviceHook `json:"post_setup,omitempty" bson:"post_setup,omitempty" yaml:"post_setup,omitempty"`       // after the device setup finished, before the device is `live`
+	PostSession []DeviceHook `json:"post_session,omitempty" bson:"post_session,omitempty" yaml:"post_session,omitempty"` // after an Appium session of the device was deleted
+}
+
+// A single hook - either a shell command on the provider host, adb arguments for the device or a declarative action
+type DeviceHook struct {
+	Name            string   `json:"name" bson:"name" yaml:"name"`
+	OS              string   `json:"os,omitempty" bson:"os,omitempty" yaml:"os,omitempty"`                                              // run only for devices with this OS, all devices if empty
+	Command         string   `json:"command,omitempty" bson:"command,omitempty" yaml:"command,omitempty"`                               // shell command or script, run with `sh -c` or `cmd /C` on Windows
+	ADB             []string `json:"adb,omitempty" bson:"adb,omitempty" yaml:"adb,omitempty"`                                           // arguments for `adb -s <udid>`, Android only
+	Action          string   `json:"action,omitempty" bson:"action,omitempty" yaml:"action,omitempty"`                                  // one of HookActions
+	App             string   `json:"app,omitempty" bson:"app,omitempty" yaml:"app,omitempty"`                                           // app file in the provider folder for `install_app`, bundle ID or package name for `uninstall_app`
+	Timeout         int      `json:"timeout,omitempty" bson:"timeout,omitempty" yaml:"timeout,omitempty"`                               // in seconds, DefaultHookTimeout if 0
+	ContinueOnError bool     `json:"continue_on_error,omitempty" bson:"continue_on_error,omitempty" yaml:"continue_on_error,omitempty"` // a failed hook does not fail the device setup
+}
+
+const DefaultHookTimeout = 60
+
+// Declarative hook actions
+const (
+	HookActionInstallApp        = "install_app"
+	HookActionUninstallApp      = "uninstall_app"
+	HookActionDisableAnimations = "disable_animations" // Android only
+	HookActionWakeScreen        = "wake_screen"        // Android only
+)
+
+var HookActions = []string{HookActionInstallApp, HookActionUninstallApp, HookActionDisableAnimations, HookActionWakeScreen}
+
+// Validate the hooks of a provider - every hook needs a name and exactly one of `command`, `adb` or `action`
+// Hooks that work only on Android devices should be limited to them with `os`
+func ValidateProviderHooks(hooks *ProviderHooks) error {
+	if hooks == nil {
+		return nil
+	}
+	stages := []struct {
+		name  string
+		hooks []DeviceHook
+	}{
+		{"pre_setup", hooks.PreSetup},
+		{"post_setup", hooks.PostSetup},
+		{"post_session", hooks.PostSession},
+	}
+	for _, stage := range stages {
+		for i, hook := range stage.hooks {
+			if hook.Name == "" {
+				return fmt.Errorf("hooks.%s[%d]: missing `name`", stage.name, i)
+			}
+			if hook.OS != "" && hook.OS != "android" && hook.OS != "ios" {
+				return fmt.Errorf("hook `%s`: invalid `os` `%s` - should be android or ios", hook.Name, hook.OS)
+			}
+			kinds := 0
+			if hook.Command != "" {
+				kinds++
+			}
+			if len(hook.ADB) > 0 {
+				kinds++
+			}
+			if hook.Action != "" {
+				kinds++
+			}
+			if kinds != 1 {
+				return fmt.Errorf("hook `%s`: should have exactly one of `command`, `adb` or `action`", hook.Name)
+			}
+			if hook.Action != "" && !slices.Contains(HookActions, hook.Action) {
+				return fmt.Errorf("hook `%s`: unknown action `%s` - should be one of %s", hook.Name, hook.Action, strings.Join(HookActions, ", "))
+			}
+			if (hook.Action == HookActionInstallApp || hook.Action == HookActionUninstallApp) && hook.App == "" {
+				return fmt.Errorf("hook `%s`: missing `app` for action `%s`", hook.Name, hook.Action)
+			}
+			androidOnly := len(hook.ADB) > 0 || hook.Action == HookActionDisableAnimations || hook.Action == HookActionWakeScreen
+			if androidOnly && hook.OS != "android" {
+				return fmt.Errorf("hook `%s`: works only on Android devices, set `os` to android", hook.Name)
+			}
+			if hook.Timeout < 0 {
+				return fmt.Errorf("hook `%s`: invalid `timeout` %d", hook.Name, hook.Timeout)
+			}
+		}
+	}
+	return nil
+}
+
+// Cleanup the provider runs on a device after one of its Appium sessions was deleted
+// A policy applies to the devices listed by UDID or to the devices with any of its tags, a policy listing the device wins over a policy matching its tags
+type CleanupPolicy struct {
+	Name         string   `json:"name" bson:"name" yaml:"name"`
+	Devices      []string `json:"devices,omitempty" bson:"devices,omitempty" yaml:"devices,omitempty"`                   // UDIDs of the devices the policy applies to
+	Tags         []string `json:"tags,omitempty" bson:"tags,omitempty" yaml:"tags,omitempty"`                            // the policy applies to devices with any of these tags
+	Actions      []string `json:"actions" bson:"actions" yaml:"actions"`                                                 // CleanupActions run in the given order
+	AppAllowlist []string `json:"app_allowlist,omitempty" bson:"app_allowlist,omitempty" yaml:"app_allowlist,omitempty"` // bundle IDs or package names `uninstall_apps` keeps on the device
+}
+
+// Cleanup policy actions
+const (
+	CleanupActionUninstallApps     = "uninstall_apps"      // uninstall user apps that are not in the allowlist
+	CleanupActionClearAppData      = "clear_app_data"      // clear the data of the user apps left on the device, Android only
+	CleanupActionPressHome         = "press_home"          // go to the home screen
+	CleanupActionResetOrientation  = "reset_orientation"   // rotate the device back to portrait
+	CleanupActionKillForegroundApp = "kill_foreground_app" // stop the app in the foreground
+)
+
+var CleanupActions = []string{CleanupActionUninstallApps, CleanupActionClearAppData, CleanupActionPressHome, CleanupActionResetOrientation, CleanupActionKillForegroundApp}
+
+// Response header the provider sets on a deleted Appium session when it holds the device for cleanup
+const DeviceCleanupHeader = "X-GADS-Device-Cleanup"
+
+// Validate the cleanup policies of a provider - unique names, known actions and at least one device or tag per policy
+func ValidateCleanupPolicies(policies []CleanupPolicy) error {
+	names := make(map[string]bool)
+	for i, policy := range policies {
+		if policy.Name == "" {
+			return fmt.Errorf("cleanup_policies[%d]: missing `name`", i)
+		}
+		if names[policy.Name] {
+			return fmt.Errorf("cleanup_policies[%d]: duplicate name `%s`", i, policy.Name)
+		}
+		names[policy.Name] = true
+
+		if len(policy.Devices) == 0 && len(policy.Tags) == 0 {
+			return fmt.Errorf("cleanup policy `%s`: should have `devices` or `tags`", policy.Name)
+		}
+		if len(policy.Actions) == 0 {
+			return fmt.Errorf("cleanup policy `%s`: missing `actions`", policy.Name)
+		}
+		for _, action := range policy.Actions {
+			if !slices.Contains(CleanupActions, action) {
+				return fmt.Errorf("cleanup policy `%s`: unknown action `%s` - should be one of %s", policy.Name, action, strings.Join(CleanupActions, ", "))
+			}
+		}
+	}
+	return nil
 }
 
 type ProviderData struct {
-	ProviderData Provider `json:"provider"`
-	DeviceData   []Device `json:"device_data"`
+	ProviderData Provider  `json:"provider"`
+	DeviceData   []*Device `json:"device_data"`
+}
+
+// Message sent from a provider to the hub over the provider websocket
+type ProviderMessage struct {
+	Type           string                 `json:"type"`
+	Devices        []*Device              `json:"devices,omitempty"`         // devices that changed since the last message, all devices on connect
+	RemovedDevices []string               `json:"removed_devices,omitempty"` // UDIDs of devices the provider no longer provides
+	CommandResult  *ProviderCommandResult `json:"command_result,omitempty"`
+}
+
+const (
+	ProviderMessageDevices       = "devices"
+	ProviderMessageHeartbeat     = "heartbeat"
+	ProviderMessageCommandResult = "command_result"
+	ProviderMessageShutdown      = "shutdown" // the provider is going offline, sent right before it closes the connection
+)
+
+// Command sent from the hub to a provider over the provider websocket
+type ProviderCommand struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	UDID string `json:"udid,omitempty"`
+	AVD  string `json:"avd,omitempty"`
+}
+
+const (
+	ProviderCommandResetDevice     = "reset_device"
+	ProviderCommandReloadConfig    = "reload_config"
+	ProviderCommandStartEmulator   = "start_emulator"
+	ProviderCommandStopEmulator    = "stop_emulator"
+	ProviderCommandSyncDevices     = "sync_devices"
+	ProviderCommandClearQuarantine = "clear_quarantine"
+	ProviderCommandRebootDevice    = "reboot_device"
+)
+
+type ProviderCommandResult struct {
+	ID      string `json:"id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
 }
 
 type HubConfig struct {
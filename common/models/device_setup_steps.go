@@ -0,0 +1,85 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// Outcomes of a device setup step
+const (
+	SetupStepRunning   = "running"
+	SetupStepDone      = "done"
+	SetupStepFailed    = "failed"
+	SetupStepCancelled = "cancelled" // the setup was stopped while the step was running, e.g. the device was disconnected
+)
+
+type DeviceSetupStep struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	StartedAt  int64  `json:"started_at"`
+	FinishedAt int64  `json:"finished_at,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Duration of the step in milliseconds, running steps are measured until now
+func (s DeviceSetupStep) DurationMs() int64 {
+	if s.FinishedAt == 0 {
+		return time.Now().UnixMilli() - s.StartedAt
+	}
+	return s.FinishedAt - s.StartedAt
+}
+
+// Start a new step of the device setup, the currently running step is finished as done
+func (d *Device) StartSetupStep(name string) {
+	d.StateMu.Lock()
+	defer d.StateMu.Unlock()
+
+	d.finishSetupStep(SetupStepDone, "")
+	d.SetupSteps = append(d.SetupSteps, DeviceSetupStep{
+		Name:      name,
+		Status:    SetupStepRunning,
+		StartedAt: time.Now().UnixMilli(),
+	})
+}
+
+// Mark the currently running setup step as failed, does nothing if no step is running
+func (d *Device) FailSetupStep(message string) {
+	d.StateMu.Lock()
+	defer d.StateMu.Unlock()
+	d.finishSetupStep(SetupStepFailed, message)
+}
+
+// Copy of the steps of the current or last device setup, in the order they ran
+func (d *Device) SetupStepsSnapshot() []DeviceSetupStep {
+	d.StateMu.RLock()
+	defer d.StateMu.RUnlock()
+	return slices.Clone(d.SetupSteps)
+}
+
+// Must be called with StateMu locked
+func (d *Device) finishSetupStep(status string, message string) {
+	if len(d.SetupSteps) == 0 {
+		return
+	}
+	step := &d.SetupSteps[len(d.SetupSteps)-1]
+	if step.Status != SetupStepRunning {
+		return
+	}
+	step.Status = status
+	step.Error = message
+	step.FinishedAt = time.Now().UnixMilli()
+}
+
+// Keep the setup steps in line with the state of the device
+// A new setup starts with no steps, a finished setup finishes its last step and a stopped setup cancels it
+// Must be called with StateMu locked
+func (d *Device) updateSetupSteps(to string) {
+	switch to {
+	case DeviceStatePreparing:
+		d.SetupSteps = nil
+	case DeviceStateLive:
+		d.finishSetupStep(SetupStepDone, "")
+	case DeviceStateInit:
+		d.finishSetupStep(SetupStepCancelled, "")
+	}
+}
@@ -0,0 +1,216 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Masterminds/semver"
+)
+
+// States of a device on the provider
+const (
+	DeviceStateInit      = "init"      // device is not set up, it is set up when connected
+	DeviceStatePreparing = "preparing" // device setup is running
+	DeviceStateLive      = "live"      // device is set up and can be used
+	DeviceStateResetting = "resetting" // device setup is being cancelled, the device goes back to `init` after that
+)
+
+// Number of state transitions kept per device
+const deviceStateHistoryLimit = 50
+
+// States a device can move to from each state
+var deviceStateTransitions = map[string][]string{
+	DeviceStateInit:      {DeviceStatePreparing},
+	DeviceStatePreparing: {DeviceStateLive, DeviceStateResetting, DeviceStateInit},
+	DeviceStateLive:      {DeviceStateResetting, DeviceStateInit},
+	DeviceStateResetting: {DeviceStateInit},
+}
+
+type DeviceStateTransition struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Reason    string `json:"reason"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// Current provider state of the device
+func (d *Device) State() string {
+	d.StateMu.RLock()
+	defer d.StateMu.RUnlock()
+	return d.ProviderState
+}
+
+// Move the device to a new provider state if the transition is allowed from the current state
+// The state check and the change are done under the same lock so concurrent transitions cannot both succeed
+func (d *Device) TransitionState(to string, reason string) error {
+	d.StateMu.Lock()
+	defer d.StateMu.Unlock()
+
+	from := d.ProviderState
+	if from == "" {
+		from = DeviceStateInit
+	}
+	if !slices.Contains(deviceStateTransitions[from], to) {
+		return fmt.Errorf("Invalid state transition for device `%s` from `%s` to `%s`", d.UDID, from, to)
+	}
+
+	d.setState(from, to, reason)
+	return nil
+}
+
+// Put the device back in `init` state without validating the transition, used when the device is (re)loaded from the DB
+func (d *Device) ResetState(reason string) {
+	d.StateMu.Lock()
+	defer d.StateMu.Unlock()
+
+	from := d.ProviderState
+	if from == "" || from == DeviceStateInit {
+		d.ProviderState = DeviceStateInit
+		d.IsResetting = false
+		return
+	}
+	d.setState(from, DeviceStateInit, reason)
+}
+
+// Must be called with StateMu locked
+func (d *Device) setState(from string, to string, reason string) {
+	d.ProviderState = to
+	d.IsResetting = to == DeviceStateResetting
+	d.updateSetupSteps(to)
+	d.stateHistory = append(d.stateHistory, DeviceStateTransition{
+		From:      from,
+		To:        to,
+		Reason:    reason,
+		Timestamp: time.Now().UnixMilli(),
+	})
+	if len(d.stateHistory) > deviceStateHistoryLimit {
+		d.stateHistory = slices.Clone(d.stateHistory[len(d.stateHistory)-deviceStateHistoryLimit:])
+	}
+}
+
+// Copy of the state transitions of the device, oldest first
+func (d *Device) StateHistory() []DeviceStateTransition {
+	d.StateMu.RLock()
+	defer d.StateMu.RUnlock()
+	return slices.Clone(d.stateHistory)
+}
+
+// Time of the last state transition of the device, 0 if its state did not change yet
+func (d *Device) StateChangedAt() int64 {
+	d.StateMu.RLock()
+	defer d.StateMu.RUnlock()
+	if len(d.stateHistory) == 0 {
+		return 0
+	}
+	return d.stateHistory[len(d.stateHistory)-1].Timestamp
+}
+
+// Current Appium session ID of the device
+func (d *Device) AppiumSession() string {
+	d.StateMu.RLock()
+	defer d.StateMu.RUnlock()
+	return d.AppiumSessionID
+}
+
+func (d *Device) SetAppiumSession(sessionID string) {
+	d.StateMu.Lock()
+	defer d.StateMu.Unlock()
+	d.AppiumSessionID = sessionID
+}
+
+// If the device is currently connected to the provider host
+func (d *Device) IsConnected() bool {
+	d.StateMu.RLock()
+	defer d.StateMu.RUnlock()
+	return d.Connected
+}
+
+func (d *Device) SetConnected(connected bool) {
+	d.StateMu.Lock()
+	defer d.StateMu.Unlock()
+	d.Connected = connected
+}
+
+func (d *Device) SetInstalledApps(apps []string) {
+	d.StateMu.Lock()
+	defer d.StateMu.Unlock()
+	d.InstalledApps = apps
+}
+
+func (d *Device) SetNetworkConnection(status *NetworkConnectionStatus) {
+	d.StateMu.Lock()
+	defer d.StateMu.Unlock()
+	d.NetworkConnection = status
+}
+
+// Apply the configuration of the DB record of the device, the OS semantic version is parsed by the caller
+func (d *Device) ApplyDBConfig(dbDevice *Device, semVer *semver.Version) {
+	d.StateMu.Lock()
+	defer d.StateMu.Unlock()
+	d.Usage = dbDevice.Usage
+	d.OS = dbDevice.OS
+	d.OSVersion = dbDevice.OSVersion
+	d.SemVer = semVer
+	d.Name = dbDevice.Name
+	d.ScreenWidth = dbDevice.ScreenWidth
+	d.ScreenHeight = dbDevice.ScreenHeight
+	d.DeviceType = dbDevice.DeviceType
+	d.Tags = slices.Clone(dbDevice.Tags)
+}
+
+// Copy of the device data taken while the state is locked, for storing or sending the device without holding its locks
+// Provider only values like the ports, the context and the loggers are not copied
+func (d *Device) Snapshot() *Device {
+	d.StateMu.RLock()
+	defer d.StateMu.RUnlock()
+
+	snapshot := &Device{
+		UDID:                 d.UDID,
+		OS:                   d.OS,
+		Name:                 d.Name,
+		OSVersion:            d.OSVersion,
+		Provider:             d.Provider,
+		Usage:                d.Usage,
+		ScreenWidth:          d.ScreenWidth,
+		ScreenHeight:         d.ScreenHeight,
+		DeviceType:           d.DeviceType,
+		Tags:                 slices.Clone(d.Tags),
+		Host:                 d.Host,
+		HardwareModel:        d.HardwareModel,
+		LastUpdatedTimestamp: d.LastUpdatedTimestamp,
+		Connected:            d.Connected,
+		IsResetting:          d.IsResetting,
+		ProviderState:        d.ProviderState,
+		SetupFailures:        d.SetupFailures,
+		LastSetupError:       d.LastSetupError,
+		NextSetupAttempt:     d.NextSetupAttempt,
+		Quarantined:          d.Quarantined,
+		SetupSteps:           slices.Clone(d.SetupSteps),
+		CleaningUp:           d.CleaningUp,
+		Unhealthy:            d.Unhealthy,
+		UnhealthyReasons:     slices.Clone(d.UnhealthyReasons),
+		Rebooting:            d.Rebooting,
+		InstalledApps:        slices.Clone(d.InstalledApps),
+		UsesCustomWDA:        d.UsesCustomWDA,
+	}
+	if d.NetworkConnection != nil {
+		networkConnection := *d.NetworkConnection
+		snapshot.NetworkConnection = &networkConnection
+	}
+	if d.Telemetry != nil {
+		telemetry := *d.Telemetry
+		snapshot.Telemetry = &telemetry
+	}
+	return snapshot
+}
+
+type deviceJSON Device
+
+// Devices are marshalled while the state is locked so the state fields are not changed halfway
+func (d *Device) MarshalJSON() ([]byte, error) {
+	d.StateMu.RLock()
+	defer d.StateMu.RUnlock()
+	return json.Marshal((*deviceJSON)(d))
+}
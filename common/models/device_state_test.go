@@ -0,0 +1,174 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestTransitionStateRejectsInvalidTransitions(t *testing.T) {
+	device := &Device{UDID: "test"}
+
+	invalid := []string{DeviceStateLive, DeviceStateResetting, DeviceStateInit}
+	for _, to := range invalid {
+		if err := device.TransitionState(to, "test"); err == nil {
+			t.Errorf("Expected transition from `init` to `%s` to fail", to)
+		}
+	}
+	if err := device.TransitionState(DeviceStatePreparing, "test"); err != nil {
+		t.Fatalf("Expected transition from `init` to `preparing` to succeed - %s", err)
+	}
+	if err := device.TransitionState(DeviceStateLive, "test"); err != nil {
+		t.Fatalf("Expected transition from `preparing` to `live` to succeed - %s", err)
+	}
+	if err := device.TransitionState(DeviceStatePreparing, "test"); err == nil {
+		t.Errorf("Expected transition from `live` to `preparing` to fail")
+	}
+	if state := device.State(); state != DeviceStateLive {
+		t.Errorf("Expected state `live` after rejected transitions, got `%s`", state)
+	}
+	if history := device.StateHistory(); len(history) != 2 {
+		t.Errorf("Expected only the successful transitions in the history, got %d", len(history))
+	}
+}
+
+func TestTransitionStateConcurrentReset(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		device := &Device{UDID: "test"}
+		device.TransitionState(DeviceStatePreparing, "test")
+		device.TransitionState(DeviceStateLive, "test")
+
+		var wg sync.WaitGroup
+		results := make([]error, 2)
+		for j := range results {
+			wg.Add(1)
+			go func(j int) {
+				defer wg.Done()
+				results[j] = device.TransitionState(DeviceStateResetting, fmt.Sprintf("reset %d", j))
+			}(j)
+		}
+		wg.Wait()
+
+		succeeded := 0
+		for _, err := range results {
+			if err == nil {
+				succeeded++
+			}
+		}
+		if succeeded != 1 {
+			t.Fatalf("Expected exactly one concurrent `live` to `resetting` transition to succeed, got %d", succeeded)
+		}
+		if !device.IsResetting {
+			t.Fatalf("Expected IsResetting to be set with the `resetting` state")
+		}
+	}
+}
+
+func TestStateHistoryLimit(t *testing.T) {
+	device := &Device{UDID: "test"}
+	for i := 0; i < 40; i++ {
+		device.TransitionState(DeviceStatePreparing, fmt.Sprintf("setup %d", i))
+		device.TransitionState(DeviceStateInit, fmt.Sprintf("disconnected %d", i))
+	}
+
+	history := device.StateHistory()
+	if len(history) != deviceStateHistoryLimit {
+		t.Fatalf("Expected %d history entries, got %d", deviceStateHistoryLimit, len(history))
+	}
+	if last := history[len(history)-1]; last.Reason != "disconnected 39" || last.To != DeviceStateInit {
+		t.Errorf("Expected the latest transition last in the history, got %+v", last)
+	}
+	if first := history[0]; first.Reason != "setup 15" {
+		t.Errorf("Expected the oldest transitions to be dropped, first entry is %+v", first)
+	}
+}
+
+func TestMarshalJSONConcurrentWithUpdates(t *testing.T) {
+	device := &Device{UDID: "test", OS: "android"}
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+
+	writers := []func(i int){
+		func(i int) {
+			device.TransitionState(DeviceStatePreparing, "test")
+			device.TransitionState(DeviceStateInit, "test")
+		},
+		func(i int) { device.SetConnected(i%2 == 0) },
+		func(i int) { device.SetAppiumSession(fmt.Sprintf("session-%d", i)) },
+		func(i int) { device.SetNetworkConnection(&NetworkConnectionStatus{Target: "test", FailedAttempts: i}) },
+		func(i int) {
+			device.ApplyDBConfig(&Device{Usage: "enabled", OS: "android", Name: fmt.Sprintf("name-%d", i), Tags: []string{"tag"}}, nil)
+		},
+	}
+	for _, write := range writers {
+		wg.Add(1)
+		go func(write func(i int)) {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				default:
+					write(i)
+				}
+			}
+		}(write)
+	}
+
+	for i := 0; i < 500; i++ {
+		data, err := json.Marshal(device)
+		if err != nil {
+			t.Fatalf("Failed to marshal device - %s", err)
+		}
+		var decoded map[string]interface{}
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Marshalled device is not valid JSON - %s", err)
+		}
+		device.IsConnected()
+		device.State()
+	}
+	close(done)
+	wg.Wait()
+}
+
+func TestSnapshotCopiesDeviceData(t *testing.T) {
+	device := &Device{}
+	deviceValue := reflect.ValueOf(device).Elem()
+	for i := 0; i < deviceValue.NumField(); i++ {
+		field := deviceValue.Type().Field(i)
+		if !field.IsExported() || field.Tag.Get("json") == "-" {
+			continue
+		}
+		switch field.Type.Kind() {
+		case reflect.String:
+			deviceValue.Field(i).SetString("value")
+		case reflect.Bool:
+			deviceValue.Field(i).SetBool(true)
+		case reflect.Int, reflect.Int64:
+			deviceValue.Field(i).SetInt(1)
+		case reflect.Slice:
+			deviceValue.Field(i).Set(reflect.MakeSlice(field.Type, 1, 1))
+		case reflect.Pointer:
+			deviceValue.Field(i).Set(reflect.New(field.Type.Elem()))
+		default:
+			t.Fatalf("Unexpected type %s of field `%s`", field.Type, field.Name)
+		}
+	}
+
+	snapshot := device.Snapshot()
+	snapshotValue := reflect.ValueOf(snapshot).Elem()
+	for i := 0; i < deviceValue.NumField(); i++ {
+		field := deviceValue.Type().Field(i)
+		if !field.IsExported() || field.Tag.Get("json") == "-" {
+			continue
+		}
+		if !reflect.DeepEqual(deviceValue.Field(i).Interface(), snapshotValue.Field(i).Interface()) {
+			t.Errorf("Field `%s` is not copied by Snapshot", field.Name)
+		}
+	}
+	if snapshot.Telemetry == device.Telemetry || snapshot.NetworkConnection == device.NetworkConnection {
+		t.Errorf("Expected Snapshot to copy the values behind pointers")
+	}
+}
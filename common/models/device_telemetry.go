@@ -0,0 +1,91 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Number of telemetry samples kept per device
+const deviceTelemetryHistoryLimit = 120
+
+// Telemetry sample of a device, values that could not be collected are left empty
+type DeviceTelemetry struct {
+	Timestamp    int64   `json:"timestamp"`
+	BatteryLevel int     `json:"battery_level"` // percent, -1 if unknown
+	Charging     bool    `json:"charging"`
+	Temperature  float64 `json:"temperature,omitempty"`   // battery temperature in °C
+	FreeStorage  int64   `json:"free_storage,omitempty"`  // bytes
+	TotalStorage int64   `json:"total_storage,omitempty"` // bytes
+	FreeMemory   int64   `json:"free_memory,omitempty"`   // bytes, Android only
+	TotalMemory  int64   `json:"total_memory,omitempty"`  // bytes, Android only
+}
+
+// Limits of the device telemetry, a device breaking any of them is taken out of rotation until it is back within the limits
+// Limits that are 0 are not checked
+type TelemetryThresholds struct {
+	MinBatteryLevel  int     `json:"min_battery_level,omitempty" bson:"min_battery_level,omitempty" yaml:"min_battery_level,omitempty"`       // percent
+	MaxTemperature   float64 `json:"max_temperature,omitempty" bson:"max_temperature,omitempty" yaml:"max_temperature,omitempty"`             // °C
+	MinFreeStorageMB int64   `json:"min_free_storage_mb,omitempty" bson:"min_free_storage_mb,omitempty" yaml:"min_free_storage_mb,omitempty"` // MB
+	MinFreeMemoryMB  int64   `json:"min_free_memory_mb,omitempty" bson:"min_free_memory_mb,omitempty" yaml:"min_free_memory_mb,omitempty"`    // MB
+}
+
+func ValidateTelemetryThresholds(thresholds *TelemetryThresholds) error {
+	if thresholds == nil {
+		return nil
+	}
+	if thresholds.MinBatteryLevel < 0 || thresholds.MinBatteryLevel > 100 {
+		return fmt.Errorf("telemetry_thresholds: invalid `min_battery_level` %d - should be between 0 and 100", thresholds.MinBatteryLevel)
+	}
+	if thresholds.MaxTemperature < 0 {
+		return fmt.Errorf("telemetry_thresholds: invalid `max_temperature` %v", thresholds.MaxTemperature)
+	}
+	if thresholds.MinFreeStorageMB < 0 || thresholds.MinFreeMemoryMB < 0 {
+		return fmt.Errorf("telemetry_thresholds: free storage and memory limits should not be negative")
+	}
+	return nil
+}
+
+// Reasons the sample breaks the thresholds, empty if it is within them
+// Unknown values are not checked
+func (t TelemetryThresholds) Check(sample DeviceTelemetry) []string {
+	var reasons []string
+	if t.MinBatteryLevel > 0 && sample.BatteryLevel >= 0 && sample.BatteryLevel < t.MinBatteryLevel {
+		reasons = append(reasons, fmt.Sprintf("battery level %d%% is below %d%%", sample.BatteryLevel, t.MinBatteryLevel))
+	}
+	if t.MaxTemperature > 0 && sample.Temperature > t.MaxTemperature {
+		reasons = append(reasons, fmt.Sprintf("temperature %.1f°C is above %.1f°C", sample.Temperature, t.MaxTemperature))
+	}
+	if t.MinFreeStorageMB > 0 && sample.TotalStorage > 0 && sample.FreeStorage/(1024*1024) < t.MinFreeStorageMB {
+		reasons = append(reasons, fmt.Sprintf("free storage %d MB is below %d MB", sample.FreeStorage/(1024*1024), t.MinFreeStorageMB))
+	}
+	if t.MinFreeMemoryMB > 0 && sample.TotalMemory > 0 && sample.FreeMemory/(1024*1024) < t.MinFreeMemoryMB {
+		reasons = append(reasons, fmt.Sprintf("free memory %d MB is below %d MB", sample.FreeMemory/(1024*1024), t.MinFreeMemoryMB))
+	}
+	return reasons
+}
+
+// Store a new telemetry sample of the device and update its health from the threshold breaches of the sample
+// Returns true if the device health changed
+func (d *Device) AddTelemetry(sample DeviceTelemetry, reasons []string) bool {
+	d.StateMu.Lock()
+	defer d.StateMu.Unlock()
+
+	d.Telemetry = &sample
+	d.telemetryHistory = append(d.telemetryHistory, sample)
+	if len(d.telemetryHistory) > deviceTelemetryHistoryLimit {
+		d.telemetryHistory = slices.Clone(d.telemetryHistory[len(d.telemetryHistory)-deviceTelemetryHistoryLimit:])
+	}
+
+	unhealthy := len(reasons) > 0
+	changed := unhealthy != d.Unhealthy
+	d.Unhealthy = unhealthy
+	d.UnhealthyReasons = reasons
+	return changed
+}
+
+// Copy of the telemetry samples of the device, oldest first
+func (d *Device) TelemetryHistory() []DeviceTelemetry {
+	d.StateMu.RLock()
+	defer d.StateMu.RUnlock()
+	return slices.Clone(d.telemetryHistory)
+}
@@ -0,0 +1,11 @@
+package models
+
+// File or directory in the storage of a device
+// Paths are relative to the shared storage on Android, the media directory or an app container on iOS
+type DeviceFile struct {
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	IsDir    bool   `json:"is_dir"`
+	Size     int64  `json:"size"`
+	Modified int64  `json:"modified,omitempty"` // unix milliseconds
+}
@@ -0,0 +1,177 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurring window during which the matching devices are taken out of rotation and rebooted by their provider
+type MaintenanceWindow struct {
+	Name     string   `json:"name" bson:"name"`
+	Devices  []string `json:"devices" bson:"devices"`   // UDIDs of the devices the window applies to
+	Tags     []string `json:"tags" bson:"tags"`         // devices with any of these tags are included too
+	Schedule string   `json:"schedule" bson:"schedule"` // cron expression in the hub local time for the start of the window, e.g. `0 3 * * 0`
+	Duration int      `json:"duration" bson:"duration"` // minutes, sessions have to finish and the device has to be back within the window
+	Enabled  bool     `json:"enabled" bson:"enabled"`
+}
+
+const DefaultMaintenanceDuration = 60
+
+// Outcome of the maintenance of a single device
+type MaintenanceRun struct {
+	Window     string `json:"window" bson:"window"`
+	UDID       string `json:"udid" bson:"udid"`
+	Provider   string `json:"provider" bson:"provider"`
+	StartedAt  int64  `json:"started_at" bson:"started_at"`
+	RebootedAt int64  `json:"rebooted_at,omitempty" bson:"rebooted_at"`
+	FinishedAt int64  `json:"finished_at" bson:"finished_at"`
+	Outcome    string `json:"outcome" bson:"outcome"`
+	Error      string `json:"error,omitempty" bson:"error"`
+}
+
+const (
+	MaintenanceOutcomeSuccess = "success"
+	MaintenanceOutcomeFailed  = "failed"
+	MaintenanceOutcomeSkipped = "skipped" // the device was busy or offline for the whole window
+)
+
+func (w MaintenanceWindow) DurationOrDefault() time.Duration {
+	if w.Duration == 0 {
+		return DefaultMaintenanceDuration * time.Minute
+	}
+	return time.Duration(w.Duration) * time.Minute
+}
+
+// Check if the window applies to a device by its UDID or one of its tags
+func (w MaintenanceWindow) AppliesTo(device *Device) bool {
+	if slices.Contains(w.Devices, device.UDID) {
+		return true
+	}
+	for _, tag := range device.Tags {
+		if slices.Contains(w.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func ValidateMaintenanceWindow(window MaintenanceWindow) error {
+	if window.Name == "" {
+		return fmt.Errorf("missing `name` field")
+	}
+	if len(window.Devices) == 0 && len(window.Tags) == 0 {
+		return fmt.Errorf("maintenance window `%s`: provide at least one of `devices` or `tags`", window.Name)
+	}
+	_, err := ParseCronSchedule(window.Schedule)
+	if err != nil {
+		return fmt.Errorf("maintenance window `%s`: invalid `schedule` - %s", window.Name, err)
+	}
+	if window.Duration < 0 {
+		return fmt.Errorf("maintenance window `%s`: invalid `duration` %d", window.Name, window.Duration)
+	}
+	return nil
+}
+
+// Parsed five field cron expression - minute, hour, day of month, month and day of week
+// Fields support `*`, single values, ranges `1-5`, lists `1,3` and steps `*/15` or `0-30/10`
+type CronSchedule struct {
+	minutes     map[int]bool
+	hours       map[int]bool
+	daysOfMonth map[int]bool
+	months      map[int]bool
+	daysOfWeek  map[int]bool
+	// Like in cron a day matches either field when both day fields are restricted
+	anyDayOfMonth bool
+	anyDayOfWeek  bool
+}
+
+func ParseCronSchedule(expression string) (CronSchedule, error) {
+	var schedule CronSchedule
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return schedule, fmt.Errorf("expected 5 fields (minute hour day-of-month month day-of-week), got %d", len(fields))
+	}
+
+	var err error
+	if schedule.minutes, err = parseCronField(fields[0], 0, 59); err != nil {
+		return schedule, fmt.Errorf("minute field - %s", err)
+	}
+	if schedule.hours, err = parseCronField(fields[1], 0, 23); err != nil {
+		return schedule, fmt.Errorf("hour field - %s", err)
+	}
+	if schedule.daysOfMonth, err = parseCronField(fields[2], 1, 31); err != nil {
+		return schedule, fmt.Errorf("day-of-month field - %s", err)
+	}
+	if schedule.months, err = parseCronField(fields[3], 1, 12); err != nil {
+		return schedule, fmt.Errorf("month field - %s", err)
+	}
+	// Both 0 and 7 are Sunday
+	if schedule.daysOfWeek, err = parseCronField(fields[4], 0, 7); err != nil {
+		return schedule, fmt.Errorf("day-of-week field - %s", err)
+	}
+	if schedule.daysOfWeek[7] {
+		schedule.daysOfWeek[0] = true
+	}
+	schedule.anyDayOfMonth = strings.HasPrefix(fields[2], "*")
+	schedule.anyDayOfWeek = strings.HasPrefix(fields[4], "*")
+	return schedule, nil
+}
+
+func parseCronField(field string, minValue int, maxValue int) (map[int]bool, error) {
+	values := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step `%s`", stepPart)
+			}
+		}
+
+		start, end := minValue, maxValue
+		if rangePart != "*" {
+			startPart, endPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			start, err = strconv.Atoi(startPart)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value `%s`", startPart)
+			}
+			end = start
+			if isRange {
+				end, err = strconv.Atoi(endPart)
+				if err != nil {
+					return nil, fmt.Errorf("invalid value `%s`", endPart)
+				}
+			} else if hasStep {
+				// `5/15` means from 5 to the maximum every 15
+				end = maxValue
+			}
+		}
+		if start < minValue || end > maxValue || start > end {
+			return nil, fmt.Errorf("`%s` is out of range %d-%d", rangePart, minValue, maxValue)
+		}
+
+		for value := start; value <= end; value += step {
+			values[value] = true
+		}
+	}
+	return values, nil
+}
+
+// Check if the schedule fires in the minute of the provided time
+func (s CronSchedule) Matches(t time.Time) bool {
+	if !s.minutes[t.Minute()] || !s.hours[t.Hour()] || !s.months[int(t.Month())] {
+		return false
+	}
+	dayOfMonth := s.daysOfMonth[t.Day()]
+	dayOfWeek := s.daysOfWeek[int(t.Weekday())]
+	if s.anyDayOfMonth || s.anyDayOfWeek {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
@@ -22,12 +22,6 @@ type AppiumLogger interface {
 	Log(device *Device, logLine string)
 }
 
-type ByUDID []Device
-
-func (a ByUDID) Len() int           { return len(a) }
-func (a ByUDID) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
-func (a ByUDID) Less(i, j int) bool { return a[i].UDID < a[j].UDID }
-
 type User struct {
 	Username string `json:"username" bson:"username"`
 	Password string `json:"password" bson:"password"`
@@ -37,23 +31,35 @@ type User struct {
 
 type Device struct {
 	// DB DATA
-	UDID         string `json:"udid" bson:"udid"`                   // device UDID
-	OS           string `json:"os" bson:"os"`                       // device OS
-	Name         string `json:"name" bson:"name"`                   // name of the device
-	OSVersion    string `json:"os_version" bson:"os_version"`       // OS version of the device
-	Provider     string `json:"provider" bson:"provider"`           // nickname of the device host(provider)
-	Usage        string `json:"usage" bson:"usage"`                 // what is the device used for: enabled(automation and remote control), automation(only Appium testing), remote(only remote control), disabled
-	ScreenWidth  string `json:"screen_width" bson:"screen_width"`   // screen width of device
-	ScreenHeight string `json:"screen_height" bson:"screen_height"` // screen height of device
-	DeviceType   string `json:"device_type" bson:"device_type"`     // The type of device - `real` or `emulator`
+	UDID         string   `json:"udid" bson:"udid"`                   // device UDID
+	OS           string   `json:"os" bson:"os"`                       // device OS
+	Name         string   `json:"name" bson:"name"`                   // name of the device
+	OSVersion    string   `json:"os_version" bson:"os_version"`       // OS version of the device
+	Provider     string   `json:"provider" bson:"provider"`           // nickname of the device host(provider)
+	Usage        string   `json:"usage" bson:"usage"`                 // what is the device used for: enabled(automation and remote control), automation(only Appium testing), remote(only remote control), disabled
+	ScreenWidth  string   `json:"screen_width" bson:"screen_width"`   // screen width of device
+	ScreenHeight string   `json:"screen_height" bson:"screen_height"` // screen height of device
+	DeviceType   string   `json:"device_type" bson:"device_type"`     // The type of device - `real` or `emulator`
+	Tags         []string `json:"tags" bson:"tags"`                   // free-form labels used to group devices, e.g. for policies and filtering
 	// NON-DB DATA
 	/// COMMON VALUES
-	Host                 string `json:"host" bson:"-"`                   // IP address of the device host(provider)
-	HardwareModel        string `json:"hardware_model" bson:"-"`         // hardware model of device
-	LastUpdatedTimestamp int64  `json:"last_updated_timestamp" bson:"-"` // last time the device data was updated
-	Connected            bool   `json:"connected" bson:"-"`              // if device is currently connected
-	IsResetting          bool   `json:"is_resetting" bson:"-"`           // if device setup is currently being reset
-	ProviderState        string `json:"provider_state" bson:"-"`         // current state of the device on the provider - init, preparing, live
+	Host                 string                   `json:"host" bson:"-"`                         // IP address of the device host(provider)
+	HardwareModel        string                   `json:"hardware_model" bson:"-"`               // hardware model of device
+	LastUpdatedTimestamp int64                    `json:"last_updated_timestamp" bson:"-"`       // last time the device data was updated
+	Connected            bool                     `json:"connected" bson:"-"`                    // if device is currently connected
+	IsResetting          bool                     `json:"is_resetting" bson:"-"`                 // if device setup is currently being reset, set together with ProviderState
+	ProviderState        string                   `json:"provider_state" bson:"-"`               // current state of the device on the provider - init, preparing, live, resetting. Changed only with TransitionState on the provider
+	NetworkConnection    *NetworkConnectionStatus `json:"network_connection,omitempty" bson:"-"` // connection health of network-attached devices, nil for USB devices
+	SetupFailures        int                      `json:"setup_failures" bson:"-"`               // consecutive failed setups of the device, guarded by StateMu
+	LastSetupError       string                   `json:"last_setup_error,omitempty" bson:"-"`   // error of the last failed setup, guarded by StateMu
+	NextSetupAttempt     int64                    `json:"next_setup_attempt,omitempty" bson:"-"` // the setup is not retried before this time after a failure, guarded by StateMu
+	Quarantined          bool                     `json:"quarantined" bson:"-"`                  // the setup failed too many times in a row and is not retried until an admin clears the quarantine, guarded by StateMu
+	SetupSteps           []DeviceSetupStep        `json:"setup_steps" bson:"-"`                  // steps of the current or last setup of the device with their outcome and timing, guarded by StateMu
+	CleaningUp           bool                     `json:"cleaning_up" bson:"-"`                  // the provider is cleaning up the device after an Appium session and it is not available until done, guarded by StateMu
+	Telemetry            *DeviceTelemetry         `json:"telemetry,omitempty" bson:"-"`          // latest telemetry sample of the device, guarded by StateMu
+	Unhealthy            bool                     `json:"unhealthy" bson:"-"`                    // the latest telemetry breaks the provider thresholds and the device is out of rotation, guarded by StateMu
+	UnhealthyReasons     []string                 `json:"unhealthy_reasons,omitempty" bson:"-"`  // thresholds the latest telemetry breaks, guarded by StateMu
+	Rebooting            bool                     `json:"rebooting" bson:"-"`                    // the provider rebooted the device and does not set it up until it reconnected, guarded by StateMu
 	/// PROVIDER ONLY VALUES
 	//// RETURNABLE VALUES
 	InstalledApps []string `json:"installed_apps" bson:"-"`  // list of installed apps on device
@@ -73,9 +79,23 @@ type Device struct {
 	Logger           CustomLogger       `json:"-" bson:"-"` // CustomLogger object for the device
 	AppiumLogger     AppiumLogger       `json:"-" bson:"-"` // AppiumLogger object for logging appium actions
 	Mutex            sync.Mutex         `json:"-" bson:"-"` // Mutex to lock resources - especially on device reset
+	StateMu          sync.RWMutex       `json:"-" bson:"-"` // Guards ProviderState, IsResetting, Connected, NetworkConnection, AppiumSessionID, the DB configuration, the installed apps, the setup failures and steps, the telemetry and the state history which are changed from many goroutines
 	GoIOSTunnel      tunnel.Tunnel      `json:"-" bson:"-"` // Tunnel obj for go-ios handling of iOS 17.4+
 	SemVer           *semver.Version    `json:"-" bson:"-"` // Semantic version of device for checks around the provider
 	InitialSetupDone bool               `json:"-" bson:"-"` // On provider startup some data is prepared for devices like logger, Mongo collection, etc. This is true if all is done
+
+	stateHistory     []DeviceStateTransition // latest provider state transitions of the device, guarded by StateMu
+	telemetryHistory []DeviceTelemetry       // latest telemetry samples of the device, guarded by StateMu
+}
+
+// Connection state of a network-attached Android device that the provider keeps connected with `adb connect`
+type NetworkConnectionStatus struct {
+	Target         string `json:"target"`               // `host:port` the provider connects to, also the device UDID
+	Connected      bool   `json:"connected"`            // if adb currently reports the device as online
+	LastConnected  int64  `json:"last_connected"`       // last time the device was seen online
+	LastAttempt    int64  `json:"last_attempt"`         // last time the provider tried to connect
+	FailedAttempts int    `json:"failed_attempts"`      // failed connection attempts since the device was last online
+	LastError      string `json:"last_error,omitempty"` // output of the last failed connection attempt
 }
 
 type LocalHubDevice struct {
@@ -89,6 +109,7 @@ type LocalHubDevice struct {
 	AppiumNewCommandTimeout  int64  `json:"appium_new_command_timeout"`
 	IsAvailableForAutomation bool   `json:"is_available_for_automation"`
 	Available                bool   `json:"available" bson:"-"` // if device is currently available - not only connected, but setup completed
+	InMaintenance            bool   `json:"in_maintenance"`     // the device is in a maintenance window and does not get new sessions
 }
 
 type IOSModelData struct {
@@ -0,0 +1,21 @@
+package models
+
+type ProviderStatus string
+
+const (
+	ProviderOnline   ProviderStatus = "online"
+	ProviderDegraded ProviderStatus = "degraded"
+	ProviderOffline  ProviderStatus = "offline"
+)
+
+// Liveness of a provider as seen by the hub
+type ProviderLiveness struct {
+	Nickname       string         `json:"nickname"`
+	Status         ProviderStatus `json:"status"`
+	StatusSince    int64          `json:"status_since"`      // when the provider entered the current status
+	LastHubUpdate  int64          `json:"last_hub_update"`   // last device data push received by the hub
+	LastDBUpdate   int64          `json:"last_db_update"`    // last time the provider updated itself in MongoDB
+	LastSeen       int64          `json:"last_seen"`         // latest of the two timestamps above
+	DevicesCount   int            `json:"devices_count"`     // devices assigned to the provider in the hub
+	ConnectedCount int            `json:"connected_devices"` // devices of the provider that are currently connected
+}
@@ -0,0 +1,21 @@
+package models
+
+type Webhook struct {
+	Name    string   `json:"name" bson:"name"`
+	URL     string   `json:"url" bson:"url"`
+	Secret  string   `json:"secret,omitempty" bson:"secret"`
+	Events  []string `json:"events" bson:"events"` // empty means all events
+	Enabled bool     `json:"enabled" bson:"enabled"`
+}
+
+type WebhookDelivery struct {
+	Webhook    string `json:"webhook" bson:"webhook"`
+	EventID    string `json:"event_id" bson:"event_id"`
+	EventType  string `json:"event_type" bson:"event_type"`
+	Attempt    int    `json:"attempt" bson:"attempt"`
+	StatusCode int    `json:"status_code" bson:"status_code"`
+	Error      string `json:"error" bson:"error"`
+	Success    bool   `json:"success" bson:"success"`
+	DurationMs int64  `json:"duration_ms" bson:"duration_ms"`
+	Timestamp  int64  `json:"ts" bson:"ts"`
+}
@@ -3,7 +3,9 @@ package devices
 import (
 	"GADS/common/db"
 	"GADS/common/models"
+	"GADS/hub/events"
 	"fmt"
+	"slices"
 	"strconv"
 	"sync"
 	"time"
@@ -44,6 +46,8 @@ func InitHubDevicesData() {
 // Get the latest devices information from MongoDB each second
 func GetLatestDBDevices() {
 	var latestDBDevices []models.Device
+	// Devices present on the first load were not added while the hub was running
+	initialLoad := true
 
 	for {
 		latestDBDevices = db.GetDBDeviceNew()
@@ -86,6 +90,9 @@ func GetLatestDBDevices() {
 				if hubDevice.Device.Provider != dbDevice.Provider {
 					hubDevice.Device.Provider = dbDevice.Provider
 				}
+				if !slices.Equal(hubDevice.Device.Tags, dbDevice.Tags) {
+					hubDevice.Device.Tags = dbDevice.Tags
+				}
 			} else {
 				HubDevicesData.Devices[dbDevice.UDID] = &models.LocalHubDevice{
 					Device:                   dbDevice,
@@ -93,9 +100,17 @@ func GetLatestDBDevices() {
 					IsAvailableForAutomation: true,
 					LastAutomationActionTS:   0,
 				}
+				if dbDevice.Usage == "pending" && !initialLoad {
+					events.PublishDeviceEvent(events.DeviceRegistered, dbDevice.Provider, dbDevice.UDID, map[string]string{
+						"name":       dbDevice.Name,
+						"os":         dbDevice.OS,
+						"os_version": dbDevice.OSVersion,
+					})
+				}
 			}
 			HubDevicesData.Mu.Unlock()
 		}
+		initialLoad = false
 		time.Sleep(1 * time.Second)
 	}
 }
@@ -0,0 +1,172 @@
+package devices
+
+import (
+	"GADS/common/db"
+	"GADS/common/models"
+	"GADS/hub/events"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	maintenancePollInterval = 5 * time.Second
+	// Time a device gets to come back after the reboot even if the window ends earlier
+	maintenanceRebootGrace = 10 * time.Minute
+)
+
+// Start the maintenance of the devices whose maintenance window begins, the schedules are checked once per minute
+func MaintainDevices() {
+	var lastMinute time.Time
+	for {
+		minute := time.Now().Truncate(time.Minute)
+		if !minute.Equal(lastMinute) {
+			lastMinute = minute
+			startMaintenanceWindows(minute)
+		}
+		time.Sleep(10 * time.Second)
+	}
+}
+
+func startMaintenanceWindows(minute time.Time) {
+	for _, window := range db.GetMaintenanceWindows() {
+		if !window.Enabled {
+			continue
+		}
+		schedule, err := models.ParseCronSchedule(window.Schedule)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"event": "device_maintenance",
+			}).Error(fmt.Sprintf("Invalid schedule of maintenance window `%s` - %s", window.Name, err))
+			continue
+		}
+		if !schedule.Matches(minute) {
+			continue
+		}
+
+		HubDevicesData.Mu.Lock()
+		var udids []string
+		for udid, hubDevice := range HubDevicesData.Devices {
+			if hubDevice.Device.Usage != "pending" && window.AppliesTo(&hubDevice.Device) {
+				udids = append(udids, udid)
+			}
+		}
+		HubDevicesData.Mu.Unlock()
+
+		windowEnd := minute.Add(window.DurationOrDefault())
+		for _, udid := range udids {
+			go runDeviceMaintenance(window, udid, windowEnd)
+		}
+	}
+}
+
+// Take a device out of rotation, wait for its sessions to finish, reboot it through its provider and wait for it to be live again
+// The outcome is stored in the DB and published as an event
+func runDeviceMaintenance(window models.MaintenanceWindow, udid string, windowEnd time.Time) {
+	HubDevicesData.Mu.Lock()
+	hubDevice, ok := HubDevicesData.Devices[udid]
+	if !ok || hubDevice.InMaintenance {
+		HubDevicesData.Mu.Unlock()
+		return
+	}
+	hubDevice.InMaintenance = true
+	provider := hubDevice.Device.Provider
+	HubDevicesData.Mu.Unlock()
+
+	run := models.MaintenanceRun{
+		Window:    window.Name,
+		UDID:      udid,
+		Provider:  provider,
+		StartedAt: time.Now().UnixMilli(),
+	}
+	events.PublishDeviceEvent(events.DeviceMaintenanceStarted, provider, udid, map[string]string{"window": window.Name})
+
+	run.Outcome, run.Error = maintainDevice(hubDevice, &run, windowEnd)
+	run.FinishedAt = time.Now().UnixMilli()
+
+	HubDevicesData.Mu.Lock()
+	hubDevice.InMaintenance = false
+	HubDevicesData.Mu.Unlock()
+
+	err := db.AddMaintenanceRun(run)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"event": "device_maintenance",
+		}).Error(fmt.Sprintf("Failed to store maintenance run of device `%s` in DB - %s", udid, err))
+	}
+	logMessage := fmt.Sprintf("Maintenance of device `%s` in window `%s` finished with outcome `%s`", udid, window.Name, run.Outcome)
+	if run.Error != "" {
+		logMessage += " - " + run.Error
+	}
+	log.WithFields(log.Fields{
+		"event": "device_maintenance",
+	}).Info(logMessage)
+	events.PublishDeviceEvent(events.DeviceMaintenanceFinished, provider, udid, map[string]string{
+		"window":  window.Name,
+		"outcome": run.Outcome,
+		"error":   run.Error,
+	})
+}
+
+func maintainDevice(hubDevice *models.LocalHubDevice, run *models.MaintenanceRun, windowEnd time.Time) (string, string) {
+	// Sessions started before the window keep running, the device is rebooted once it is idle
+	for {
+		HubDevicesData.Mu.Lock()
+		busy := hubDevice.IsRunningAutomation || hubDevice.InUse || !hubDevice.IsAvailableForAutomation || hubDevice.Device.CleaningUp
+		live := hubDevice.Device.Connected && hubDevice.Device.ProviderState == "live"
+		HubDevicesData.Mu.Unlock()
+
+		if !busy && live {
+			break
+		}
+		if time.Now().After(windowEnd) {
+			if busy {
+				return models.MaintenanceOutcomeSkipped, "the device was still in use when the window ended"
+			}
+			return models.MaintenanceOutcomeSkipped, "the device was not live during the window"
+		}
+		time.Sleep(maintenancePollInterval)
+	}
+
+	providerConnection, ok := GetProviderConnection(run.Provider)
+	if !ok {
+		return models.MaintenanceOutcomeFailed, fmt.Sprintf("Provider `%s` is not connected to the hub", run.Provider)
+	}
+	result, err := providerConnection.SendCommand(models.ProviderCommand{Type: models.ProviderCommandRebootDevice, UDID: run.UDID}, 60*time.Second)
+	if err != nil {
+		return models.MaintenanceOutcomeFailed, err.Error()
+	}
+	if !result.Success {
+		return models.MaintenanceOutcomeFailed, result.Error
+	}
+	run.RebootedAt = time.Now().UnixMilli()
+
+	// The provider resets the device when rebooting it, it is back after it reconnected and its setup finished
+	deadline := windowEnd
+	if rebootDeadline := time.Now().Add(maintenanceRebootGrace); rebootDeadline.After(deadline) {
+		deadline = rebootDeadline
+	}
+	wentDown := false
+	for {
+		time.Sleep(maintenancePollInterval)
+
+		HubDevicesData.Mu.Lock()
+		live := hubDevice.Device.Connected && hubDevice.Device.ProviderState == "live"
+		lastSetupError := hubDevice.Device.LastSetupError
+		HubDevicesData.Mu.Unlock()
+
+		if !live {
+			wentDown = true
+		} else if wentDown {
+			return models.MaintenanceOutcomeSuccess, ""
+		}
+		if time.Now().After(deadline) {
+			message := "the device did not come back live after the reboot"
+			if lastSetupError != "" {
+				message += fmt.Sprintf(", last setup error - %s", lastSetupError)
+			}
+			return models.MaintenanceOutcomeFailed, message
+		}
+	}
+}
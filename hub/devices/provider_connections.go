@@ -0,0 +1,110 @@
+package devices
+
+import (
+	"GADS/common/models"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gobwas/ws/wsutil"
+	"github.com/google/uuid"
+)
+
+// Persistent websocket connection of a provider to the hub
+type ProviderConnection struct {
+	Nickname    string
+	ConnectedAt int64
+	conn        net.Conn
+	writeMu     sync.Mutex
+	pendingMu   sync.Mutex
+	pending     map[string]chan models.ProviderCommandResult
+}
+
+var providerConnectionsMu sync.Mutex
+var providerConnections = make(map[string]*ProviderConnection)
+
+// Register a new provider connection, replacing and closing any previous connection of the same provider
+func RegisterProviderConnection(nickname string, conn net.Conn) *ProviderConnection {
+	providerConnectionsMu.Lock()
+	defer providerConnectionsMu.Unlock()
+
+	if oldConnection, ok := providerConnections[nickname]; ok {
+		oldConnection.conn.Close()
+	}
+
+	providerConnection := &ProviderConnection{
+		Nickname:    nickname,
+		ConnectedAt: time.Now().UnixMilli(),
+		conn:        conn,
+		pending:     make(map[string]chan models.ProviderCommandResult),
+	}
+	providerConnections[nickname] = providerConnection
+	return providerConnection
+}
+
+// Remove the provider connection if it was not already replaced by a newer one
+func UnregisterProviderConnection(providerConnection *ProviderConnection) {
+	providerConnectionsMu.Lock()
+	defer providerConnectionsMu.Unlock()
+
+	if providerConnections[providerConnection.Nickname] == providerConnection {
+		delete(providerConnections, providerConnection.Nickname)
+	}
+}
+
+func GetProviderConnection(nickname string) (*ProviderConnection, bool) {
+	providerConnectionsMu.Lock()
+	defer providerConnectionsMu.Unlock()
+
+	providerConnection, ok := providerConnections[nickname]
+	return providerConnection, ok
+}
+
+// Send a command to the provider and wait for its result, the command ID is generated here
+func (pc *ProviderConnection) SendCommand(command models.ProviderCommand, timeout time.Duration) (models.ProviderCommandResult, error) {
+	command.ID = uuid.New().String()
+	data, err := json.Marshal(command)
+	if err != nil {
+		return models.ProviderCommandResult{}, fmt.Errorf("Failed to marshal command - %s", err)
+	}
+
+	resultChan := make(chan models.ProviderCommandResult, 1)
+	pc.pendingMu.Lock()
+	pc.pending[command.ID] = resultChan
+	pc.pendingMu.Unlock()
+	defer func() {
+		pc.pendingMu.Lock()
+		delete(pc.pending, command.ID)
+		pc.pendingMu.Unlock()
+	}()
+
+	pc.writeMu.Lock()
+	pc.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	err = wsutil.WriteServerText(pc.conn, data)
+	pc.writeMu.Unlock()
+	if err != nil {
+		return models.ProviderCommandResult{}, fmt.Errorf("Failed to send command to provider `%s` - %s", pc.Nickname, err)
+	}
+
+	select {
+	case result := <-resultChan:
+		return result, nil
+	case <-time.After(timeout):
+		return models.ProviderCommandResult{}, fmt.Errorf("Provider `%s` did not respond to command `%s` in %v", pc.Nickname, command.Type, timeout)
+	}
+}
+
+// Pass a command result received from the provider to the waiting sender
+func (pc *ProviderConnection) ResolveCommand(result models.ProviderCommandResult) {
+	pc.pendingMu.Lock()
+	defer pc.pendingMu.Unlock()
+
+	if resultChan, ok := pc.pending[result.ID]; ok {
+		select {
+		case resultChan <- result:
+		default:
+		}
+	}
+}
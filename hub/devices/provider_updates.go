@@ -0,0 +1,111 @@
+package devices
+
+import (
+	"GADS/common/models"
+	"GADS/hub/events"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Apply the latest data of a device received from its provider to the hub device
+// Devices that are assigned to another provider are ignored, e.g. a provider that did not sync a reassignment yet
+func ApplyProviderDevice(nickname string, providerDevice *models.Device) {
+	HubDevicesData.Mu.Lock()
+	defer HubDevicesData.Mu.Unlock()
+
+	hubDevice, ok := HubDevicesData.Devices[providerDevice.UDID]
+	if !ok || hubDevice.Device.Provider != nickname {
+		return
+	}
+
+	// Devices that were not reported since the hub started only set the baseline, they did not change state
+	firstUpdate := hubDevice.Device.LastUpdatedTimestamp == 0
+
+	// If device is not connected reset all fields that might allow it to get stuck in Running automation state
+	// If its not connected, then its not running automation or is available for automation
+	if !providerDevice.Connected {
+		if hubDevice.Device.Connected && !firstUpdate {
+			events.PublishDeviceEvent(events.DeviceDisconnected, hubDevice.Device.Provider, hubDevice.Device.UDID, nil)
+		}
+		// Keep the connection state so we only emit the event on the actual change
+		hubDevice.Device.Connected = false
+		hubDevice.Device.ProviderState = providerDevice.ProviderState
+		hubDevice.Device.NetworkConnection = providerDevice.NetworkConnection
+		hubDevice.Device.SetupSteps = providerDevice.SetupSteps
+		hubDevice.Device.LastUpdatedTimestamp = time.Now().UnixMilli()
+		hubDevice.IsAvailableForAutomation = false
+		hubDevice.IsRunningAutomation = false
+		hubDevice.InUseBy = ""
+		hubDevice.SessionID = ""
+		return
+	}
+	// Set a timestamp to indicate last time info about the device was updated from the provider
+	providerDevice.LastUpdatedTimestamp = time.Now().UnixMilli()
+
+	// Check all DB related values so if you make a change in the DB for a device
+	// The provider pushing updates will not overwrite with something wrong
+	providerDevice.Usage = hubDevice.Device.Usage
+	providerDevice.Name = hubDevice.Device.Name
+	providerDevice.OSVersion = hubDevice.Device.OSVersion
+	providerDevice.ScreenWidth = hubDevice.Device.ScreenWidth
+	providerDevice.ScreenHeight = hubDevice.Device.ScreenHeight
+	providerDevice.Provider = hubDevice.Device.Provider
+	providerDevice.Tags = hubDevice.Device.Tags
+
+	if !firstUpdate {
+		if !hubDevice.Device.Connected {
+			events.PublishDeviceEvent(events.DeviceConnected, providerDevice.Provider, providerDevice.UDID, nil)
+		}
+		if hubDevice.Device.ProviderState != providerDevice.ProviderState {
+			events.PublishDeviceEvent(events.DeviceStateChanged, providerDevice.Provider, providerDevice.UDID, map[string]string{
+				"from": hubDevice.Device.ProviderState,
+				"to":   providerDevice.ProviderState,
+			})
+		}
+		if !hubDevice.Device.Quarantined && providerDevice.Quarantined {
+			events.PublishDeviceEvent(events.DeviceQuarantined, providerDevice.Provider, providerDevice.UDID, map[string]string{
+				"setup_failures":   strconv.Itoa(providerDevice.SetupFailures),
+				"last_setup_error": providerDevice.LastSetupError,
+			})
+		}
+		if !hubDevice.Device.Unhealthy && providerDevice.Unhealthy {
+			events.PublishDeviceEvent(events.DeviceUnhealthy, providerDevice.Provider, providerDevice.UDID, map[string]string{
+				"reasons": strings.Join(providerDevice.UnhealthyReasons, ", "),
+			})
+		} else if hubDevice.Device.Unhealthy && !providerDevice.Unhealthy {
+			events.PublishDeviceEvent(events.DeviceHealthy, providerDevice.Provider, providerDevice.UDID, nil)
+		}
+	}
+
+	hubDevice.Device = *providerDevice
+}
+
+// Providers connected over websocket only send devices that changed
+// On each message refresh the update timestamp of the rest of their connected devices so they are not considered stale
+func TouchProviderDevices(nickname string) {
+	HubDevicesData.Mu.Lock()
+	defer HubDevicesData.Mu.Unlock()
+
+	now := time.Now().UnixMilli()
+	for _, hubDevice := range HubDevicesData.Devices {
+		if hubDevice.Device.Provider == nickname && hubDevice.Device.Connected {
+			hubDevice.Device.LastUpdatedTimestamp = now
+		}
+	}
+}
+
+// Mark a device the provider stopped providing as disconnected, unless it is assigned to another provider now
+func RemoveProviderDevice(nickname string, udid string) {
+	HubDevicesData.Mu.Lock()
+	defer HubDevicesData.Mu.Unlock()
+
+	hubDevice, ok := HubDevicesData.Devices[udid]
+	if !ok || hubDevice.Device.Provider != nickname {
+		return
+	}
+	if hubDevice.Device.Connected {
+		events.PublishDeviceEvent(events.DeviceDisconnected, hubDevice.Device.Provider, udid, map[string]string{"reason": "removed"})
+	}
+	markDeviceDisconnected(hubDevice)
+}
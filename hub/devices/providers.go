@@ -0,0 +1,184 @@
+package devices
+
+import (
+	"GADS/common/db"
+	"GADS/common/models"
+	"GADS/hub/events"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// Providers push device data to the hub each second, same threshold as for device availability
+	providerDegradedAfterMs = 3000
+	// After this long without any sign of life all devices of the provider are considered disconnected
+	providerOfflineAfterMs = 10000
+)
+
+type providerLiveness struct {
+	models.ProviderLiveness
+	shutdown bool // the provider announced it is going offline, reset on its next update
+}
+
+var providersLivenessMu sync.Mutex
+var providersLiveness = make(map[string]*providerLiveness)
+
+// Register a device data push from a provider
+func ProviderUpdateReceived(nickname string) {
+	if nickname == "" {
+		return
+	}
+
+	providersLivenessMu.Lock()
+	defer providersLivenessMu.Unlock()
+
+	provider, ok := providersLiveness[nickname]
+	if !ok {
+		provider = &providerLiveness{ProviderLiveness: models.ProviderLiveness{Nickname: nickname}}
+		providersLiveness[nickname] = provider
+	}
+	provider.LastHubUpdate = time.Now().UnixMilli()
+	provider.shutdown = false
+}
+
+// Mark a provider that announced its shutdown as offline right away instead of waiting for it to go stale
+// All of its devices are marked as disconnected
+func ProviderShuttingDown(nickname string) {
+	providersLivenessMu.Lock()
+	defer providersLivenessMu.Unlock()
+
+	provider, ok := providersLiveness[nickname]
+	if !ok {
+		provider = &providerLiveness{ProviderLiveness: models.ProviderLiveness{Nickname: nickname}}
+		providersLiveness[nickname] = provider
+	}
+	provider.shutdown = true
+	provider.Status = models.ProviderOffline
+	provider.StatusSince = time.Now().UnixMilli()
+
+	HubDevicesData.Mu.Lock()
+	defer HubDevicesData.Mu.Unlock()
+
+	for _, hubDevice := range HubDevicesData.Devices {
+		if hubDevice.Device.Provider != nickname {
+			continue
+		}
+		if hubDevice.Device.Connected {
+			events.PublishDeviceEvent(events.DeviceDisconnected, nickname, hubDevice.Device.UDID, map[string]string{"reason": "provider_shutdown"})
+		}
+		markDeviceDisconnected(hubDevice)
+	}
+}
+
+// Evaluate the liveness of all providers each second
+// When a provider goes offline all of its devices are marked as disconnected so they are not used for automation or remote control
+func MonitorProviders() {
+	for {
+		updateProvidersLiveness()
+		time.Sleep(1 * time.Second)
+	}
+}
+
+func updateProvidersLiveness() {
+	dbProviders := db.GetProvidersFromDB()
+	now := time.Now().UnixMilli()
+
+	providersLivenessMu.Lock()
+	defer providersLivenessMu.Unlock()
+
+	seenProviders := make(map[string]bool)
+	for _, dbProvider := range dbProviders {
+		seenProviders[dbProvider.Nickname] = true
+
+		provider, ok := providersLiveness[dbProvider.Nickname]
+		if !ok {
+			provider = &providerLiveness{ProviderLiveness: models.ProviderLiveness{Nickname: dbProvider.Nickname}}
+			providersLiveness[dbProvider.Nickname] = provider
+		}
+		provider.LastDBUpdate = dbProvider.LastUpdatedTimestamp
+		provider.LastSeen = max(provider.LastHubUpdate, provider.LastDBUpdate)
+
+		var status models.ProviderStatus
+		if provider.shutdown {
+			status = models.ProviderOffline
+		} else if now-provider.LastHubUpdate <= providerDegradedAfterMs {
+			status = models.ProviderOnline
+		} else if now-provider.LastSeen <= providerOfflineAfterMs {
+			// The provider is still alive but its device data does not reach the hub
+			status = models.ProviderDegraded
+		} else {
+			status = models.ProviderOffline
+		}
+
+		if provider.Status != status {
+			// Only report providers that actually went away, not the ones that were already offline when the hub started
+			if status == models.ProviderOffline && provider.Status != "" {
+				events.PublishDeviceEvent(events.ProviderStale, provider.Nickname, "", map[string]string{
+					"previous_status": string(provider.Status),
+					"last_seen":       time.UnixMilli(provider.LastSeen).Format(time.RFC3339),
+				})
+			}
+			provider.Status = status
+			provider.StatusSince = now
+		}
+	}
+
+	// Forget providers that were deleted from the DB
+	for nickname := range providersLiveness {
+		if !seenProviders[nickname] {
+			delete(providersLiveness, nickname)
+		}
+	}
+
+	HubDevicesData.Mu.Lock()
+	defer HubDevicesData.Mu.Unlock()
+
+	for _, provider := range providersLiveness {
+		provider.DevicesCount = 0
+		provider.ConnectedCount = 0
+	}
+	for _, hubDevice := range HubDevicesData.Devices {
+		provider, ok := providersLiveness[hubDevice.Device.Provider]
+		if !ok {
+			continue
+		}
+
+		if provider.Status == models.ProviderOffline {
+			if hubDevice.Device.Connected {
+				events.PublishDeviceEvent(events.DeviceDisconnected, provider.Nickname, hubDevice.Device.UDID, map[string]string{"reason": "provider_offline"})
+			}
+			markDeviceDisconnected(hubDevice)
+		}
+
+		provider.DevicesCount++
+		if hubDevice.Device.Connected {
+			provider.ConnectedCount++
+		}
+	}
+}
+
+func markDeviceDisconnected(hubDevice *models.LocalHubDevice) {
+	hubDevice.Device.Connected = false
+	hubDevice.Device.ProviderState = "init"
+	hubDevice.Available = false
+	hubDevice.IsAvailableForAutomation = false
+	hubDevice.IsRunningAutomation = false
+	hubDevice.InUseBy = ""
+}
+
+// Get the liveness data of all providers ordered by nickname
+func GetProvidersLiveness() []models.ProviderLiveness {
+	providersLivenessMu.Lock()
+	defer providersLivenessMu.Unlock()
+
+	providers := []models.ProviderLiveness{}
+	for _, provider := range providersLiveness {
+		providers = append(providers, provider.ProviderLiveness)
+	}
+	sort.Slice(providers, func(i, j int) bool {
+		return providers[i].Nickname < providers[j].Nickname
+	})
+
+	return providers
+}
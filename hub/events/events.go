@@ -0,0 +1,118 @@
+package events
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+type EventType string
+
+const (
+	DeviceConnected           EventType = "device.connected"
+	DeviceDisconnected        EventType = "device.disconnected"
+	DeviceStateChanged        EventType = "device.state_changed"
+	DeviceReset               EventType = "device.reset"
+	DeviceRegistered          EventType = "device.registered"
+	DeviceQuarantined         EventType = "device.quarantined"
+	DeviceUnhealthy           EventType = "device.unhealthy"
+	DeviceHealthy             EventType = "device.healthy"
+	DeviceMaintenanceStarted  EventType = "device.maintenance_started"
+	DeviceMaintenanceFinished EventType = "device.maintenance_finished"
+	SessionStarted            EventType = "session.started"
+	SessionEnded              EventType = "session.ended"
+	ProviderStale             EventType = "provider.stale"
+	WebhookTest               EventType = "webhook.test"
+)
+
+// How many events can be queued for a subscriber before new ones are dropped
+const subscriberBufferSize = 100
+
+var AllEventTypes = []EventType{
+	DeviceConnected,
+	DeviceDisconnected,
+	DeviceStateChanged,
+	DeviceReset,
+	DeviceRegistered,
+	DeviceQuarantined,
+	DeviceUnhealthy,
+	DeviceHealthy,
+	DeviceMaintenanceStarted,
+	DeviceMaintenanceFinished,
+	SessionStarted,
+	SessionEnded,
+	ProviderStale,
+}
+
+type Event struct {
+	ID        string            `json:"id"`
+	Type      EventType         `json:"type"`
+	Timestamp int64             `json:"ts"`
+	Provider  string            `json:"provider,omitempty"`
+	UDID      string            `json:"udid,omitempty"`
+	SessionID string            `json:"session_id,omitempty"`
+	Data      map[string]string `json:"data,omitempty"`
+}
+
+// New creates an event of the provided type with a unique ID and the current timestamp
+func New(eventType EventType) Event {
+	return Event{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		Timestamp: time.Now().UnixMilli(),
+	}
+}
+
+var (
+	subscribersMu sync.RWMutex
+	subscribers   []chan Event
+)
+
+// Subscribe returns a channel that receives all events published after the call
+func Subscribe() <-chan Event {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+
+	subscriber := make(chan Event, subscriberBufferSize)
+	subscribers = append(subscribers, subscriber)
+	return subscriber
+}
+
+// Publish sends the event to all subscribers without blocking
+// Events are published while holding the devices mutex so a slow subscriber should never stall the hub, we drop the event for it instead
+func Publish(event Event) {
+	subscribersMu.RLock()
+	defer subscribersMu.RUnlock()
+
+	for _, subscriber := range subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			log.WithFields(log.Fields{
+				"event": "publish_event",
+			}).Warn(fmt.Sprintf("Dropped event `%s` of type `%s`, subscriber is not keeping up", event.ID, event.Type))
+		}
+	}
+}
+
+// Helper to publish device related events with less boilerplate
+func PublishDeviceEvent(eventType EventType, provider, udid string, data map[string]string) {
+	event := New(eventType)
+	event.Provider = provider
+	event.UDID = udid
+	event.Data = data
+	Publish(event)
+}
+
+// Helper to publish Appium session related events
+func PublishSessionEvent(eventType EventType, provider, udid, sessionID string, data map[string]string) {
+	event := New(eventType)
+	event.Provider = provider
+	event.UDID = udid
+	event.SessionID = sessionID
+	event.Data = data
+	Publish(event)
+}
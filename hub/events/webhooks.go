@@ -0,0 +1,168 @@
+package events
+
+import (
+	"GADS/common/db"
+	"GADS/common/models"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	webhookMaxAttempts    = 5
+	webhookInitialBackoff = 1 * time.Second
+	// Webhooks changed outside of this hub, e.g. by another hub instance, are picked up after this long
+	webhooksCacheTTL = 30 * time.Second
+)
+
+// Webhooks are cached so publishing an event does not query the DB each time
+var (
+	webhooksCacheMu  sync.Mutex
+	webhooksCache    []models.Webhook
+	webhooksCachedAt time.Time
+)
+
+var webhookClient = &http.Client{
+	Timeout: 10 * time.Second,
+}
+
+// StartWebhookDispatcher subscribes to the event bus and delivers each event to all enabled webhooks interested in it
+func StartWebhookDispatcher() {
+	err := db.CreateCappedCollection("gads", "webhook_deliveries", 10000, 10)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"event": "webhook_dispatcher",
+		}).Error(fmt.Sprintf("Failed to create capped collection for the webhook delivery log - %s", err))
+	}
+
+	eventsChan := Subscribe()
+	go func() {
+		for event := range eventsChan {
+			for _, webhook := range cachedWebhooks() {
+				if webhook.Enabled && webhookWantsEvent(webhook, event.Type) {
+					go DeliverWebhook(webhook, event)
+				}
+			}
+		}
+	}()
+}
+
+func cachedWebhooks() []models.Webhook {
+	webhooksCacheMu.Lock()
+	defer webhooksCacheMu.Unlock()
+
+	if webhooksCachedAt.IsZero() || time.Since(webhooksCachedAt) > webhooksCacheTTL {
+		webhooksCache = db.GetWebhooks()
+		webhooksCachedAt = time.Now()
+	}
+	return webhooksCache
+}
+
+// InvalidateWebhooks makes the next event load the webhooks from the DB again, call it after webhooks are changed
+func InvalidateWebhooks() {
+	webhooksCacheMu.Lock()
+	defer webhooksCacheMu.Unlock()
+	webhooksCachedAt = time.Time{}
+}
+
+func webhookWantsEvent(webhook models.Webhook, eventType EventType) bool {
+	return len(webhook.Events) == 0 || slices.Contains(webhook.Events, string(eventType))
+}
+
+// DeliverWebhook posts the event to the webhook URL, retrying with exponential backoff on failure
+// Every attempt is stored in the delivery log
+func DeliverWebhook(webhook models.Webhook, event Event) bool {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"event": "webhook_delivery",
+		}).Error(fmt.Sprintf("Failed to marshal event `%s` for webhook `%s` - %s", event.ID, webhook.Name, err))
+		return false
+	}
+
+	backoff := webhookInitialBackoff
+	for attempt := 1; attempt <= webhookMaxAttempts; attempt++ {
+		delivery := models.WebhookDelivery{
+			Webhook:   webhook.Name,
+			EventID:   event.ID,
+			EventType: string(event.Type),
+			Attempt:   attempt,
+			Timestamp: time.Now().UnixMilli(),
+		}
+
+		retry := false
+		start := time.Now()
+		statusCode, err := postWebhook(webhook, event, payload)
+		delivery.DurationMs = time.Since(start).Milliseconds()
+		delivery.StatusCode = statusCode
+		if err != nil {
+			delivery.Error = err.Error()
+			retry = true
+		} else if statusCode < 200 || statusCode > 299 {
+			delivery.Error = fmt.Sprintf("Webhook responded with status code %v", statusCode)
+			// Other client errors will not go away by sending the same payload again
+			retry = statusCode >= 500 || statusCode == http.StatusTooManyRequests || statusCode == http.StatusRequestTimeout
+		} else {
+			delivery.Success = true
+		}
+
+		err = db.AddWebhookDelivery(delivery)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"event": "webhook_delivery",
+			}).Error(fmt.Sprintf("Failed to store delivery of event `%s` to webhook `%s` - %s", event.ID, webhook.Name, err))
+		}
+
+		if delivery.Success {
+			return true
+		}
+		if !retry || attempt == webhookMaxAttempts {
+			break
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+
+	log.WithFields(log.Fields{
+		"event": "webhook_delivery",
+	}).Warn(fmt.Sprintf("Giving up delivering event `%s` of type `%s` to webhook `%s`", event.ID, event.Type, webhook.Name))
+	return false
+}
+
+func postWebhook(webhook models.Webhook, event Event, payload []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, fmt.Errorf("Failed to create request - %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GADS-Event", string(event.Type))
+	req.Header.Set("X-GADS-Delivery", event.ID)
+	if webhook.Secret != "" {
+		req.Header.Set("X-GADS-Signature", "sha256="+SignPayload(webhook.Secret, payload))
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to send request - %s", err)
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
+
+// SignPayload returns the hex encoded HMAC-SHA256 of the payload using the webhook secret
+// Receivers can compute the same value over the raw request body to verify it was sent by the hub
+func SignPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
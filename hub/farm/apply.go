@@ -0,0 +1,329 @@
+package farm
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+
+	"GADS/common/db"
+	"GADS/common/models"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+)
+
+// Change is a single planned modification of the DB computed from the difference between the manifest and the current state
+type Change struct {
+	Action  string   // create, update or delete
+	Kind    string   // provider, device or user
+	Name    string   // nickname, udid or username
+	Details []string // human readable field differences for updates
+	apply   func() error
+}
+
+func (c Change) String() string {
+	var symbol string
+	switch c.Action {
+	case "create":
+		symbol = "+"
+	case "update":
+		symbol = "~"
+	case "delete":
+		symbol = "-"
+	}
+
+	result := fmt.Sprintf("%s %s `%s`", symbol, c.Kind, c.Name)
+	for _, detail := range c.Details {
+		result += fmt.Sprintf("\n    %s", detail)
+	}
+	return result
+}
+
+// Entry point for `GADS apply`
+func ApplyManifest(flags *pflag.FlagSet) {
+	filePath, _ := flags.GetString("file")
+	dryRun, _ := flags.GetBool("dry-run")
+	prune, _ := flags.GetBool("prune")
+	mongoDB, _ := flags.GetString("mongo-db")
+
+	if filePath == "" {
+		log.Fatalf("Please provide a manifest file via the --file flag, e.g. --file=farm.yaml")
+	}
+
+	manifest, err := LoadManifest(filePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	validationErrs := manifest.Validate()
+	if len(validationErrs) > 0 {
+		fmt.Printf("Manifest `%s` is not valid:\n", filePath)
+		for _, validationErr := range validationErrs {
+			fmt.Printf("  - %s\n", validationErr)
+		}
+		os.Exit(1)
+	}
+
+	db.InitMongoClient(mongoDB)
+	defer db.CloseMongoConn()
+
+	changes, err := Plan(manifest, prune)
+	if err != nil {
+		log.Fatalf("Failed to compute changes for manifest `%s` - %s", filePath, err)
+	}
+
+	if len(changes) == 0 {
+		fmt.Println("Farm is up to date, nothing to apply")
+		return
+	}
+
+	fmt.Printf("%v change(s) to apply:\n", len(changes))
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+
+	if dryRun {
+		fmt.Println("Dry run, no changes were applied")
+		return
+	}
+
+	failed := Apply(changes)
+	if failed > 0 {
+		fmt.Printf("%v of %v change(s) failed to apply\n", failed, len(changes))
+		os.Exit(1)
+	}
+	fmt.Printf("Successfully applied %v change(s)\n", len(changes))
+}
+
+// Plan computes the changes needed to bring the DB to the state described in the manifest
+// If prune is true, providers, devices and users missing from the manifest are deleted
+func Plan(manifest Manifest, prune bool) ([]Change, error) {
+	var changes []Change
+
+	dbProviders := make(map[string]models.Provider)
+	for _, provider := range db.GetProvidersFromDB() {
+		dbProviders[provider.Nickname] = provider
+	}
+	dbDevices := make(map[string]*models.Device)
+	dbDeviceList := db.GetDBDeviceNew()
+	for i := range dbDeviceList {
+		dbDevices[dbDeviceList[i].UDID] = &dbDeviceList[i]
+	}
+	dbUsers := make(map[string]models.User)
+	for _, user := range db.GetUsers() {
+		dbUsers[user.Username] = user
+	}
+
+	// Providers
+	manifestProviders := make(map[string]bool)
+	for _, manifestProvider := range manifest.Providers {
+		manifestProviders[manifestProvider.Nickname] = true
+
+		provider, exists := dbProviders[manifestProvider.Nickname]
+		manifestProvider.apply(&provider)
+		if !exists {
+			changes = append(changes, Change{
+				Action: "create",
+				Kind:   "provider",
+				Name:   provider.Nickname,
+				apply:  func() error { return db.AddOrUpdateProvider(provider) },
+			})
+			continue
+		}
+
+		details := diffFields(manifestProviderFromModel(dbProviders[provider.Nickname]), manifestProvider, "supervision_password")
+		if manifestProvider.SupervisionPassword != "" && manifestProvider.SupervisionPassword != dbProviders[provider.Nickname].SupervisionPassword {
+			details = append(details, "supervision_password: changed")
+		}
+		if len(details) > 0 {
+			changes = append(changes, Change{
+				Action:  "update",
+				Kind:    "provider",
+				Name:    provider.Nickname,
+				Details: details,
+				apply:   func() error { return db.AddOrUpdateProvider(provider) },
+			})
+		}
+	}
+
+	// Devices
+	manifestDevices := make(map[string]bool)
+	for _, manifestDevice := range manifest.Devices {
+		manifestDevices[manifestDevice.UDID] = true
+
+		if _, ok := dbProviders[manifestDevice.Provider]; !ok && !manifestProviders[manifestDevice.Provider] {
+			return nil, fmt.Errorf("Device `%s` is assigned to provider `%s` which is neither in the manifest nor in the DB", manifestDevice.UDID, manifestDevice.Provider)
+		}
+
+		dbDevice, exists := dbDevices[manifestDevice.UDID]
+		if !exists {
+			device := &models.Device{}
+			manifestDevice.apply(device)
+			changes = append(changes, Change{
+				Action: "create",
+				Kind:   "device",
+				Name:   device.UDID,
+				apply:  func() error { return db.UpsertDeviceDB(device) },
+			})
+			continue
+		}
+
+		currentState := manifestDeviceFromModel(dbDevice)
+		manifestDevice.apply(dbDevice)
+		details := diffFields(currentState, manifestDeviceFromModel(dbDevice))
+		if len(details) > 0 {
+			changes = append(changes, Change{
+				Action:  "update",
+				Kind:    "device",
+				Name:    dbDevice.UDID,
+				Details: details,
+				apply:   func() error { return db.UpsertDeviceDB(dbDevice) },
+			})
+		}
+	}
+
+	// Users
+	manifestUsers := make(map[string]bool)
+	for _, manifestUser := range manifest.Users {
+		manifestUsers[manifestUser.Username] = true
+
+		dbUser, exists := dbUsers[manifestUser.Username]
+		if !exists {
+			if manifestUser.Password == "" {
+				return nil, fmt.Errorf("User `%s` does not exist in the DB and no password was provided in the manifest", manifestUser.Username)
+			}
+			user := models.User{Username: manifestUser.Username, Password: manifestUser.Password, Role: manifestUser.Role}
+			changes = append(changes, Change{
+				Action: "create",
+				Kind:   "user",
+				Name:   user.Username,
+				apply:  func() error { return db.AddOrUpdateUser(user) },
+			})
+			continue
+		}
+
+		var details []string
+		user := dbUser
+		if manifestUser.Role != dbUser.Role {
+			if dbUser.Username == "admin" {
+				return nil, fmt.Errorf("The role of the default `admin` user cannot be changed")
+			}
+			details = append(details, fmt.Sprintf("role: %s -> %s", dbUser.Role, manifestUser.Role))
+			user.Role = manifestUser.Role
+		}
+		if manifestUser.Password != "" && manifestUser.Password != dbUser.Password {
+			details = append(details, "password: changed")
+			user.Password = manifestUser.Password
+		}
+		if len(details) > 0 {
+			changes = append(changes, Change{
+				Action:  "update",
+				Kind:    "user",
+				Name:    user.Username,
+				Details: details,
+				apply:   func() error { return db.AddOrUpdateUser(user) },
+			})
+		}
+	}
+
+	if prune {
+		// Delete devices before providers so we never leave devices assigned to a missing provider
+		for _, udid := range sortedKeys(dbDevices) {
+			if !manifestDevices[udid] {
+				changes = append(changes, Change{
+					Action: "delete",
+					Kind:   "device",
+					Name:   udid,
+					apply:  func() error { return db.DeleteDeviceDB(udid) },
+				})
+			}
+		}
+		for _, nickname := range sortedKeys(dbProviders) {
+			if !manifestProviders[nickname] {
+				changes = append(changes, Change{
+					Action: "delete",
+					Kind:   "provider",
+					Name:   nickname,
+					apply:  func() error { return db.DeleteProviderDB(nickname) },
+				})
+			}
+		}
+		for _, username := range sortedKeys(dbUsers) {
+			// The default admin user is never deleted
+			if !manifestUsers[username] && username != "admin" {
+				changes = append(changes, Change{
+					Action: "delete",
+					Kind:   "user",
+					Name:   username,
+					apply:  func() error { return db.DeleteUserDB(username) },
+				})
+			}
+		}
+	}
+
+	return changes, nil
+}
+
+// Apply executes the planned changes in order and returns the number of failed ones
+func Apply(changes []Change) int {
+	failed := 0
+	for _, change := range changes {
+		err := change.apply()
+		if err != nil {
+			failed++
+			fmt.Printf("Failed to %s %s `%s` - %s\n", change.Action, change.Kind, change.Name, err)
+		}
+	}
+	return failed
+}
+
+// Compare two values field by field using their JSON representation
+// Returns `field: old -> new` entries for every differing field, skipping the hidden ones
+func diffFields(oldValue, newValue interface{}, hiddenFields ...string) []string {
+	oldMap := toJSONMap(oldValue)
+	newMap := toJSONMap(newValue)
+
+	keys := make(map[string]bool)
+	for key := range oldMap {
+		keys[key] = true
+	}
+	for key := range newMap {
+		keys[key] = true
+	}
+
+	var details []string
+	for _, key := range sortedKeys(keys) {
+		oldJSON, _ := json.Marshal(oldMap[key])
+		newJSON, _ := json.Marshal(newMap[key])
+		if string(oldJSON) == string(newJSON) {
+			continue
+		}
+		if slices.Contains(hiddenFields, key) {
+			details = append(details, fmt.Sprintf("%s: changed", key))
+		} else {
+			details = append(details, fmt.Sprintf("%s: %s -> %s", key, oldJSON, newJSON))
+		}
+	}
+	return details
+}
+
+func toJSONMap(value interface{}) map[string]interface{} {
+	result := make(map[string]interface{})
+	data, err := json.Marshal(value)
+	if err != nil {
+		return result
+	}
+	json.Unmarshal(data, &result)
+	return result
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
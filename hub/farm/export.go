@@ -0,0 +1,78 @@
+package farm
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"GADS/common/db"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+)
+
+// Entry point for `GADS export`
+func ExportManifest(flags *pflag.FlagSet) {
+	filePath, _ := flags.GetString("file")
+	format, _ := flags.GetString("format")
+	mongoDB, _ := flags.GetString("mongo-db")
+
+	// If the format is not explicitly provided, decide it by the output file extension
+	if format == "" {
+		format = "yaml"
+		if isJSONFile(filePath) {
+			format = "json"
+		}
+	}
+
+	db.InitMongoClient(mongoDB)
+	defer db.CloseMongoConn()
+
+	data, err := Export().Marshal(format)
+	if err != nil {
+		log.Fatalf("Failed to export farm manifest - %s", err)
+	}
+
+	if filePath == "" {
+		fmt.Print(string(data))
+		return
+	}
+
+	err = os.WriteFile(filePath, data, 0644)
+	if err != nil {
+		log.Fatalf("Failed to write farm manifest to `%s` - %s", filePath, err)
+	}
+	fmt.Printf("Exported farm manifest to `%s`\n", filePath)
+}
+
+// Export builds a manifest from the current DB state
+// User and provider supervision passwords are never exported, applying the manifest back keeps the existing passwords
+func Export() Manifest {
+	var manifest Manifest
+
+	for _, provider := range db.GetProvidersFromDB() {
+		manifestProvider := manifestProviderFromModel(provider)
+		manifestProvider.SupervisionPassword = ""
+		manifest.Providers = append(manifest.Providers, manifestProvider)
+	}
+	sort.Slice(manifest.Providers, func(i, j int) bool {
+		return manifest.Providers[i].Nickname < manifest.Providers[j].Nickname
+	})
+
+	dbDevices := db.GetDBDeviceNew()
+	for i := range dbDevices {
+		manifest.Devices = append(manifest.Devices, manifestDeviceFromModel(&dbDevices[i]))
+	}
+	sort.Slice(manifest.Devices, func(i, j int) bool {
+		return manifest.Devices[i].UDID < manifest.Devices[j].UDID
+	})
+
+	for _, user := range db.GetUsers() {
+		manifest.Users = append(manifest.Users, ManifestUser{Username: user.Username, Role: user.Role})
+	}
+	sort.Slice(manifest.Users, func(i, j int) bool {
+		return manifest.Users[i].Username < manifest.Users[j].Username
+	})
+
+	return manifest
+}
@@ -0,0 +1,291 @@
+package farm
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"GADS/common/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest is the declarative description of a whole farm - providers, devices and users
+// It is kept in YAML or JSON and applied to the DB with `GADS apply`
+type Manifest struct {
+	Providers []ManifestProvider `json:"providers" yaml:"providers"`
+	Devices   []ManifestDevice   `json:"devices" yaml:"devices"`
+	Users     []ManifestUser     `json:"users" yaml:"users"`
+}
+
+type ManifestProvider struct {
+	Nickname            string                      `json:"nickname" yaml:"nickname"`
+	OS                  string                      `json:"os" yaml:"os"`
+	HostAddress         string                      `json:"host_address" yaml:"host_address"`
+	Port                int                         `json:"port" yaml:"port"`
+	ProvideAndroid      bool                        `json:"provide_android" yaml:"provide_android"`
+	ProvideIOS          bool                        `json:"provide_ios" yaml:"provide_ios"`
+	UseSeleniumGrid     bool                        `json:"use_selenium_grid" yaml:"use_selenium_grid"`
+	SeleniumGrid        string                      `json:"selenium_grid,omitempty" yaml:"selenium_grid,omitempty"`
+	WdaBundleID         string                      `json:"wda_bundle_id,omitempty" yaml:"wda_bundle_id,omitempty"`
+	WdaRepoPath         string                      `json:"wda_repo_path,omitempty" yaml:"wda_repo_path,omitempty"`
+	SupervisionPassword string                      `json:"supervision_password,omitempty" yaml:"supervision_password,omitempty"` // optional for existing providers, an empty password keeps the current one and it is never exported
+	UseGadsIosStream    bool                        `json:"use_gads_ios_stream" yaml:"use_gads_ios_stream"`
+	UseCustomWDA        bool                        `json:"use_custom_wda" yaml:"use_custom_wda"`
+	Emulators           []models.ProviderEmulator   `json:"emulators,omitempty" yaml:"emulators,omitempty"`
+	NetworkDevices      []string                    `json:"network_devices,omitempty" yaml:"network_devices,omitempty"`
+	AutoRegisterDevices bool                        `json:"auto_register_devices,omitempty" yaml:"auto_register_devices,omitempty"`
+	PortRanges          map[string]string           `json:"port_ranges,omitempty" yaml:"port_ranges,omitempty"`
+	Hooks               *models.ProviderHooks       `json:"hooks,omitempty" yaml:"hooks,omitempty"`
+	CleanupPolicies     []models.CleanupPolicy      `json:"cleanup_policies,omitempty" yaml:"cleanup_policies,omitempty"`
+	TelemetryThresholds *models.TelemetryThresholds `json:"telemetry_thresholds,omitempty" yaml:"telemetry_thresholds,omitempty"`
+}
+
+type ManifestDevice struct {
+	UDID         string   `json:"udid" yaml:"udid"`
+	Name         string   `json:"name" yaml:"name"`
+	OS           string   `json:"os" yaml:"os"`
+	OSVersion    string   `json:"os_version,omitempty" yaml:"os_version,omitempty"`
+	Provider     string   `json:"provider" yaml:"provider"`
+	Usage        string   `json:"usage" yaml:"usage"`
+	DeviceType   string   `json:"device_type,omitempty" yaml:"device_type,omitempty"`
+	ScreenWidth  string   `json:"screen_width,omitempty" yaml:"screen_width,omitempty"`
+	ScreenHeight string   `json:"screen_height,omitempty" yaml:"screen_height,omitempty"`
+	Tags         []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+}
+
+// ManifestUser password is optional when updating users - an empty password keeps the current one
+type ManifestUser struct {
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password,omitempty" yaml:"password,omitempty"`
+	Role     string `json:"role" yaml:"role"`
+}
+
+var validDeviceUsages = []string{"enabled", "automation", "control", "disabled", "pending"}
+
+// Load a manifest from a YAML or JSON file, the format is decided by the file extension
+func LoadManifest(filePath string) (Manifest, error) {
+	var manifest Manifest
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return manifest, fmt.Errorf("Failed to read manifest file `%s` - %s", filePath, err)
+	}
+
+	if isJSONFile(filePath) {
+		err = json.Unmarshal(data, &manifest)
+	} else {
+		err = yaml.Unmarshal(data, &manifest)
+	}
+	if err != nil {
+		return manifest, fmt.Errorf("Failed to parse manifest file `%s` - %s", filePath, err)
+	}
+
+	return manifest, nil
+}
+
+// Marshal the manifest to YAML or JSON depending on the requested format
+func (m Manifest) Marshal(format string) ([]byte, error) {
+	switch format {
+	case "json":
+		return json.MarshalIndent(m, "", "  ")
+	case "yaml", "yml":
+		return yaml.Marshal(m)
+	default:
+		return nil, fmt.Errorf("Unsupported manifest format `%s`, use `yaml` or `json`", format)
+	}
+}
+
+// Validate the manifest and return all found problems at once
+func (m Manifest) Validate() []error {
+	var errs []error
+
+	providerNames := make(map[string]bool)
+	for i, provider := range m.Providers {
+		if provider.Nickname == "" {
+			errs = append(errs, fmt.Errorf("providers[%d]: missing `nickname`", i))
+			continue
+		}
+		if providerNames[provider.Nickname] {
+			errs = append(errs, fmt.Errorf("providers[%d]: duplicate nickname `%s`", i, provider.Nickname))
+		}
+		providerNames[provider.Nickname] = true
+
+		if provider.OS == "" {
+			errs = append(errs, fmt.Errorf("provider `%s`: missing `os`", provider.Nickname))
+		}
+		if provider.HostAddress == "" {
+			errs = append(errs, fmt.Errorf("provider `%s`: missing `host_address`", provider.Nickname))
+		}
+		if provider.Port == 0 {
+			errs = append(errs, fmt.Errorf("provider `%s`: missing `port`", provider.Nickname))
+		}
+		if provider.ProvideIOS {
+			if provider.WdaBundleID == "" && (provider.OS == "windows" || provider.OS == "linux") {
+				errs = append(errs, fmt.Errorf("provider `%s`: missing `wda_bundle_id`", provider.Nickname))
+			}
+			if provider.WdaRepoPath == "" && provider.OS == "darwin" {
+				errs = append(errs, fmt.Errorf("provider `%s`: missing `wda_repo_path`", provider.Nickname))
+			}
+		}
+		if provider.UseSeleniumGrid && provider.SeleniumGrid == "" {
+			errs = append(errs, fmt.Errorf("provider `%s`: missing `selenium_grid`", provider.Nickname))
+		}
+		if err := models.ValidateProviderEmulators(provider.Emulators); err != nil {
+			errs = append(errs, fmt.Errorf("provider `%s`: %s", provider.Nickname, err))
+		}
+		if err := models.ValidateProviderNetworkDevices(provider.NetworkDevices); err != nil {
+			errs = append(errs, fmt.Errorf("provider `%s`: %s", provider.Nickname, err))
+		}
+		if err := models.ValidateProviderPortRanges(provider.PortRanges); err != nil {
+			errs = append(errs, fmt.Errorf("provider `%s`: %s", provider.Nickname, err))
+		}
+		if err := models.ValidateProviderHooks(provider.Hooks); err != nil {
+			errs = append(errs, fmt.Errorf("provider `%s`: %s", provider.Nickname, err))
+		}
+		if err := models.ValidateCleanupPolicies(provider.CleanupPolicies); err != nil {
+			errs = append(errs, fmt.Errorf("provider `%s`: %s", provider.Nickname, err))
+		}
+		if err := models.ValidateTelemetryThresholds(provider.TelemetryThresholds); err != nil {
+			errs = append(errs, fmt.Errorf("provider `%s`: %s", provider.Nickname, err))
+		}
+	}
+
+	deviceUDIDs := make(map[string]bool)
+	for i, device := range m.Devices {
+		if device.UDID == "" {
+			errs = append(errs, fmt.Errorf("devices[%d]: missing `udid`", i))
+			continue
+		}
+		if deviceUDIDs[device.UDID] {
+			errs = append(errs, fmt.Errorf("devices[%d]: duplicate udid `%s`", i, device.UDID))
+		}
+		deviceUDIDs[device.UDID] = true
+
+		if device.OS != "android" && device.OS != "ios" && device.OS != "fake" {
+			errs = append(errs, fmt.Errorf("device `%s`: invalid `os` `%s` - `android`, `ios` and `fake` are the accepted values", device.UDID, device.OS))
+		}
+		if device.Provider == "" {
+			errs = append(errs, fmt.Errorf("device `%s`: missing `provider`", device.UDID))
+		}
+		if device.Usage != "" && !slices.Contains(validDeviceUsages, device.Usage) {
+			errs = append(errs, fmt.Errorf("device `%s`: invalid `usage` `%s` - accepted values are %s", device.UDID, device.Usage, strings.Join(validDeviceUsages, ", ")))
+		}
+	}
+
+	usernames := make(map[string]bool)
+	for i, user := range m.Users {
+		if user.Username == "" {
+			errs = append(errs, fmt.Errorf("users[%d]: missing `username`", i))
+			continue
+		}
+		if usernames[user.Username] {
+			errs = append(errs, fmt.Errorf("users[%d]: duplicate username `%s`", i, user.Username))
+		}
+		usernames[user.Username] = true
+
+		if user.Role != "admin" && user.Role != "user" {
+			errs = append(errs, fmt.Errorf("user `%s`: invalid `role` `%s` - `admin` and `user` are the accepted values", user.Username, user.Role))
+		}
+	}
+
+	return errs
+}
+
+func (p ManifestProvider) apply(provider *models.Provider) {
+	provider.Nickname = p.Nickname
+	provider.OS = p.OS
+	provider.HostAddress = p.HostAddress
+	provider.Port = p.Port
+	provider.ProvideAndroid = p.ProvideAndroid
+	provider.ProvideIOS = p.ProvideIOS
+	provider.UseSeleniumGrid = p.UseSeleniumGrid
+	provider.SeleniumGrid = p.SeleniumGrid
+	provider.WdaBundleID = p.WdaBundleID
+	provider.WdaRepoPath = p.WdaRepoPath
+	if p.SupervisionPassword != "" {
+		provider.SupervisionPassword = p.SupervisionPassword
+	}
+	provider.UseGadsIosStream = p.UseGadsIosStream
+	provider.UseCustomWDA = p.UseCustomWDA
+	provider.Emulators = p.Emulators
+	provider.NetworkDevices = p.NetworkDevices
+	provider.AutoRegisterDevices = p.AutoRegisterDevices
+	provider.PortRanges = p.PortRanges
+	provider.Hooks = p.Hooks
+	provider.CleanupPolicies = p.CleanupPolicies
+	provider.TelemetryThresholds = p.TelemetryThresholds
+}
+
+func manifestProviderFromModel(provider models.Provider) ManifestProvider {
+	return ManifestProvider{
+		Nickname:            provider.Nickname,
+		OS:                  provider.OS,
+		HostAddress:         provider.HostAddress,
+		Port:                provider.Port,
+		ProvideAndroid:      provider.ProvideAndroid,
+		ProvideIOS:          provider.ProvideIOS,
+		UseSeleniumGrid:     provider.UseSeleniumGrid,
+		SeleniumGrid:        provider.SeleniumGrid,
+		WdaBundleID:         provider.WdaBundleID,
+		WdaRepoPath:         provider.WdaRepoPath,
+		SupervisionPassword: provider.SupervisionPassword,
+		UseGadsIosStream:    provider.UseGadsIosStream,
+		UseCustomWDA:        provider.UseCustomWDA,
+		Emulators:           provider.Emulators,
+		NetworkDevices:      provider.NetworkDevices,
+		AutoRegisterDevices: provider.AutoRegisterDevices,
+		PortRanges:          provider.PortRanges,
+		Hooks:               provider.Hooks,
+		CleanupPolicies:     provider.CleanupPolicies,
+		TelemetryThresholds: provider.TelemetryThresholds,
+	}
+}
+
+// Only overwrite the optional device values if they are provided in the manifest
+// Screen dimensions and OS version are usually detected by the provider on setup
+func (d ManifestDevice) apply(device *models.Device) {
+	device.UDID = d.UDID
+	device.Name = d.Name
+	device.OS = d.OS
+	device.Provider = d.Provider
+	device.Usage = d.Usage
+	if device.Usage == "" {
+		device.Usage = "enabled"
+	}
+	if d.OSVersion != "" {
+		device.OSVersion = d.OSVersion
+	}
+	if d.DeviceType != "" {
+		device.DeviceType = d.DeviceType
+	}
+	if d.ScreenWidth != "" {
+		device.ScreenWidth = d.ScreenWidth
+	}
+	if d.ScreenHeight != "" {
+		device.ScreenHeight = d.ScreenHeight
+	}
+	device.Tags = d.Tags
+}
+
+func manifestDeviceFromModel(device *models.Device) ManifestDevice {
+	return ManifestDevice{
+		UDID:         device.UDID,
+		Name:         device.Name,
+		OS:           device.OS,
+		OSVersion:    device.OSVersion,
+		Provider:     device.Provider,
+		Usage:        device.Usage,
+		DeviceType:   device.DeviceType,
+		ScreenWidth:  device.ScreenWidth,
+		ScreenHeight: device.ScreenHeight,
+		Tags:         device.Tags,
+	}
+}
+
+func isJSONFile(filePath string) bool {
+	return strings.ToLower(filepath.Ext(filePath)) == ".json"
+}
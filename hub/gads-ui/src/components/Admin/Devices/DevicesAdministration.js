@@ -258,7 +258,7 @@ function NewDevice({ providers, handleGetDeviceData }) {
                         />
                     </Tooltip>
                     <Tooltip
-                        title={<div>Intended usage of the device <br />Enabled: Can be used for automation and remote control <br />Automation: Can be used only as automation target <br />Remote control: Can be used only for remote control testing <br />Disabled: Device will not be provided</div>}
+                        title={<div>Intended usage of the device <br />Enabled: Can be used for automation and remote control <br />Automation: Can be used only as automation target <br />Remote control: Can be used only for remote control testing <br />Disabled: Device will not be provided <br />Pending approval: Device was registered by its provider and is not provided until approved</div>}
                         arrow
                         placement='top'
                     >
@@ -418,6 +418,34 @@ function ExistingDevice({ deviceData, providersData, handleGetDeviceData }) {
             })
     }
 
+    const [clearQuarantineLoading, setClearQuarantineLoading] = useState(false)
+    const [clearQuarantineStatus, setClearQuarantineStatus] = useState(null)
+
+    function handleClearQuarantine(event) {
+        setClearQuarantineLoading(true)
+        setClearQuarantineStatus(null)
+        event.preventDefault()
+
+        let url = `/admin/devices/${udid}/clear-quarantine`
+
+        api.post(url)
+            .then(() => {
+                setClearQuarantineStatus('success')
+            })
+            .catch(() => {
+                setClearQuarantineStatus('error')
+            })
+            .finally(() => {
+                setTimeout(() => {
+                    setClearQuarantineLoading(false)
+                    handleGetDeviceData()
+                    setTimeout(() => {
+                        setClearQuarantineStatus(null)
+                    }, 2000)
+                }, 1000)
+            })
+    }
+
     function handleDeleteDevice(event) {
         event.preventDefault()
 
@@ -441,7 +469,7 @@ function ExistingDevice({ deviceData, providersData, handleGetDeviceData }) {
                 width: '400px',
                 minWidth: '400px',
                 maxWidth: '400px',
-                height: '830px',
+                minHeight: '830px',
                 borderRadius: '5px',
                 backgroundColor: '#9ba984'
             }}
@@ -556,7 +584,7 @@ function ExistingDevice({ deviceData, providersData, handleGetDeviceData }) {
                         />
                     </Tooltip>
                     <Tooltip
-                        title={<div>Intended usage of the device <br />Enabled: Can be used for automation and remote control <br />Automation: Can be used only as automation target <br />Remote control: Can be used only for remote control testing <br />Disabled: Device will not be provided</div>}
+                        title={<div>Intended usage of the device <br />Enabled: Can be used for automation and remote control <br />Automation: Can be used only as automation target <br />Remote control: Can be used only for remote control testing <br />Disabled: Device will not be provided <br />Pending approval: Device was registered by its provider and is not provided until approved</div>}
                         arrow
                         placement='top'
                     >
@@ -574,6 +602,7 @@ function ExistingDevice({ deviceData, providersData, handleGetDeviceData }) {
                                 <MenuItem value='automation'>Automation</MenuItem>
                                 <MenuItem value='control'>Remote control</MenuItem>
                                 <MenuItem value='disabled'>Disabled</MenuItem>
+                                <MenuItem disabled value='pending'>Pending approval</MenuItem>
                             </TextField>
                         </FormControl>
                     </Tooltip>
@@ -645,6 +674,36 @@ function ExistingDevice({ deviceData, providersData, handleGetDeviceData }) {
                             'Re-provision device'
                         )}
                     </Button>
+                    {deviceData.quarantined &&
+                        <Tooltip
+                            title={<div>Setup failed {deviceData.setup_failures} times in a row, the provider does not set the device up until the quarantine is cleared<br />Last error: {deviceData.last_setup_error}</div>}
+                            arrow
+                            placement='top'
+                        >
+                            <Button
+                                variant="contained"
+                                style={{
+                                    backgroundColor: 'orange',
+                                    color: '#2f3b26',
+                                    fontWeight: "bold",
+                                    boxShadow: 'none',
+                                    height: '40px'
+                                }}
+                                onClick={handleClearQuarantine}
+                                disabled={clearQuarantineLoading || clearQuarantineStatus === 'success' || clearQuarantineStatus === 'error'}
+                            >
+                                {clearQuarantineLoading ? (
+                                    <CircularProgress size={25} style={{ color: '#2f3b26' }} />
+                                ) : clearQuarantineStatus === 'success' ? (
+                                    <CheckIcon size={25} style={{ color: '#2f3b26', stroke: '#2f3b26', strokeWidth: 2 }} />
+                                ) : clearQuarantineStatus === 'error' ? (
+                                    <CloseIcon size={25} style={{ color: 'red', stroke: 'red', strokeWidth: 2 }} />
+                                ) : (
+                                    'Clear quarantine'
+                                )}
+                            </Button>
+                        </Tooltip>
+                    }
                     <Button
                         onClick={() => setOpenAlert(true)}
                         style={{
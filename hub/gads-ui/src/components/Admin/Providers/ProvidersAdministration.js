@@ -72,6 +72,7 @@ function NewProvider({ handleGetProvidersData }) {
     const [wdaRepoPath, setWdaRepoPath] = useState('')
     const [wdaBundleId, setWdaBundleId] = useState('')
     const [useCustomWda, setUseCustomWda] = useState(false)
+    const [autoRegisterDevices, setAutoRegisterDevices] = useState(false)
     const [useSeleniumGrid, setUseSeleniumGrid] = useState(false)
     const [seleniumGridInstance, setSeleniumGridInstance] = useState('')
     const [loading, setLoading] = useState(false);
@@ -85,6 +86,7 @@ function NewProvider({ handleGetProvidersData }) {
         body.port = port
         body.provide_android = android
         body.provide_ios = ios
+        body.auto_register_devices = autoRegisterDevices
         if (ios) {
             body.wda_bundle_id = wdaBundleId
             body.wda_repo_path = wdaRepoPath
@@ -119,6 +121,7 @@ function NewProvider({ handleGetProvidersData }) {
                 setWdaRepoPath('')
                 setWdaBundleId('')
                 setUseCustomWda(false)
+                setAutoRegisterDevices(false)
                 setUseSeleniumGrid(false)
                 setSeleniumGridInstance('')
             })
@@ -241,6 +244,25 @@ function NewProvider({ handleGetProvidersData }) {
                             </TextField>
                         </FormControl>
                     </Tooltip>
+                    <Tooltip
+                        title='Should the provider register new connected devices? They stay pending until approved in the devices administration'
+                        arrow
+                        placement='top'
+                    >
+                        <FormControl fullWidth required>
+                            <TextField
+                                value={autoRegisterDevices}
+                                onChange={(e) => setAutoRegisterDevices(e.target.value)}
+                                select
+                                label='Auto-register devices?'
+                                required
+                                size='small'
+                            >
+                                <MenuItem value={true}>Yes</MenuItem>
+                                <MenuItem value={false}>No</MenuItem>
+                            </TextField>
+                        </FormControl>
+                    </Tooltip>
                     <Tooltip
                         title='WebDriverAgent bundle identifier, e.g. com.facebook.WebDriverAgentRunner.xctrunner'
                         arrow
@@ -364,6 +386,7 @@ function ExistingProvider({ providerData, handleGetProvidersData }) {
     const [wdaRepoPath, setWdaRepoPath] = useState(providerData.wda_repo_path)
     const [wdaBundleId, setWdaBundleId] = useState(providerData.wda_bundle_id)
     const [useCustomWda, setUseCustomWda] = useState(providerData.use_custom_wda)
+    const [autoRegisterDevices, setAutoRegisterDevices] = useState(providerData.auto_register_devices)
     const [useSeleniumGrid, setUseSeleniumGrid] = useState(providerData.use_selenium_grid)
     const [seleniumGridInstance, setSeleniumGridInstance] = useState(providerData.selenium_grid)
 
@@ -395,6 +418,7 @@ function ExistingProvider({ providerData, handleGetProvidersData }) {
         body.port = port
         body.provide_android = android
         body.provide_ios = ios
+        body.auto_register_devices = autoRegisterDevices
         if (ios) {
             body.wda_bundle_id = wdaBundleId
             body.wda_repo_path = wdaRepoPath
@@ -542,6 +566,25 @@ function ExistingProvider({ providerData, handleGetProvidersData }) {
                             </TextField>
                         </FormControl>
                     </Tooltip>
+                    <Tooltip
+                        title='Should the provider register new connected devices? They stay pending until approved in the devices administration'
+                        arrow
+                        placement='top'
+                    >
+                        <FormControl fullWidth required>
+                            <TextField
+                                value={autoRegisterDevices}
+                                onChange={(e) => setAutoRegisterDevices(e.target.value)}
+                                select
+                                label='Auto-register devices?'
+                                required
+                                size='small'
+                            >
+                                <MenuItem value={true}>Yes</MenuItem>
+                                <MenuItem value={false}>No</MenuItem>
+                            </TextField>
+                        </FormControl>
+                    </Tooltip>
                     <Tooltip
                         title='WebDriverAgent bundle identifier, e.g. com.facebook.WebDriverAgentRunner.xctrunner'
                         arrow
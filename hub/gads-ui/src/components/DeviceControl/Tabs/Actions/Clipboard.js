@@ -7,6 +7,7 @@ import {useState} from "react";
 export default function Clipboard({ deviceData }) {
     const [isGettingCb, setIsGettingCb] = useState(false)
     const [cbValue, setCbValue] = useState("")
+    const [isSettingCb, setIsSettingCb] = useState(false)
 
     function handleGetClipboard() {
         setIsGettingCb(true)
@@ -22,6 +23,18 @@ export default function Clipboard({ deviceData }) {
             });
     }
 
+    function handleSetClipboard(endpoint) {
+        setIsSettingCb(true)
+        const url = `/device/${deviceData.udid}/${endpoint}`
+        api.post(url, {content: cbValue})
+            .then(() => {
+                setIsSettingCb(false)
+            })
+            .catch(() => {
+                setIsSettingCb(false)
+            });
+    }
+
     return (
         <Box
             marginTop='10px'
@@ -37,9 +50,9 @@ export default function Clipboard({ deviceData }) {
                     marginRight: "10px"
                 }}
             >
-                <h3>Get clipboard value</h3>
+                <h3>Clipboard</h3>
                 {deviceData.os === "ios" &&
-                    <h5 style={{marginTop: "1px"}}>On iOS devices WebDriverAgent has to be the active app to get the pasteboard value, it will be activated and then you'll be navigated to Springboard</h5>
+                    <h5 style={{marginTop: "1px"}}>On iOS devices WebDriverAgent has to be the active app to get the pasteboard value, it will be activated and then the previously active app is restored</h5>
                 }
                 <Button
                     onClick={handleGetClipboard}
@@ -62,12 +75,39 @@ export default function Clipboard({ deviceData }) {
                     id="outlined-basic"
                     variant="outlined"
                     value={cbValue}
+                    onChange={(event) => setCbValue(event.target.value)}
                     style={{
                         backgroundColor: '#9ba984',
                         marginTop: '15px',
                         width: '100%'
                     }}
                 />
+                <Stack direction='row' spacing={1} style={{marginTop: '15px'}}>
+                    <Button
+                        onClick={() => handleSetClipboard('setClipboard')}
+                        variant='contained'
+                        disabled={isSettingCb || cbValue === ""}
+                        style={{
+                            backgroundColor: "#2f3b26",
+                            color: "#9ba984",
+                            fontWeight: "bold"
+                        }}
+                    >
+                        Set clipboard
+                    </Button>
+                    <Button
+                        onClick={() => handleSetClipboard('paste')}
+                        variant='contained'
+                        disabled={isSettingCb || cbValue === ""}
+                        style={{
+                            backgroundColor: "#2f3b26",
+                            color: "#9ba984",
+                            fontWeight: "bold"
+                        }}
+                    >
+                        Paste
+                    </Button>
+                </Stack>
             </Stack>
         </Box>
     )
@@ -0,0 +1,170 @@
+import { useEffect, useState } from "react";
+import { Alert, Box, Button, CircularProgress, List, ListItemButton, ListItemIcon, ListItemText, Stack, TextField } from "@mui/material";
+import FolderIcon from '@mui/icons-material/Folder';
+import DescriptionIcon from '@mui/icons-material/Description';
+import AttachFileIcon from '@mui/icons-material/AttachFile';
+import { api } from '../../../../services/api.js'
+
+export default function Files({ deviceData }) {
+    const [currentPath, setCurrentPath] = useState('/')
+    const [app, setApp] = useState('')
+    const [files, setFiles] = useState([])
+    const [isLoading, setIsLoading] = useState(false)
+    const [isUploading, setIsUploading] = useState(false)
+    const [error, setError] = useState(null)
+
+    function showError(err) {
+        if (err.response && err.response.data && err.response.data.error) {
+            setError(err.response.data.error)
+        } else {
+            setError('Request failed')
+        }
+    }
+
+    function loadFiles(path) {
+        setIsLoading(true)
+        setError(null)
+        api.get(`/device/${deviceData.udid}/files`, { params: { path: path, app: app } })
+            .then((response) => {
+                setCurrentPath(response.data.path)
+                setFiles(response.data.files.sort((a, b) => (b.is_dir - a.is_dir) || a.name.localeCompare(b.name)))
+            })
+            .catch((err) => showError(err))
+            .finally(() => setIsLoading(false))
+    }
+
+    useEffect(() => {
+        loadFiles('/')
+    }, [])
+
+    function parentPath() {
+        const parent = currentPath.substring(0, currentPath.lastIndexOf('/'))
+        return parent === '' ? '/' : parent
+    }
+
+    function handleDownload(file) {
+        setError(null)
+        api.get(`/device/${deviceData.udid}/files/pull`, { params: { path: file.path, app: app }, responseType: 'blob' })
+            .then((response) => {
+                const url = URL.createObjectURL(response.data)
+                const link = document.createElement('a')
+                link.href = url
+                link.download = file.name
+                link.click()
+                URL.revokeObjectURL(url)
+            })
+            .catch(() => setError(`Failed to download ${file.name}`))
+    }
+
+    function handleUpload(event) {
+        if (!event.target.files || event.target.files.length === 0) {
+            return
+        }
+        const formData = new FormData()
+        formData.append('file', event.target.files[0])
+        formData.append('path', currentPath.endsWith('/') ? currentPath : currentPath + '/')
+        formData.append('app', app)
+        event.target.value = ''
+
+        setIsUploading(true)
+        setError(null)
+        api.post(`/device/${deviceData.udid}/files/push`, formData, {
+            headers: {
+                'Content-Type': 'multipart/form-data'
+            }
+        })
+            .then(() => loadFiles(currentPath))
+            .catch((err) => showError(err))
+            .finally(() => setIsUploading(false))
+    }
+
+    return (
+        <Box
+            marginTop='10px'
+            style={{
+                backgroundColor: '#9ba984',
+                width: '600px'
+            }}
+        >
+            <Stack
+                style={{
+                    marginLeft: '10px',
+                    marginRight: '10px',
+                    marginBottom: '10px'
+                }}
+            >
+                <h3>Device files</h3>
+                <h5 style={{ marginTop: '1px' }}>
+                    {deviceData.os === 'ios'
+                        ? 'Paths are relative to the media directory, provide a bundle ID to browse the container of an app signed with a development profile'
+                        : 'Paths are relative to the shared storage, pushed images and videos are added to the gallery'}
+                </h5>
+                {deviceData.os === 'ios' &&
+                    <TextField
+                        label='App bundle ID'
+                        variant='outlined'
+                        size='small'
+                        value={app}
+                        onChange={(event) => setApp(event.target.value)}
+                        onKeyUp={(event) => event.keyCode === 13 && loadFiles('/')}
+                        style={{ marginBottom: '10px' }}
+                    />
+                }
+                <Stack direction='row' spacing={1} alignItems='center'>
+                    <TextField
+                        label='Path'
+                        variant='outlined'
+                        size='small'
+                        value={currentPath}
+                        onChange={(event) => setCurrentPath(event.target.value)}
+                        onKeyUp={(event) => event.keyCode === 13 && loadFiles(currentPath)}
+                        style={{ flexGrow: 1 }}
+                    />
+                    <Button
+                        component='label'
+                        variant='contained'
+                        startIcon={<AttachFileIcon />}
+                        disabled={isUploading}
+                        style={{
+                            backgroundColor: '#2f3b26',
+                            color: '#9ba984',
+                            fontWeight: 'bold'
+                        }}
+                    >
+                        <input
+                            type='file'
+                            hidden
+                            onChange={(event) => handleUpload(event)}
+                        />
+                        {isUploading ? <CircularProgress size={25} style={{ color: '#f4e6cd' }} /> : 'Upload'}
+                    </Button>
+                </Stack>
+                {error && <Alert severity='error' style={{ marginTop: '10px' }}>{error}</Alert>}
+                {isLoading ? (
+                    <CircularProgress style={{ margin: '10px auto' }} />
+                ) : (
+                    <List dense={true}>
+                        {currentPath !== '/' &&
+                            <ListItemButton onClick={() => loadFiles(parentPath())}>
+                                <ListItemIcon><FolderIcon /></ListItemIcon>
+                                <ListItemText primary='..' />
+                            </ListItemButton>
+                        }
+                        {files.map((file) => (
+                            <ListItemButton
+                                key={file.path}
+                                onClick={() => file.is_dir ? loadFiles(file.path) : handleDownload(file)}
+                            >
+                                <ListItemIcon>{file.is_dir ? <FolderIcon /> : <DescriptionIcon />}</ListItemIcon>
+                                <ListItemText
+                                    primary={file.name}
+                                    secondary={file.is_dir ? null : (file.size / (1024 * 1024)).toFixed(2) + ' mb'}
+                                />
+                            </ListItemButton>
+                        ))}
+                    </List>
+                )}
+            </Stack>
+        </Box>
+    )
+}
@@ -4,6 +4,7 @@ import { useState } from "react"
 import Screenshot from "./Screenshot/Screenshot"
 import Actions from "./Actions/Actions"
 import AppiumLogsTable from "./Logs/AppiumLogsTable";
+import Files from "./Files/Files";
 
 export default function TabularControl({ deviceData }) {
     const udid = deviceData.udid
@@ -51,10 +52,16 @@ export default function TabularControl({ deviceData }) {
                     label='Appium Logs'
                     style={{ fontWeight: "bold" }}
                 />
+                <Tab
+                    className='control-tabs'
+                    label='Files'
+                    style={{ fontWeight: "bold" }}
+                />
             </Tabs>
             {currentTabIndex === 1 && <Screenshot udid={udid} screenshots={screenshots} setScreenshots={setScreenshots} />}
             {currentTabIndex === 0 && <Actions deviceData={deviceData} />}
             {currentTabIndex === 2 && <AppiumLogsTable udid={udid} />}
+            {currentTabIndex === 3 && <Files deviceData={deviceData} />}
         </Box >
     )
 }
\ No newline at end of file
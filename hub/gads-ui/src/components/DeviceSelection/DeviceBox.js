@@ -156,6 +156,38 @@ function DeviceStatus({ device }) {
             }
         }
     } else {
+        if (device.in_maintenance) {
+            return (
+                <div
+                    className='offline-status'
+                >Maintenance</div>
+            )
+        }
+        // Show the setup step a connected device is currently on so it is visible where it is stuck
+        const setupSteps = device.info.setup_steps || []
+        if (device.info.connected && device.info.provider_state === 'preparing' && setupSteps.length > 0) {
+            return (
+                <div className='offline-status'>
+                    <div>Preparing</div>
+                    <div style={{ marginTop: '5px' }}>{setupSteps[setupSteps.length - 1].name}</div>
+                </div>
+            )
+        }
+        if (device.info.connected && device.info.unhealthy) {
+            return (
+                <div className='offline-status'>
+                    <div>Unhealthy</div>
+                    <div style={{ marginTop: '5px' }}>{(device.info.unhealthy_reasons || []).join(', ')}</div>
+                </div>
+            )
+        }
+        if (device.info.connected && device.info.cleaning_up) {
+            return (
+                <div
+                    className='offline-status'
+                >Cleaning up</div>
+            )
+        }
         return (
             <div
                 className='offline-status'
@@ -4,6 +4,7 @@ import (
 	"GADS/common/db"
 	"GADS/common/models"
 	"GADS/hub/devices"
+	"GADS/hub/events"
 	"GADS/hub/router"
 	"embed"
 	"fmt"
@@ -65,11 +66,18 @@ func StartHub(flags *pflag.FlagSet, appVersion string) {
 	// Create a new connection to MongoDB
 	db.InitMongoClient(mongoDB)
 
+	// Deliver device, session and provider events to the configured webhooks
+	events.StartWebhookDispatcher()
+
 	devices.InitHubDevicesData()
 	// Start a goroutine that continuously gets the latest devices data from MongoDB
 	go devices.GetLatestDBDevices()
+	// Start a goroutine that tracks provider liveness and disconnects the devices of offline providers
+	go devices.MonitorProviders()
 	// Start a goroutine to clean hanging grid sessions
 	go router.UpdateExpiredGridSessions()
+	// Start a goroutine that takes devices out of rotation and reboots them in their maintenance windows
+	go devices.MaintainDevices()
 
 	defer db.MongoCtxCancel()
 
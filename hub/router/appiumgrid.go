@@ -3,11 +3,13 @@ package router
 import (
 	"GADS/common/models"
 	"GADS/hub/devices"
+	"GADS/hub/events"
 	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -28,6 +30,7 @@ type CommonCapabilities struct {
 	DeviceUDID        string `json:"appium:udid"`
 	NewCommandTimeout int64  `json:"appium:newCommandTimeout"`
 	SessionTimeout    int64  `json:"appium:sessionTimeout"`
+	DeviceType        string `json:"gads:deviceType"`
 }
 
 type AppiumSession struct {
@@ -62,9 +65,19 @@ func UpdateExpiredGridSessions() {
 			// Reset device if its not connected
 			// Or it hasn't received any Appium requests in the command timeout and is running automation
 			// Or if its provider state is not "live" - device was re-provisioned for example
-			if !hubDevice.Device.Connected ||
-				(hubDevice.LastAutomationActionTS <= (time.Now().UnixMilli()-hubDevice.AppiumNewCommandTimeout) && hubDevice.IsRunningAutomation) ||
-				hubDevice.Device.ProviderState != "live" {
+			var endReason string
+			if !hubDevice.Device.Connected {
+				endReason = "disconnected"
+			} else if hubDevice.LastAutomationActionTS <= (time.Now().UnixMilli()-hubDevice.AppiumNewCommandTimeout) && hubDevice.IsRunningAutomation {
+				endReason = "timeout"
+			} else if hubDevice.Device.ProviderState != "live" {
+				endReason = "provider_state"
+			}
+
+			if endReason != "" {
+				if hubDevice.SessionID != "" {
+					events.PublishSessionEvent(events.SessionEnded, hubDevice.Device.Provider, hubDevice.Device.UDID, hubDevice.SessionID, map[string]string{"reason": endReason})
+				}
 				hubDevice.IsRunningAutomation = false
 				hubDevice.IsAvailableForAutomation = true
 				hubDevice.SessionID = ""
@@ -109,6 +122,10 @@ func AppiumGridMiddleware() gin.HandlerFunc {
 				c.JSON(http.StatusBadRequest, createErrorResponse("GADS did not find any suitable capabilities object in the session request, check your setup or open an issues on the project Github page", "", ""))
 				return
 			}
+			// The GADS specific capabilities can be in alwaysMatch even when the platform is matched in firstMatch
+			if capsToUse.DeviceType == "" {
+				capsToUse.DeviceType = appiumSessionBody.Capabilities.AlwaysMatch.DeviceType
+			}
 
 			// Check for available device
 			var foundDevice *models.LocalHubDevice
@@ -229,6 +246,10 @@ func AppiumGridMiddleware() gin.HandlerFunc {
 
 			devices.HubDevicesData.Mu.Lock()
 			foundDevice.SessionID = proxySessionResponse.Value.SessionID
+			events.PublishSessionEvent(events.SessionStarted, foundDevice.Device.Provider, foundDevice.Device.UDID, foundDevice.SessionID, map[string]string{
+				"platform_name":   capsToUse.PlatformName,
+				"automation_name": capsToUse.AutomationName,
+			})
 			devices.HubDevicesData.Mu.Unlock()
 
 			// Copy the response back to the original client
@@ -329,6 +350,13 @@ func AppiumGridMiddleware() gin.HandlerFunc {
 			if c.Request.Method == http.MethodDelete {
 				devices.HubDevicesData.Mu.Lock()
 				foundDevice.IsAvailableForAutomation = true
+				if resp.StatusCode < 300 {
+					events.PublishSessionEvent(events.SessionEnded, foundDevice.Device.Provider, foundDevice.Device.UDID, sessionID, map[string]string{"reason": "deleted"})
+				}
+				// The provider holds the device while it cleans it up, keep it unavailable until the provider reports the cleanup finished
+				if resp.Header.Get(models.DeviceCleanupHeader) == "true" {
+					foundDevice.Device.CleaningUp = true
+				}
 				devices.HubDevicesData.Mu.Unlock()
 				// Start a goroutine that will release the device after 10 seconds if no other actions were taken
 				go func() {
@@ -418,7 +446,7 @@ func findAvailableDevice(caps CommonCapabilities) (*models.LocalHubDevice, error
 
 	if deviceUDID != "" {
 		foundDevice, _ := getDeviceByUDID(deviceUDID)
-		if foundDevice.IsAvailableForAutomation {
+		if foundDevice.IsAvailableForAutomation && !foundDevice.Device.CleaningUp && !foundDevice.Device.Unhealthy && !foundDevice.InMaintenance {
 			foundDevice.IsAvailableForAutomation = false
 			return foundDevice, nil
 		} else {
@@ -440,6 +468,9 @@ func findAvailableDevice(caps CommonCapabilities) (*models.LocalHubDevice, error
 					localDevice.Device.ProviderState == "live" &&
 					localDevice.Device.LastUpdatedTimestamp >= (time.Now().UnixMilli()-3000) &&
 					localDevice.IsAvailableForAutomation &&
+					!localDevice.Device.CleaningUp &&
+					!localDevice.Device.Unhealthy &&
+					!localDevice.InMaintenance &&
 					localDevice.Device.Usage != "control" &&
 					localDevice.Device.Usage != "disabled" {
 					availableDevices = append(availableDevices, localDevice)
@@ -457,6 +488,9 @@ func findAvailableDevice(caps CommonCapabilities) (*models.LocalHubDevice, error
 					localDevice.Device.ProviderState == "live" &&
 					localDevice.Device.LastUpdatedTimestamp >= (time.Now().UnixMilli()-3000) &&
 					localDevice.IsAvailableForAutomation &&
+					!localDevice.Device.CleaningUp &&
+					!localDevice.Device.Unhealthy &&
+					!localDevice.InMaintenance &&
 					localDevice.Device.Usage != "control" &&
 					localDevice.Device.Usage != "disabled" {
 					availableDevices = append(availableDevices, localDevice)
@@ -464,6 +498,18 @@ func findAvailableDevice(caps CommonCapabilities) (*models.LocalHubDevice, error
 			}
 		}
 
+		// If we have `gads:deviceType` capability provided, keep only the devices of that type - `real` or `emulator`
+		// Devices without a type in the DB are considered real
+		if caps.DeviceType != "" {
+			availableDevices = slices.DeleteFunc(availableDevices, func(device *models.LocalHubDevice) bool {
+				deviceType := device.Device.DeviceType
+				if deviceType == "" {
+					deviceType = "real"
+				}
+				return !strings.EqualFold(deviceType, caps.DeviceType)
+			})
+		}
+
 		// If we have `appium:platformVersion` capability provided, then we want to filter out the devices even more
 		// Loop through the accumulated available devices slice and get a device that matches the platform version
 		if caps.PlatformVersion != "" {
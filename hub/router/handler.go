@@ -36,7 +36,7 @@ func HandleRequests() *gin.Engine {
 	authGroup.GET("/available-devices", AvailableDevicesSSE)
 	authGroup.GET("/admin/provider/:nickname/info", ProviderInfoSSE)
 	authGroup.GET("/devices/control/:udid/in-use", DeviceInUseWS)
-	authGroup.POST("/provider-update", ProviderUpdate)
+	authGroup.GET("/provider-ws", ProviderWS)
 	// Enable authentication on the endpoints below
 	authGroup.Use(auth.AuthMiddleware())
 	authGroup.GET("/appium-logs", GetAppiumLogs)
@@ -50,15 +50,31 @@ func HandleRequests() *gin.Engine {
 	authGroup.POST("/admin/providers/update", UpdateProvider)
 	authGroup.DELETE("/admin/providers/:nickname", DeleteProvider)
 	authGroup.GET("/admin/providers/logs", GetProviderLogs)
+	authGroup.GET("/admin/providers/status", GetProvidersStatus)
+	authGroup.POST("/admin/providers/:nickname/reload", ReloadProviderConfig)
+	authGroup.POST("/admin/providers/:nickname/emulators/:avd/:action", ProviderEmulatorAction)
 	authGroup.POST("/admin/device", AddDevice)
 	authGroup.PUT("/admin/device", UpdateDevice)
 	authGroup.DELETE("/admin/device/:udid", DeleteDevice)
 	authGroup.GET("/admin/devices", GetDevices)
+	authGroup.GET("/admin/devices/pending", GetPendingDevices)
+	authGroup.POST("/admin/devices/:udid/approve", ApprovePendingDevice)
+	authGroup.POST("/admin/devices/:udid/reject", RejectPendingDevice)
+	authGroup.POST("/admin/devices/:udid/clear-quarantine", ClearDeviceQuarantine)
 	authGroup.POST("/admin/user", AddUser)
 	authGroup.GET("/admin/users", GetUsers)
 	authGroup.POST("/admin/upload-selenium-jar", UploadSeleniumJar)
 	authGroup.PUT("/admin/user", UpdateUser)
 	authGroup.DELETE("/admin/user/:nickname", DeleteUser)
+	authGroup.GET("/admin/webhooks", GetWebhooks)
+	authGroup.POST("/admin/webhooks", AddOrUpdateWebhook)
+	authGroup.GET("/admin/webhooks/deliveries", GetWebhookDeliveries)
+	authGroup.DELETE("/admin/webhooks/:name", DeleteWebhook)
+	authGroup.POST("/admin/webhooks/:name/test", TestWebhook)
+	authGroup.GET("/admin/maintenance-windows", GetMaintenanceWindows)
+	authGroup.POST("/admin/maintenance-windows", AddOrUpdateMaintenanceWindow)
+	authGroup.GET("/admin/maintenance-windows/runs", GetMaintenanceRuns)
+	authGroup.DELETE("/admin/maintenance-windows/:name", DeleteMaintenanceWindow)
 	appiumGroup := r.Group("/grid")
 	appiumGroup.Use(AppiumGridMiddleware())
 	appiumGroup.Any("/*path")
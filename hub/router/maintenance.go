@@ -0,0 +1,74 @@
+package router
+
+import (
+	"GADS/common/db"
+	"GADS/common/models"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func GetMaintenanceWindows(c *gin.Context) {
+	OkJSON(c, db.GetMaintenanceWindows())
+}
+
+func AddOrUpdateMaintenanceWindow(c *gin.Context) {
+	var window models.MaintenanceWindow
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		InternalServerError(c, fmt.Sprintf("%s", err))
+		return
+	}
+
+	err = json.Unmarshal(body, &window)
+	if err != nil {
+		BadRequest(c, fmt.Sprintf("%s", err))
+		return
+	}
+
+	err = models.ValidateMaintenanceWindow(window)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
+	err = db.AddOrUpdateMaintenanceWindow(window)
+	if err != nil {
+		InternalServerError(c, fmt.Sprintf("Failed to add/update maintenance window in DB - %s", err))
+		return
+	}
+	OK(c, fmt.Sprintf("Successfully added/updated maintenance window `%s`", window.Name))
+}
+
+func DeleteMaintenanceWindow(c *gin.Context) {
+	name := c.Param("name")
+
+	err := db.DeleteMaintenanceWindowDB(name)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			NotFound(c, fmt.Sprintf("No maintenance window with name `%s` found", name))
+			return
+		}
+		InternalServerError(c, fmt.Sprintf("Failed to delete maintenance window from DB - %s", err))
+		return
+	}
+	OK(c, fmt.Sprintf("Successfully deleted maintenance window `%s`", name))
+}
+
+func GetMaintenanceRuns(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit <= 0 || limit > 1000 {
+		limit = 1000
+	}
+
+	runs, err := db.GetMaintenanceRuns(c.Query("udid"), int64(limit))
+	if err != nil {
+		InternalServerError(c, fmt.Sprintf("Failed to get maintenance runs from DB - %s", err))
+		return
+	}
+	OkJSON(c, runs)
+}
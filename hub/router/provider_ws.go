@@ -0,0 +1,247 @@
+package router
+
+import (
+	"GADS/common/db"
+	"GADS/common/models"
+	"GADS/hub/devices"
+	"GADS/hub/events"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+	log "github.com/sirupsen/logrus"
+)
+
+// Providers send at least a heartbeat each second, consider the connection dead after this long without messages
+const providerWSReadTimeout = 10 * time.Second
+
+// Persistent connection on which providers push device updates and receive commands from the hub
+func ProviderWS(c *gin.Context) {
+	nickname := c.GetHeader("X-Provider-Nickname")
+	token := c.GetHeader("X-Provider-Token")
+
+	provider, err := db.GetProviderFromDB(nickname)
+	if err != nil || provider.HubToken == "" || subtle.ConstantTimeCompare([]byte(provider.HubToken), []byte(token)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid provider nickname or token"})
+		return
+	}
+
+	conn, _, _, err := ws.UpgradeHTTP(c.Request, c.Writer)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"event": "provider_ws",
+		}).Error(fmt.Sprintf("Failed upgrading websocket for provider `%s` - %s", nickname, err))
+		return
+	}
+	defer conn.Close()
+
+	providerConnection := devices.RegisterProviderConnection(nickname, conn)
+	defer devices.UnregisterProviderConnection(providerConnection)
+	log.WithFields(log.Fields{
+		"event": "provider_ws",
+	}).Info(fmt.Sprintf("Provider `%s` connected", nickname))
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(providerWSReadTimeout))
+		data, op, err := wsutil.ReadClientData(conn)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"event": "provider_ws",
+			}).Warn(fmt.Sprintf("Provider `%s` disconnected - %s", nickname, err))
+			return
+		}
+		if op == ws.OpClose {
+			return
+		}
+
+		var message models.ProviderMessage
+		err = json.Unmarshal(data, &message)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"event": "provider_ws",
+			}).Error(fmt.Sprintf("Failed to unmarshal message from provider `%s` - %s", nickname, err))
+			continue
+		}
+
+		devices.ProviderUpdateReceived(nickname)
+		switch message.Type {
+		case models.ProviderMessageDevices:
+			for _, providerDevice := range message.Devices {
+				devices.ApplyProviderDevice(nickname, providerDevice)
+			}
+			for _, udid := range message.RemovedDevices {
+				devices.RemoveProviderDevice(nickname, udid)
+			}
+		case models.ProviderMessageCommandResult:
+			if message.CommandResult != nil {
+				providerConnection.ResolveCommand(*message.CommandResult)
+			}
+		case models.ProviderMessageShutdown:
+			log.WithFields(log.Fields{
+				"event": "provider_ws",
+			}).Info(fmt.Sprintf("Provider `%s` is shutting down", nickname))
+			devices.ProviderShuttingDown(nickname)
+			return
+		}
+		devices.TouchProviderDevices(nickname)
+	}
+}
+
+// Ask a provider to reload its configuration from the DB
+func ReloadProviderConfig(c *gin.Context) {
+	nickname := c.Param("nickname")
+
+	providerConnection, ok := devices.GetProviderConnection(nickname)
+	if !ok {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": fmt.Sprintf("Provider `%s` is not connected to the hub", nickname)})
+		return
+	}
+
+	result, err := providerConnection.SendCommand(models.ProviderCommand{Type: models.ProviderCommandReloadConfig}, 30*time.Second)
+	if err != nil {
+		InternalServerError(c, err.Error())
+		return
+	}
+	if !result.Success {
+		InternalServerError(c, result.Error)
+		return
+	}
+	OK(c, fmt.Sprintf("Provider `%s` reloaded its configuration", nickname))
+}
+
+// Notify the providers that their device records changed so they apply the changes without waiting for the next periodic sync
+// Providers that are not connected pick the changes up when they connect or on the periodic sync
+func notifyProvidersDevicesChanged(nicknames ...string) {
+	slices.Sort(nicknames)
+	for _, nickname := range slices.Compact(nicknames) {
+		if nickname == "" {
+			continue
+		}
+		providerConnection, ok := devices.GetProviderConnection(nickname)
+		if !ok {
+			continue
+		}
+		go func(nickname string) {
+			result, err := providerConnection.SendCommand(models.ProviderCommand{Type: models.ProviderCommandSyncDevices}, 10*time.Second)
+			if err == nil && !result.Success {
+				err = fmt.Errorf("%s", result.Error)
+			}
+			if err != nil {
+				log.WithFields(log.Fields{
+					"event": "sync_provider_devices",
+				}).Warn(fmt.Sprintf("Failed to notify provider `%s` about changed devices - %s", nickname, err))
+			}
+		}(nickname)
+	}
+}
+
+// Start or stop a configured emulator of a provider
+func ProviderEmulatorAction(c *gin.Context) {
+	nickname := c.Param("nickname")
+	avd := c.Param("avd")
+
+	var commandType, done string
+	switch c.Param("action") {
+	case "start":
+		commandType = models.ProviderCommandStartEmulator
+		done = "started"
+	case "stop":
+		commandType = models.ProviderCommandStopEmulator
+		done = "stopped"
+	default:
+		NotFound(c, fmt.Sprintf("Unknown emulator action `%s`, use `start` or `stop`", c.Param("action")))
+		return
+	}
+
+	providerConnection, ok := devices.GetProviderConnection(nickname)
+	if !ok {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": fmt.Sprintf("Provider `%s` is not connected to the hub", nickname)})
+		return
+	}
+
+	result, err := providerConnection.SendCommand(models.ProviderCommand{Type: commandType, AVD: avd}, 30*time.Second)
+	if err != nil {
+		InternalServerError(c, err.Error())
+		return
+	}
+	if !result.Success {
+		InternalServerError(c, result.Error)
+		return
+	}
+	OK(c, fmt.Sprintf("Emulator `%s` on provider `%s` %s", avd, nickname, done))
+}
+
+// Clear the quarantine of a device whose setup failed too many times so its provider sets it up again
+func ClearDeviceQuarantine(c *gin.Context) {
+	udid := c.Param("udid")
+
+	devices.HubDevicesData.Mu.Lock()
+	hubDevice, ok := devices.HubDevicesData.Devices[udid]
+	var nickname string
+	if ok {
+		nickname = hubDevice.Device.Provider
+	}
+	devices.HubDevicesData.Mu.Unlock()
+	if !ok {
+		NotFound(c, fmt.Sprintf("Device with udid `%s` does not exist", udid))
+		return
+	}
+
+	providerConnection, ok := devices.GetProviderConnection(nickname)
+	if !ok {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": fmt.Sprintf("Provider `%s` is not connected to the hub", nickname)})
+		return
+	}
+
+	result, err := providerConnection.SendCommand(models.ProviderCommand{Type: models.ProviderCommandClearQuarantine, UDID: udid}, 30*time.Second)
+	if err != nil {
+		InternalServerError(c, err.Error())
+		return
+	}
+	if !result.Success {
+		BadRequest(c, result.Error)
+		return
+	}
+	OK(c, fmt.Sprintf("Cleared quarantine of device `%s`", udid))
+}
+
+// Reset a device over its provider connection instead of proxying the request to the provider
+// Returns false if the provider is not connected over websocket so the request can be proxied as before
+func resetDeviceOverProviderConnection(c *gin.Context, udid string) bool {
+	devices.HubDevicesData.Mu.Lock()
+	hubDevice, ok := devices.HubDevicesData.Devices[udid]
+	var nickname string
+	if ok {
+		nickname = hubDevice.Device.Provider
+	}
+	devices.HubDevicesData.Mu.Unlock()
+	if !ok {
+		NotFound(c, fmt.Sprintf("Device with udid `%s` does not exist", udid))
+		return true
+	}
+
+	providerConnection, ok := devices.GetProviderConnection(nickname)
+	if !ok {
+		return false
+	}
+
+	result, err := providerConnection.SendCommand(models.ProviderCommand{Type: models.ProviderCommandResetDevice, UDID: udid}, 30*time.Second)
+	if err != nil {
+		InternalServerError(c, err.Error())
+		return true
+	}
+	if !result.Success {
+		InternalServerError(c, result.Error)
+		return true
+	}
+
+	events.PublishDeviceEvent(events.DeviceReset, nickname, udid, nil)
+	OK(c, "Initiate setup reset on device")
+	return true
+}
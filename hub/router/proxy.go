@@ -3,6 +3,7 @@ package router
 import (
 	"GADS/common/db"
 	"GADS/hub/devices"
+	"GADS/hub/events"
 	"fmt"
 	"net/http"
 	"net/http/httputil"
@@ -27,16 +28,32 @@ func DeviceProxyHandler(c *gin.Context) {
 	}()
 	path := c.Param("path")
 
+	// Providers connected over websocket get the reset command on that connection
+	if path == "/reset" && c.Request.Method == http.MethodPost && resetDeviceOverProviderConnection(c, c.Param("udid")) {
+		return
+	}
+
+	udid := c.Param("udid")
+	devices.HubDevicesData.Mu.Lock()
+	hubDevice, ok := devices.HubDevicesData.Devices[udid]
+	var host, provider string
+	if ok {
+		host = hubDevice.Device.Host
+		provider = hubDevice.Device.Provider
+	}
+	devices.HubDevicesData.Mu.Unlock()
+	if !ok {
+		NotFound(c, fmt.Sprintf("Device with udid `%s` does not exist", udid))
+		return
+	}
+
 	// Create a new ReverseProxy instance that will forward the requests
 	// Update its scheme, host and path in the Director
 	// Limit the number of open connections for the host
 	proxy := &httputil.ReverseProxy{
 		Director: func(req *http.Request) {
-			udid := c.Param("udid")
 			req.URL.Scheme = "http"
-			devices.HubDevicesData.Mu.Lock()
-			req.URL.Host = devices.HubDevicesData.Devices[udid].Device.Host
-			devices.HubDevicesData.Mu.Unlock()
+			req.URL.Host = host
 			req.URL.Path = "/device/" + udid + path
 		},
 		Transport: proxyTransport,
@@ -47,6 +64,10 @@ func DeviceProxyHandler(c *gin.Context) {
 				}
 			}
 
+			if path == "/reset" && resp.StatusCode < 300 {
+				events.PublishDeviceEvent(events.DeviceReset, provider, udid, nil)
+			}
+
 			return nil
 		},
 	}
@@ -10,6 +10,7 @@ import (
 	"io"
 	"net/http"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -242,11 +243,17 @@ func UpdateUser(c *gin.Context) {
 		user.Password = dbUser.Password
 	}
 
+	if user.Role == "" {
+		user.Role = dbUser.Role
+	}
+
 	err = db.AddOrUpdateUser(user)
 	if err != nil {
 		InternalServerError(c, fmt.Sprintf("Failed adding/updating user - %s", err))
 		return
 	}
+
+	OK(c, "Successfully updated user")
 }
 
 func DeleteUser(c *gin.Context) {
@@ -333,6 +340,36 @@ func AddProvider(c *gin.Context) {
 		BadRequest(c, "Missing or invalid Selenium Grid address")
 		return
 	}
+	err = models.ValidateProviderEmulators(provider.Emulators)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	err = models.ValidateProviderNetworkDevices(provider.NetworkDevices)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	err = models.ValidateProviderPortRanges(provider.PortRanges)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	err = models.ValidateProviderHooks(provider.Hooks)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	err = models.ValidateCleanupPolicies(provider.CleanupPolicies)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	err = models.ValidateTelemetryThresholds(provider.TelemetryThresholds)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
 
 	err = db.AddOrUpdateProvider(provider)
 	if err != nil {
@@ -357,12 +394,21 @@ func UpdateProvider(c *gin.Context) {
 		BadRequest(c, fmt.Sprintf("%s", err))
 		return
 	}
+	var sentFields map[string]json.RawMessage
+	err = json.Unmarshal(body, &sentFields)
+	if err != nil {
+		BadRequest(c, fmt.Sprintf("%s", err))
+		return
+	}
 
 	// Validations
 	if provider.Nickname == "" {
 		BadRequest(c, "missing `nickname` field")
 		return
 	}
+	if dbProvider, err := db.GetProviderFromDB(provider.Nickname); err == nil {
+		keepUnsentProviderFields(&provider, dbProvider, sentFields)
+	}
 	if provider.OS == "" {
 		BadRequest(c, "missing `os` field")
 		return
@@ -389,6 +435,36 @@ func UpdateProvider(c *gin.Context) {
 		BadRequest(c, "missing `selenium_grid` field")
 		return
 	}
+	err = models.ValidateProviderEmulators(provider.Emulators)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	err = models.ValidateProviderNetworkDevices(provider.NetworkDevices)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	err = models.ValidateProviderPortRanges(provider.PortRanges)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	err = models.ValidateProviderHooks(provider.Hooks)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	err = models.ValidateCleanupPolicies(provider.CleanupPolicies)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	err = models.ValidateTelemetryThresholds(provider.TelemetryThresholds)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
 
 	err = db.AddOrUpdateProvider(provider)
 	if err != nil {
@@ -398,6 +474,34 @@ func UpdateProvider(c *gin.Context) {
 	OK(c, "Provider updated successfully")
 }
 
+// Provider fields that are not part of the provider form keep their DB value when an update does not send them
+// A field that is sent empty clears the DB value
+func keepUnsentProviderFields(provider *models.Provider, dbProvider models.Provider, sentFields map[string]json.RawMessage) {
+	if _, ok := sentFields["emulators"]; !ok {
+		provider.Emulators = dbProvider.Emulators
+	}
+	if _, ok := sentFields["network_devices"]; !ok {
+		provider.NetworkDevices = dbProvider.NetworkDevices
+	}
+	if _, ok := sentFields["port_ranges"]; !ok {
+		provider.PortRanges = dbProvider.PortRanges
+	}
+	if _, ok := sentFields["hooks"]; !ok {
+		provider.Hooks = dbProvider.Hooks
+	}
+	if _, ok := sentFields["cleanup_policies"]; !ok {
+		provider.CleanupPolicies = dbProvider.CleanupPolicies
+	}
+	if _, ok := sentFields["telemetry_thresholds"]; !ok {
+		provider.TelemetryThresholds = dbProvider.TelemetryThresholds
+	}
+}
+
+// Get the liveness status of all providers - online, degraded or offline
+func GetProvidersStatus(c *gin.Context) {
+	OkJSON(c, devices.GetProvidersLiveness())
+}
+
 func DeleteProvider(c *gin.Context) {
 	nickname := c.Param("nickname")
 
@@ -506,9 +610,13 @@ func AvailableDevicesSSE(c *gin.Context) {
 
 		var deviceList = []*models.LocalHubDevice{}
 		for _, key := range hubDeviceMapKeys {
+			// Devices registered by their provider are not exposed until an admin approves them
+			if devices.HubDevicesData.Devices[key].Device.Usage == "pending" {
+				continue
+			}
 			if devices.HubDevicesData.Devices[key].Device.LastUpdatedTimestamp < (time.Now().UnixMilli()-3000) && devices.HubDevicesData.Devices[key].Device.Connected {
 				devices.HubDevicesData.Devices[key].Available = false
-			} else if devices.HubDevicesData.Devices[key].Device.ProviderState != "live" {
+			} else if devices.HubDevicesData.Devices[key].Device.ProviderState != "live" || devices.HubDevicesData.Devices[key].Device.CleaningUp || devices.HubDevicesData.Devices[key].Device.Unhealthy || devices.HubDevicesData.Devices[key].InMaintenance {
 				devices.HubDevicesData.Devices[key].Available = false
 			} else {
 				devices.HubDevicesData.Devices[key].Available = true
@@ -592,6 +700,7 @@ func AddDevice(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upsert device in DB"})
 		return
 	}
+	notifyProvidersDevicesChanged(device.Provider)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Added device in DB"})
 }
@@ -614,6 +723,7 @@ func UpdateDevice(c *gin.Context) {
 	dbDevices := db.GetDBDeviceNew()
 	for _, dbDevice := range dbDevices {
 		if dbDevice.UDID == reqDevice.UDID {
+			previousProvider := dbDevice.Provider
 			// Update only the relevant data and only if something has changed
 			if dbDevice.Provider != reqDevice.Provider {
 				dbDevice.Provider = reqDevice.Provider
@@ -638,11 +748,15 @@ func UpdateDevice(c *gin.Context) {
 			if reqDevice.Usage != "" && reqDevice.Usage != dbDevice.Usage {
 				dbDevice.Usage = reqDevice.Usage
 			}
+			if reqDevice.Tags != nil {
+				dbDevice.Tags = reqDevice.Tags
+			}
 			err = db.UpsertDeviceDB(&dbDevice)
 			if err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upsert device in DB"})
 				return
 			}
+			notifyProvidersDevicesChanged(previousProvider, dbDevice.Provider)
 			c.JSON(http.StatusOK, gin.H{"message": "Successfully updated device in DB"})
 			return
 		}
@@ -654,11 +768,17 @@ func UpdateDevice(c *gin.Context) {
 func DeleteDevice(c *gin.Context) {
 	udid := c.Param("udid")
 
+	var provider string
+	if hubDevice := devices.GetHubDeviceByUDID(udid); hubDevice != nil {
+		provider = hubDevice.Device.Provider
+	}
+
 	err := db.DeleteDeviceDB(udid)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to delete device from DB - %s", err)})
 		return
 	}
+	notifyProvidersDevicesChanged(provider)
 
 	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Successfully deleted device with udid `%s` from DB", udid)})
 }
@@ -681,6 +801,18 @@ func GetDevices(c *gin.Context) {
 		dbDevices = []models.Device{}
 	}
 
+	// Show the setup failures and steps reported by the providers so admins can clear quarantined devices and see where setups get stuck
+	devices.HubDevicesData.Mu.Lock()
+	for i := range dbDevices {
+		if hubDevice, ok := devices.HubDevicesData.Devices[dbDevices[i].UDID]; ok {
+			dbDevices[i].SetupFailures = hubDevice.Device.SetupFailures
+			dbDevices[i].LastSetupError = hubDevice.Device.LastSetupError
+			dbDevices[i].Quarantined = hubDevice.Device.Quarantined
+			dbDevices[i].SetupSteps = hubDevice.Device.SetupSteps
+		}
+	}
+	devices.HubDevicesData.Mu.Unlock()
+
 	var adminDeviceData = AdminDeviceData{
 		Devices:   dbDevices,
 		Providers: providerNames,
@@ -689,64 +821,86 @@ func GetDevices(c *gin.Context) {
 	c.JSON(http.StatusOK, adminDeviceData)
 }
 
-func ProviderUpdate(c *gin.Context) {
-	bodyBytes, err := io.ReadAll(c.Request.Body)
-	defer c.Request.Body.Close()
-	if err != nil {
-		// handle error if needed
+// Get the devices registered by their providers that wait for admin approval
+func GetPendingDevices(c *gin.Context) {
+	dbDevices := db.GetDBDeviceNew()
+	pendingDevices := []*models.Device{}
+	for i := range dbDevices {
+		if dbDevices[i].Usage == "pending" {
+			pendingDevices = append(pendingDevices, &dbDevices[i])
+		}
 	}
 
-	var providerDeviceData models.ProviderData
+	OkJSON(c, pendingDevices)
+}
+
+type approveDeviceRequest struct {
+	Usage string   `json:"usage"`
+	Name  string   `json:"name"`
+	Tags  []string `json:"tags"`
+}
 
-	err = json.Unmarshal(bodyBytes, &providerDeviceData)
-	if err != nil {
-		// handle error if needed
+// Approve a pending device so its provider sets it up, by default it is enabled for automation and remote control
+func ApprovePendingDevice(c *gin.Context) {
+	var req approveDeviceRequest
+	if c.Request.ContentLength > 0 {
+		err := c.ShouldBindJSON(&req)
+		if err != nil {
+			BadRequest(c, fmt.Sprintf("Invalid request body - %s", err))
+			return
+		}
+	}
+	if req.Usage == "" {
+		req.Usage = "enabled"
+	}
+	if !slices.Contains([]string{"enabled", "automation", "control"}, req.Usage) {
+		BadRequest(c, fmt.Sprintf("Invalid usage `%s` - use `enabled`, `automation` or `control`", req.Usage))
+		return
 	}
 
-	for _, providerDevice := range providerDeviceData.DeviceData {
-		devices.HubDevicesData.Mu.Lock()
-		hubDevice, ok := devices.HubDevicesData.Devices[providerDevice.UDID]
-		if ok {
-			// If device is not connected reset all fields that might allow it to get stuck in Running automation state
-			// If its not connected, then its not running automation or is available for automation
-			if !providerDevice.Connected {
-				hubDevice.IsAvailableForAutomation = false
-				hubDevice.IsRunningAutomation = false
-				hubDevice.InUseBy = ""
-				hubDevice.SessionID = ""
-				devices.HubDevicesData.Mu.Unlock()
-				continue
-			}
-			// Set a timestamp to indicate last time info about the device was updated from the provider
-			providerDevice.LastUpdatedTimestamp = time.Now().UnixMilli()
+	updatePendingDevice(c, func(device *models.Device) {
+		device.Usage = req.Usage
+		if req.Name != "" {
+			device.Name = req.Name
+		}
+		if req.Tags != nil {
+			device.Tags = req.Tags
+		}
+	}, fmt.Sprintf("Approved device with usage `%s`", req.Usage))
+}
 
-			// Check all DB related values so if you make a change in the DB for a device
-			// The provider pushing updates will not overwrite with something wrong
-			if providerDevice.Usage != hubDevice.Device.Usage {
-				providerDevice.Usage = hubDevice.Device.Usage
-			}
-			if providerDevice.Name != hubDevice.Device.Name {
-				providerDevice.Name = hubDevice.Device.Name
-			}
-			if providerDevice.OSVersion != hubDevice.Device.OSVersion {
-				providerDevice.OSVersion = hubDevice.Device.OSVersion
-			}
-			if providerDevice.ScreenWidth != hubDevice.Device.ScreenWidth {
-				providerDevice.ScreenWidth = hubDevice.Device.ScreenWidth
-			}
-			if providerDevice.ScreenHeight != hubDevice.Device.ScreenHeight {
-				providerDevice.ScreenHeight = hubDevice.Device.ScreenHeight
-			}
-			if providerDevice.Provider != hubDevice.Device.Provider {
-				providerDevice.Provider = hubDevice.Device.Provider
-			}
+// Reject a pending device, it is kept in the DB as disabled so its provider does not register it again
+func RejectPendingDevice(c *gin.Context) {
+	updatePendingDevice(c, func(device *models.Device) {
+		device.Usage = "disabled"
+	}, "Rejected device")
+}
 
-			hubDevice.Device = providerDevice
+func updatePendingDevice(c *gin.Context, update func(device *models.Device), message string) {
+	udid := c.Param("udid")
+	dbDevices := db.GetDBDeviceNew()
+	for i := range dbDevices {
+		dbDevice := &dbDevices[i]
+		if dbDevice.UDID != udid {
+			continue
 		}
-		devices.HubDevicesData.Mu.Unlock()
+		if dbDevice.Usage != "pending" {
+			BadRequest(c, fmt.Sprintf("Device `%s` is not pending approval", udid))
+			return
+		}
+
+		update(dbDevice)
+		err := db.UpsertDeviceDB(dbDevice)
+		if err != nil {
+			InternalServerError(c, "Failed to upsert device in DB")
+			return
+		}
+		notifyProvidersDevicesChanged(dbDevice.Provider)
+		OK(c, fmt.Sprintf("%s `%s`", message, udid))
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{})
+	NotFound(c, fmt.Sprintf("Device with udid `%s` does not exist in the DB", udid))
 }
 
 func GetUsers(c *gin.Context) {
@@ -0,0 +1,126 @@
+package router
+
+import (
+	"GADS/common/db"
+	"GADS/common/models"
+	"GADS/hub/events"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func GetWebhooks(c *gin.Context) {
+	webhooks := db.GetWebhooks()
+	// Don't send the signing secrets back, only if one is set
+	response := []gin.H{}
+	for _, webhook := range webhooks {
+		response = append(response, gin.H{
+			"name":       webhook.Name,
+			"url":        webhook.URL,
+			"events":     webhook.Events,
+			"enabled":    webhook.Enabled,
+			"has_secret": webhook.Secret != "",
+		})
+	}
+	OkJSON(c, response)
+}
+
+func AddOrUpdateWebhook(c *gin.Context) {
+	var webhook models.Webhook
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		InternalServerError(c, fmt.Sprintf("%s", err))
+		return
+	}
+
+	err = json.Unmarshal(body, &webhook)
+	if err != nil {
+		BadRequest(c, fmt.Sprintf("%s", err))
+		return
+	}
+
+	// Validations
+	if webhook.Name == "" {
+		BadRequest(c, "missing `name` field")
+		return
+	}
+	webhookURL, err := url.Parse(webhook.URL)
+	if err != nil || (webhookURL.Scheme != "http" && webhookURL.Scheme != "https") || webhookURL.Host == "" {
+		BadRequest(c, "missing or invalid `url` field, provide an absolute http or https URL")
+		return
+	}
+	for _, eventType := range webhook.Events {
+		if !slices.Contains(events.AllEventTypes, events.EventType(eventType)) {
+			BadRequest(c, fmt.Sprintf("unknown event type `%s` in `events` field", eventType))
+			return
+		}
+	}
+
+	// The secret is never returned to clients so keep the current one if none was provided
+	if webhook.Secret == "" {
+		for _, dbWebhook := range db.GetWebhooks() {
+			if dbWebhook.Name == webhook.Name {
+				webhook.Secret = dbWebhook.Secret
+			}
+		}
+	}
+
+	err = db.AddOrUpdateWebhook(webhook)
+	if err != nil {
+		InternalServerError(c, fmt.Sprintf("Failed to add/update webhook in DB - %s", err))
+		return
+	}
+	events.InvalidateWebhooks()
+	OK(c, fmt.Sprintf("Successfully added/updated webhook `%s`", webhook.Name))
+}
+
+func DeleteWebhook(c *gin.Context) {
+	name := c.Param("name")
+
+	err := db.DeleteWebhookDB(name)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			NotFound(c, fmt.Sprintf("No webhook with name `%s` found", name))
+			return
+		}
+		InternalServerError(c, fmt.Sprintf("Failed to delete webhook from DB - %s", err))
+		return
+	}
+	events.InvalidateWebhooks()
+	OK(c, fmt.Sprintf("Successfully deleted webhook `%s`", name))
+}
+
+// Send a test event to a webhook to check the receiving side, the result can be checked in the delivery log
+func TestWebhook(c *gin.Context) {
+	name := c.Param("name")
+
+	for _, webhook := range db.GetWebhooks() {
+		if webhook.Name == name {
+			event := events.New(events.WebhookTest)
+			go events.DeliverWebhook(webhook, event)
+			OkJSON(c, gin.H{"message": fmt.Sprintf("Sending test event to webhook `%s`", name), "event_id": event.ID})
+			return
+		}
+	}
+	NotFound(c, fmt.Sprintf("No webhook with name `%s` found", name))
+}
+
+func GetWebhookDeliveries(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit <= 0 || limit > 1000 {
+		limit = 1000
+	}
+
+	deliveries, err := db.GetWebhookDeliveries(c.Query("webhook"), int64(limit))
+	if err != nil {
+		InternalServerError(c, fmt.Sprintf("Failed to get webhook deliveries from DB - %s", err))
+		return
+	}
+	OkJSON(c, deliveries)
+}
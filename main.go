@@ -1,7 +1,9 @@
 package main
 
 import (
+	"GADS/cli"
 	"GADS/hub"
+	"GADS/hub/farm"
 	"GADS/provider"
 	"fmt"
 	"os"
@@ -42,8 +44,35 @@ func main() {
 	providerCmd.Flags().String("provider-folder", ".", "The folder where logs and other data will be stored")
 	providerCmd.Flags().String("log-level", "info", "The verbosity of the logs of the provider instance")
 	providerCmd.Flags().String("hub", "", "The address of the GADS hub instance")
+	providerCmd.Flags().Int("fake-devices", 0, "Number of simulated devices to provide, for running the provider without real devices")
+	providerCmd.Flags().String("config", "", "Path to a YAML or TOML provider config file, values in it take precedence over the provider configuration in the DB")
 	rootCmd.AddCommand(providerCmd)
 
+	// Apply Command
+	var applyCmd = &cobra.Command{
+		Use:   "apply",
+		Short: "Apply a declarative farm manifest of providers, devices and users to the DB",
+		Run: func(cmd *cobra.Command, args []string) {
+			farm.ApplyManifest(cmd.Flags())
+		},
+	}
+	applyCmd.Flags().StringP("file", "f", "", "Path to the YAML or JSON farm manifest")
+	applyCmd.Flags().Bool("dry-run", false, "Only print the changes that would be applied")
+	applyCmd.Flags().Bool("prune", false, "Delete providers, devices and users that are not present in the manifest")
+	rootCmd.AddCommand(applyCmd)
+
+	// Export Command
+	var exportCmd = &cobra.Command{
+		Use:   "export",
+		Short: "Export the current providers, devices and users as a farm manifest",
+		Run: func(cmd *cobra.Command, args []string) {
+			farm.ExportManifest(cmd.Flags())
+		},
+	}
+	exportCmd.Flags().StringP("file", "f", "", "Path to write the manifest to, prints to stdout if not provided")
+	exportCmd.Flags().String("format", "", "Manifest format - `yaml` or `json`, by default decided by the file extension")
+	rootCmd.AddCommand(exportCmd)
+
 	var versionCmd = &cobra.Command{
 		Use:   "version",
 		Short: "Print the application version",
@@ -53,6 +82,12 @@ func main() {
 	}
 	rootCmd.AddCommand(versionCmd)
 
+	// Admin commands that manage a running hub over its REST API
+	rootCmd.AddCommand(cli.Commands()...)
+
+	// Errors are printed below, avoid printing them twice
+	rootCmd.SilenceErrors = true
+
 	// Execute the root command
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
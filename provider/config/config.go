@@ -4,30 +4,186 @@ import (
 	"GADS/common/db"
 	"GADS/common/models"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
 
+	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/gridfs"
 )
 
-var ProviderConfig = &models.Provider{}
+// The provider configuration is replaced as a whole when it changes so goroutines reading it never see a partly updated configuration
+var providerConfig atomic.Pointer[models.Provider]
 
-func SetupConfig(nickname, folder, hubAddress string) {
-	provider, err := db.GetProviderFromDB(nickname)
+// Serializes the configuration updates so concurrent updates do not overwrite each other
+var providerConfigMu sync.Mutex
+
+func init() {
+	providerConfig.Store(&models.Provider{})
+}
+
+// Current provider configuration
+// It must not be changed once the devices are being set up, use UpdateConfig instead
+func ProviderConfig() *models.Provider {
+	return providerConfig.Load()
+}
+
+// Replace the provider configuration
+func SetProviderConfig(provider *models.Provider) {
+	providerConfigMu.Lock()
+	defer providerConfigMu.Unlock()
+	providerConfig.Store(provider)
+}
+
+// Apply the changes to a copy of the current provider configuration and replace the configuration with it
+func UpdateConfig(update func(provider *models.Provider)) {
+	providerConfigMu.Lock()
+	defer providerConfigMu.Unlock()
+	provider := *providerConfig.Load()
+	update(&provider)
+	providerConfig.Store(&provider)
+}
+
+// If the provider was not present in the DB and is configured only through the config file and env vars
+var registerProvider bool
+
+// Set up the provider configuration from the DB, the config file and env vars
+// All invalid values are returned at once
+func SetupConfig(settings Settings) []error {
+	var errs []error
+	provider, err := db.GetProviderFromDB(settings.Nickname)
 	if err != nil {
-		log.Fatalf("Failed to get provider data from DB - %s", err)
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			errs = append(errs, fmt.Errorf("Failed to get provider data from DB - %s", err))
+		} else if len(overrides) == 0 {
+			errs = append(errs, fmt.Errorf("Provider `%s` is not registered in the DB and no configuration was provided via a config file or env vars", settings.Nickname))
+		} else {
+			registerProvider = true
+		}
 	}
-	if provider.Nickname == "" {
-		log.Fatal("Provider with this nickname is not registered in the DB")
+	applyOverrides(&provider)
+	provider.Nickname = settings.Nickname
+	provider.OS = runtime.GOOS
+	provider.ProviderFolder = settings.ProviderFolder
+	provider.HubAddress = settings.HubAddress
+	provider.FakeDevices = settings.FakeDevices
+
+	SetProviderConfig(&provider)
+
+	return append(errs, ValidateConfig(&provider)...)
+}
+
+// Validate the provider configuration, returns all found problems
+func ValidateConfig(provider *models.Provider) []error {
+	var errs []error
+	if provider.HostAddress == "" {
+		errs = append(errs, fmt.Errorf("Missing `host_address`"))
+	}
+	if provider.Port < 1 || provider.Port > 65535 {
+		errs = append(errs, fmt.Errorf("Invalid `port` %d", provider.Port))
 	}
-	provider.ProviderFolder = folder
-	provider.HubAddress = hubAddress
+	if provider.UseSeleniumGrid && provider.SeleniumGrid == "" {
+		errs = append(errs, fmt.Errorf("Missing `selenium_grid` address while `use_selenium_grid` is enabled"))
+	}
+	if provider.ProvideIOS && provider.WdaBundleID == "" && (provider.OS == "windows" || provider.OS == "linux") {
+		errs = append(errs, fmt.Errorf("Missing `wda_bundle_id` while `provide_ios` is enabled on Linux/Windows"))
+	}
+	err := models.ValidateProviderEmulators(provider.Emulators)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("Invalid `emulators` - %s", err))
+	}
+	err = models.ValidateProviderNetworkDevices(provider.NetworkDevices)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("Invalid `network_devices` - %s", err))
+	}
+	err = models.ValidateProviderPortRanges(provider.PortRanges)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("Invalid `port_ranges` - %s", err))
+	}
+	err = models.ValidateProviderHooks(provider.Hooks)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("Invalid `hooks` - %s", err))
+	}
+	err = models.ValidateCleanupPolicies(provider.CleanupPolicies)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("Invalid `cleanup_policies` - %s", err))
+	}
+	err = models.ValidateTelemetryThresholds(provider.TelemetryThresholds)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("Invalid `telemetry_thresholds` - %s", err))
+	}
+	return errs
+}
 
-	ProviderConfig = &provider
+// Store the provider in the DB if it was configured only through the config file and env vars
+// and generate the token used to authenticate the hub connection if the provider does not have one yet
+// The hub validates the token against the DB so there is nothing else to configure
+func RegisterProvider() error {
+	if registerProvider {
+		err := db.AddOrUpdateProvider(*ProviderConfig())
+		if err != nil {
+			return fmt.Errorf("Failed to register provider in DB - %s", err)
+		}
+	}
+
+	if ProviderConfig().HubToken == "" {
+		ProviderConfig().HubToken = uuid.New().String()
+		err := db.SetProviderHubToken(ProviderConfig().Nickname, ProviderConfig().HubToken)
+		if err != nil {
+			return fmt.Errorf("Failed to store provider hub token in DB - %s", err)
+		}
+	}
+
+	return nil
+}
+
+// Reload the provider configuration values that are used during device setup from the DB
+// Values that need preparation of the host, like the WebDriverAgent repo path or Selenium Grid, still require a provider restart
+// Values from the config file and env vars keep precedence over the DB values
+func ReloadConfig() ([]string, error) {
+	provider, err := db.GetProviderFromDB(ProviderConfig().Nickname)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get provider data from DB - %s", err)
+	}
+	applyOverrides(&provider)
+
+	var restartRequired []string
+	UpdateConfig(func(current *models.Provider) {
+		if provider.ProvideAndroid != current.ProvideAndroid {
+			restartRequired = append(restartRequired, "provide_android")
+		}
+		if provider.ProvideIOS != current.ProvideIOS {
+			restartRequired = append(restartRequired, "provide_ios")
+		}
+		if provider.UseSeleniumGrid != current.UseSeleniumGrid || provider.SeleniumGrid != current.SeleniumGrid {
+			restartRequired = append(restartRequired, "selenium_grid")
+		}
+		if provider.HostAddress != current.HostAddress || provider.Port != current.Port {
+			restartRequired = append(restartRequired, "host_address/port")
+		}
+
+		current.WdaBundleID = provider.WdaBundleID
+		current.SupervisionPassword = provider.SupervisionPassword
+		current.UseGadsIosStream = provider.UseGadsIosStream
+		current.UseCustomWDA = provider.UseCustomWDA
+		current.Emulators = provider.Emulators
+		current.NetworkDevices = provider.NetworkDevices
+		// New port ranges apply to the ports allocated from now on, allocated ports are kept until their devices release them
+		current.PortRanges = provider.PortRanges
+		// Hooks, cleanup policies and telemetry thresholds are read when they are used so changes apply without a restart
+		current.Hooks = provider.Hooks
+		current.CleanupPolicies = provider.CleanupPolicies
+		current.TelemetryThresholds = provider.TelemetryThresholds
+	})
+
+	return restartRequired, nil
 }
 
 func SetupSeleniumJar() error {
@@ -63,7 +219,7 @@ func SetupSeleniumJar() error {
 	}
 
 	// Create the filepath and remove the selenium jar if present
-	filePath := fmt.Sprintf("%s/%s", ProviderConfig.ProviderFolder, "selenium.jar")
+	filePath := fmt.Sprintf("%s/%s", ProviderConfig().ProviderFolder, "selenium.jar")
 	err = os.Remove(filePath)
 	if err != nil {
 		fmt.Printf("There is no Selenium jar file located at `%s`, nothing to remove\n", filePath)
@@ -0,0 +1,234 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"GADS/common/models"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+const envPrefix = "GADS_PROVIDER_"
+
+// Settings of the provider process itself, they can be provided as flags, env vars or in the config file
+type Settings struct {
+	ConfigFile     string `json:"-"`
+	Nickname       string `json:"nickname"`
+	HubAddress     string `json:"hub_address"`
+	MongoDB        string `json:"mongo_db"`
+	ProviderFolder string `json:"provider_folder"`
+	LogLevel       string `json:"log_level"`
+	FakeDevices    int    `json:"fake_devices"`
+}
+
+// Flags of the provider command and the config keys they set
+var flagKeys = map[string]string{
+	"nickname":        "nickname",
+	"hub":             "hub_address",
+	"mongo-db":        "mongo_db",
+	"provider-folder": "provider_folder",
+	"log-level":       "log_level",
+	"fake-devices":    "fake_devices",
+}
+
+// Provider values that are not configuration but state or are detected on the host
+var nonConfigKeys = []string{"os", "last_updated", "provided_devices"}
+
+// Provider configuration values from the config file and env vars, applied over the values from the DB
+var overrides = make(map[string]interface{})
+
+// Get the keys and their types from the json tags of a struct type
+func jsonKeys(t reflect.Type) map[string]reflect.Type {
+	keys := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		key := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if key == "" || key == "-" || slices.Contains(nonConfigKeys, key) {
+			continue
+		}
+		keys[key] = t.Field(i).Type
+	}
+	return keys
+}
+
+// Load the provider settings and the provider configuration overrides
+// Precedence from highest to lowest is explicitly provided flags, env vars, the config file, the provider configuration in the DB and then the flag defaults
+// All found problems are returned at once instead of failing on the first one
+func LoadSettings(flags *pflag.FlagSet) (Settings, []error) {
+	var errs []error
+	settingsKeys := jsonKeys(reflect.TypeOf(Settings{}))
+	providerKeys := jsonKeys(reflect.TypeOf(models.Provider{}))
+	values := make(map[string]interface{})
+
+	configFile, _ := flags.GetString("config")
+	if configFile == "" {
+		configFile = os.Getenv(envPrefix + "CONFIG")
+	}
+	if configFile != "" {
+		fileValues, err := readConfigFile(configFile)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for key, value := range fileValues {
+			_, isSetting := settingsKeys[key]
+			_, isProvider := providerKeys[key]
+			if !isSetting && !isProvider {
+				errs = append(errs, fmt.Errorf("Unknown key `%s` in config file `%s`", key, configFile))
+				continue
+			}
+			values[key] = value
+		}
+	}
+
+	for _, keys := range []map[string]reflect.Type{providerKeys, settingsKeys} {
+		for key, keyType := range keys {
+			envName := envPrefix + strings.ToUpper(key)
+			envValue, ok := os.LookupEnv(envName)
+			if !ok {
+				continue
+			}
+			value, err := parseEnvValue(envValue, keyType)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("Invalid value for env var `%s` - %s", envName, err))
+				continue
+			}
+			values[key] = value
+		}
+	}
+
+	// Flags that were explicitly provided win over everything else
+	flags.Visit(func(flag *pflag.Flag) {
+		if key, ok := flagKeys[flag.Name]; ok {
+			delete(values, key)
+		}
+	})
+
+	// Start from the flag values so the flag defaults are used for anything that is not configured elsewhere
+	settings := Settings{ConfigFile: configFile}
+	settings.Nickname, _ = flags.GetString("nickname")
+	settings.HubAddress, _ = flags.GetString("hub")
+	settings.MongoDB, _ = flags.GetString("mongo-db")
+	settings.ProviderFolder, _ = flags.GetString("provider-folder")
+	settings.LogLevel, _ = flags.GetString("log-level")
+	settings.FakeDevices, _ = flags.GetInt("fake-devices")
+
+	// Apply each value separately so all values of the wrong type are reported
+	clear(overrides)
+	for key, value := range values {
+		var err error
+		if _, isSetting := settingsKeys[key]; isSetting {
+			err = applyValues(map[string]interface{}{key: value}, &settings)
+		} else {
+			err = applyValues(map[string]interface{}{key: value}, &models.Provider{})
+			overrides[key] = value
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Invalid value for `%s` - %s", key, err))
+			delete(overrides, key)
+		}
+	}
+
+	settings.Nickname = strings.TrimSpace(settings.Nickname)
+	if settings.Nickname == "" {
+		errs = append(errs, fmt.Errorf("Missing provider nickname - provide it via the --nickname flag, the `%sNICKNAME` env var or the config file, e.g. --nickname=Provider1", envPrefix))
+	}
+	if settings.HubAddress == "" {
+		errs = append(errs, fmt.Errorf("Missing hub address - provide it via the --hub flag, the `%sHUB_ADDRESS` env var or the config file, e.g. --hub=http://192.168.1.6:10000", envPrefix))
+	}
+	if !slices.Contains(logLevels, settings.LogLevel) {
+		errs = append(errs, fmt.Errorf("Invalid log level `%s` - use one of %v", settings.LogLevel, logLevels))
+	}
+	if settings.FakeDevices < 0 {
+		errs = append(errs, fmt.Errorf("Invalid number of fake devices `%d`", settings.FakeDevices))
+	}
+
+	return settings, errs
+}
+
+var logLevels = []string{"debug", "info", "error"}
+
+// Read a YAML or TOML config file, the format is decided by the file extension
+func readConfigFile(filePath string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read config file `%s` - %s", filePath, err)
+	}
+
+	values := make(map[string]interface{})
+	if strings.EqualFold(filepath.Ext(filePath), ".toml") {
+		err = toml.Unmarshal(data, &values)
+	} else {
+		err = yaml.Unmarshal(data, &values)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse config file `%s` - %s", filePath, err)
+	}
+
+	return values, nil
+}
+
+// Convert an env var string to a value of the type of the respective config key
+// Lists of strings are comma separated, other lists and objects are JSON
+func parseEnvValue(value string, valueType reflect.Type) (interface{}, error) {
+	switch valueType.Kind() {
+	case reflect.String:
+		return value, nil
+	case reflect.Bool:
+		return strconv.ParseBool(value)
+	case reflect.Int, reflect.Int64:
+		return strconv.Atoi(value)
+	case reflect.Slice:
+		if valueType.Elem().Kind() == reflect.String {
+			var list []string
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					list = append(list, item)
+				}
+			}
+			return list, nil
+		}
+	}
+
+	var parsed interface{}
+	err := json.Unmarshal([]byte(value), &parsed)
+	if err != nil {
+		return nil, fmt.Errorf("expected JSON - %s", err)
+	}
+	return parsed, nil
+}
+
+// Set the values on the target struct by their json keys, keys that are not present keep their current values
+func applyValues(values map[string]interface{}, target interface{}) error {
+	if len(values) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
+
+// Apply the config file and env var values over the provider configuration from the DB
+func applyOverrides(provider *models.Provider) {
+	// The values were already checked when loading the settings
+	applyValues(overrides, provider)
+}
+
+// Keys of the provider configuration that are overridden by the config file or env vars
+func OverriddenKeys() []string {
+	var keys []string
+	for key := range overrides {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -6,6 +6,7 @@ import (
 	"GADS/provider/config"
 	"GADS/provider/logger"
 	"bytes"
+	"context"
 	"fmt"
 	"os/exec"
 	"strings"
@@ -43,7 +44,7 @@ func stopGadsStreamService(device *models.Device) {
 func installGadsStream(device *models.Device) error {
 	logger.ProviderLogger.LogInfo("android_device_setup", fmt.Sprintf("Installing GADS-stream apk on device `%v`", device.UDID))
 
-	cmd := exec.CommandContext(device.Context, "adb", "-s", device.UDID, "install", "-r", fmt.Sprintf("%s/gads-stream.apk", config.ProviderConfig.ProviderFolder))
+	cmd := exec.CommandContext(device.Context, "adb", "-s", device.UDID, "install", "-r", fmt.Sprintf("%s/gads-stream.apk", config.ProviderConfig().ProviderFolder))
 	err := cmd.Run()
 	if err != nil {
 		return fmt.Errorf("installGadsStream: Error executing `%s` - %s", cmd.Args, err)
@@ -200,7 +201,7 @@ func uninstallAppAndroid(device *models.Device, packageName string) error {
 
 // Install app on Android device by apk name
 func installAppAndroid(device *models.Device, appName string) error {
-	cmd := exec.CommandContext(device.Context, "adb", "-s", device.UDID, "install", "-r", fmt.Sprintf("%s/%s", config.ProviderConfig.ProviderFolder, appName))
+	cmd := exec.CommandContext(device.Context, "adb", "-s", device.UDID, "install", "-r", fmt.Sprintf("%s/%s", config.ProviderConfig().ProviderFolder, appName))
 
 	if err := cmd.Run(); err != nil {
 		device.Logger.LogError("install_app", fmt.Sprintf("installAppAndroid: Error executing `%s` trying to install app - %v", cmd.Args, err))
@@ -209,3 +210,216 @@ func installAppAndroid(device *models.Device, appName string) error {
 
 	return nil
 }
+
+// Check if the Android device is an emulator, emulators report the qemu kernel property
+func isAndroidEmulator(device *models.Device) bool {
+	if strings.HasPrefix(device.UDID, "emulator-") {
+		return true
+	}
+
+	output, err := exec.CommandContext(device.Context, "adb", "-s", device.UDID, "shell", "getprop", "ro.kernel.qemu").Output()
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(string(output)) == "1"
+}
+
+// Get the Android device hardware model and screen size if it is not already set in the DB
+// The device type is also updated in the DB if it does not match the detected one
+func updateAndroidHardwareInfo(device *models.Device) error {
+	getAndroidDeviceHardwareModel(device)
+
+	deviceType := "real"
+	if isAndroidEmulator(device) {
+		deviceType = "emulator"
+	}
+	if device.DeviceType != deviceType {
+		device.StateMu.Lock()
+		device.DeviceType = deviceType
+		device.StateMu.Unlock()
+		err := db.SetDeviceTypeDB(device.UDID, deviceType)
+		if err != nil {
+			return fmt.Errorf("Failed to update device type in DB - %s", err)
+		}
+	}
+
+	if device.ScreenHeight == "" || device.ScreenWidth == "" {
+		err := updateAndroidScreenSizeADB(device)
+		if err != nil {
+			return fmt.Errorf("Failed to update screen dimensions with adb - %s", err)
+		}
+	}
+
+	return nil
+}
+
+// Get a system property of an Android device with adb
+func getAndroidProp(device *models.Device, prop string) (string, error) {
+	cmd := exec.CommandContext(device.Context, "adb", "-s", device.UDID, "shell", "getprop", prop)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("Error executing `%s` - %s", cmd.Args, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// Detect the data of a connected Android device that is not registered in the DB yet
+func identifyAndroidDevice(device *models.Device) error {
+	var err error
+	device.Name, err = getAndroidProp(device, "ro.product.model")
+	if err != nil {
+		return fmt.Errorf("Failed to get device model - %s", err)
+	}
+	device.OSVersion, err = getAndroidProp(device, "ro.build.version.release")
+	if err != nil {
+		return fmt.Errorf("Failed to get Android version - %s", err)
+	}
+
+	device.DeviceType = "real"
+	if isAndroidEmulator(device) {
+		device.DeviceType = "emulator"
+	}
+
+	return updateAndroidScreenSizeADB(device)
+}
+
+// Take a PNG screenshot of the device screen with adb
+func screenshotAndroid(device *models.Device) ([]byte, error) {
+	var outBuffer bytes.Buffer
+	cmd := exec.CommandContext(device.Context, "adb", "-s", device.UDID, "exec-out", "screencap", "-p")
+	cmd.Stdout = &outBuffer
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("screenshotAndroid: Error executing `%s` - %s", cmd.Args, err)
+	}
+
+	return outBuffer.Bytes(), nil
+}
+
+type androidDriver struct{}
+
+func (androidDriver) Name() string {
+	return "android"
+}
+
+func (androidDriver) Enabled() bool {
+	return config.ProviderConfig().ProvideAndroid
+}
+
+func (androidDriver) Discover() []string {
+	return getConnectedDevicesAndroid()
+}
+
+func (androidDriver) Setup(device *models.Device) {
+	setupAndroidDevice(device)
+}
+
+// GADS-stream port forwards are removed together with all other adb forwards on provider start
+func (androidDriver) Teardown(device *models.Device) {}
+
+// Stop the GADS-stream service, the device context is cancelled right after so the service is stopped with its own timeout
+func (androidDriver) Stop(device *models.Device) {
+	ctx, cancel := context.WithTimeout(context.Background(), processStopTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "adb", "-s", device.UDID, "shell", "am", "stopservice", "com.shamanec.stream/.ScreenCaptureService")
+	err := cmd.Run()
+	if err != nil {
+		logger.ProviderLogger.LogWarn("provider_shutdown", fmt.Sprintf("Failed to stop GADS-stream service on device `%s` - %s", device.UDID, err))
+	}
+}
+
+func (androidDriver) InstallApp(device *models.Device, appName string) error {
+	return installAppAndroid(device, appName)
+}
+
+func (androidDriver) UninstallApp(device *models.Device, app string) error {
+	return uninstallAppAndroid(device, app)
+}
+
+func (androidDriver) InstalledApps(device *models.Device) []string {
+	return GetInstalledAppsAndroid(device)
+}
+
+func (androidDriver) LaunchApp(device *models.Device, app string) error {
+	ctx, cancel := context.WithTimeout(device.Context, appCommandTimeout)
+	defer cancel()
+	return launchAppAndroid(ctx, device, app)
+}
+
+func (androidDriver) TerminateApp(device *models.Device, app string) error {
+	ctx, cancel := context.WithTimeout(device.Context, appCommandTimeout)
+	defer cancel()
+	return terminateAppAndroid(ctx, device, app)
+}
+
+func (androidDriver) OpenURL(device *models.Device, url string) error {
+	ctx, cancel := context.WithTimeout(device.Context, appCommandTimeout)
+	defer cancel()
+	return openURLAndroid(ctx, device, url)
+}
+
+func (androidDriver) ForegroundApp(device *models.Device) (string, error) {
+	ctx, cancel := context.WithTimeout(device.Context, appCommandTimeout)
+	defer cancel()
+	return foregroundAppAndroid(ctx, device)
+}
+
+func (androidDriver) Screenshot(device *models.Device) ([]byte, error) {
+	return screenshotAndroid(device)
+}
+
+// GADS-stream serves JPEG frames over a websocket forwarded to the host
+func (androidDriver) StreamSource(device *models.Device) string {
+	return "ws://localhost:" + device.StreamPort
+}
+
+func (androidDriver) HardwareInfo(device *models.Device) error {
+	return updateAndroidHardwareInfo(device)
+}
+
+func (androidDriver) Identify(device *models.Device) error {
+	return identifyAndroidDevice(device)
+}
+
+func (androidDriver) Telemetry(device *models.Device) (models.DeviceTelemetry, error) {
+	return telemetryAndroid(device)
+}
+
+func (androidDriver) Cleanup(ctx context.Context, device *models.Device, action string, policy models.CleanupPolicy) error {
+	return cleanupActionAndroid(ctx, device, action, policy)
+}
+
+func (androidDriver) Reboot(device *models.Device) error {
+	return rebootAndroidDevice(device)
+}
+
+func (androidDriver) ListFiles(device *models.Device, app string, dir string) ([]models.DeviceFile, error) {
+	ctx, cancel := context.WithTimeout(device.Context, fileTransferTimeout)
+	defer cancel()
+	return listFilesAndroid(ctx, device, app, dir)
+}
+
+func (androidDriver) PushFile(device *models.Device, app string, localPath string, devicePath string) error {
+	err := checkLocalFileSize(localPath)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(device.Context, fileTransferTimeout)
+	defer cancel()
+	return pushFileAndroid(ctx, device, app, localPath, devicePath)
+}
+
+func (androidDriver) PullFile(device *models.Device, app string, devicePath string, localPath string) error {
+	ctx, cancel := context.WithTimeout(device.Context, fileTransferTimeout)
+	defer cancel()
+	return pullFileAndroid(ctx, device, app, devicePath, localPath)
+}
+
+func (androidDriver) AppiumCapabilities(device *models.Device) models.AppiumServerCapabilities {
+	return models.AppiumServerCapabilities{
+		UDID:           device.UDID,
+		AutomationName: "UiAutomator2",
+		PlatformName:   "Android",
+		DeviceName:     device.Name,
+	}
+}
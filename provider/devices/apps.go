@@ -0,0 +1,125 @@
+package devices
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"GADS/common/models"
+)
+
+// Limit for app lifecycle commands run from the remote control
+const appCommandTimeout = 30 * time.Second
+
+// Start an app from its launcher activity, monkey resolves the activity from the package name
+func launchAppAndroid(ctx context.Context, device *models.Device, app string) error {
+	output, err := exec.CommandContext(ctx, "adb", "-s", device.UDID, "shell", "monkey", "-p", quoteShellArg(app), "-c", "android.intent.category.LAUNCHER", "1").CombinedOutput()
+	if err != nil || strings.Contains(string(output), "monkey aborted") {
+		return fmt.Errorf("Failed to launch app `%s` - %v: %s", app, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func terminateAppAndroid(ctx context.Context, device *models.Device, app string) error {
+	return cleanupADB(ctx, device, "shell", "am", "force-stop", quoteShellArg(app))
+}
+
+// Open a URL or deep link with the app that handles it
+func openURLAndroid(ctx context.Context, device *models.Device, url string) error {
+	// Quote the URL so `&` and `?` are kept by the device shell
+	output, err := exec.CommandContext(ctx, "adb", "-s", device.UDID, "shell", "am", "start", "-a", "android.intent.action.VIEW", "-d", quoteShellArg(url)).CombinedOutput()
+	if err != nil || strings.Contains(string(output), "Error:") {
+		return fmt.Errorf("Failed to open URL `%s` - %v: %s", url, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Get the package of the resumed activity on an Android device
+func foregroundAppAndroid(ctx context.Context, device *models.Device) (string, error) {
+	output, err := exec.CommandContext(ctx, "adb", "-s", device.UDID, "shell", "dumpsys", "activity", "activities").Output()
+	if err != nil {
+		return "", fmt.Errorf("Failed to get the foreground app - %s", err)
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "mResumedActivity") && !strings.HasPrefix(line, "ResumedActivity") {
+			continue
+		}
+		// e.g. mResumedActivity: ActivityRecord{1a2b3c u0 com.example.app/.MainActivity t12}
+		for _, field := range strings.Fields(line) {
+			if pkg, _, found := strings.Cut(field, "/"); found {
+				return pkg, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+func launchAppIOS(ctx context.Context, device *models.Device, bundleID string) error {
+	return wdaJSONRequest(ctx, device, http.MethodPost, "/session/"+device.WDASessionID+"/wda/apps/launch", map[string]string{"bundleId": bundleID}, nil)
+}
+
+func terminateAppIOS(ctx context.Context, device *models.Device, bundleID string) error {
+	return wdaJSONRequest(ctx, device, http.MethodPost, "/session/"+device.WDASessionID+"/wda/apps/terminate", map[string]string{"bundleId": bundleID}, nil)
+}
+
+// Open a URL or deep link with the app that handles it through WebDriverAgent
+func openURLIOS(ctx context.Context, device *models.Device, url string) error {
+	return wdaJSONRequest(ctx, device, http.MethodPost, "/session/"+device.WDASessionID+"/url", map[string]string{"url": url}, nil)
+}
+
+// Get the bundle ID of the app in the foreground, `com.apple.springboard` for the home screen
+func foregroundAppIOS(ctx context.Context, device *models.Device) (string, error) {
+	var activeApp struct {
+		Value struct {
+			BundleID string `json:"bundleId"`
+		} `json:"value"`
+	}
+	err := wdaJSONRequest(ctx, device, http.MethodGet, "/wda/activeAppInfo", nil, &activeApp)
+	if err != nil {
+		return "", err
+	}
+	return activeApp.Value.BundleID, nil
+}
+
+// Send a request to the WebDriverAgent of an iOS device and optionally unmarshal the response into result
+func wdaJSONRequest(ctx context.Context, device *models.Device, method string, path string, payload interface{}, result interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("Failed to marshal WebDriverAgent request payload - %s", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, "http://localhost:"+device.WDAPort+path, body)
+	if err != nil {
+		return fmt.Errorf("Failed to create WebDriverAgent request - %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	response, err := netClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Failed executing WebDriverAgent request `%s` - %s", path, err)
+	}
+	defer response.Body.Close()
+
+	responseBody, _ := io.ReadAll(response.Body)
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("WebDriverAgent request `%s` returned status code %d - %s", path, response.StatusCode, responseBody)
+	}
+	if result != nil {
+		err = json.Unmarshal(responseBody, result)
+		if err != nil {
+			return fmt.Errorf("Failed to unmarshal WebDriverAgent response for `%s` - %s", path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package devices
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"GADS/common/models"
+	"GADS/provider/config"
+	"GADS/provider/logger"
+)
+
+// Limit for the whole cleanup of a device so it is not held forever if a device command hangs
+const deviceCleanupTimeout = 2 * time.Minute
+
+// Apps GADS needs on the devices, they are never uninstalled or cleared by the cleanup
+var cleanupProtectedApps = []string{
+	"com.shamanec.stream",
+	"io.appium.settings",
+	"io.appium.uiautomator2.server",
+	"io.appium.uiautomator2.server.test",
+}
+
+// Called when an Appium session of the device was deleted through the provider
+// Runs the cleanup policy of the device and the post-session hooks in the background
+// Returns true if the device is held as not available until they finish
+func SessionEnded(device *models.Device) bool {
+	policy := cleanupPolicyFor(device)
+	if policy == nil && len(stageHooks(hookStagePostSession)) == 0 {
+		return false
+	}
+
+	device.StateMu.Lock()
+	device.CleaningUp = true
+	device.StateMu.Unlock()
+
+	go func() {
+		defer func() {
+			device.StateMu.Lock()
+			device.CleaningUp = false
+			device.StateMu.Unlock()
+		}()
+
+		if policy != nil {
+			cleanupDevice(device, *policy)
+		}
+		err := runDeviceHooks(device, hookStagePostSession)
+		if err != nil {
+			logger.ProviderLogger.LogWarn("device_hook", fmt.Sprintf("Post-session hooks failed for device `%s` - %s", device.UDID, err))
+		}
+	}()
+	return true
+}
+
+// Get the cleanup policy for a device, a policy listing the device UDID wins over a policy matching one of its tags
+func cleanupPolicyFor(device *models.Device) *models.CleanupPolicy {
+	policies := config.ProviderConfig().CleanupPolicies
+	for i := range policies {
+		if slices.Contains(policies[i].Devices, device.UDID) {
+			return &policies[i]
+		}
+	}
+	for i := range policies {
+		for _, tag := range device.Tags {
+			if slices.Contains(policies[i].Tags, tag) {
+				return &policies[i]
+			}
+		}
+	}
+	return nil
+}
+
+// Run the actions of a cleanup policy on a device, a failed action is logged and the next action runs anyway
+func cleanupDevice(device *models.Device, policy models.CleanupPolicy) {
+	ctx, cancel := context.WithTimeout(device.Context, deviceCleanupTimeout)
+	defer cancel()
+
+	driver, err := GetDeviceDriver(device)
+	if err != nil {
+		device.Logger.LogWarn("device_cleanup", fmt.Sprintf("Cleanup policy `%s` not run - %s", policy.Name, err))
+		return
+	}
+
+	start := time.Now()
+	for _, action := range policy.Actions {
+		err := driver.Cleanup(ctx, device, action, policy)
+		if err != nil {
+			device.Logger.LogWarn("device_cleanup", fmt.Sprintf("Cleanup action `%s` of policy `%s` failed - %s", action, policy.Name, err))
+		}
+	}
+	device.Logger.LogInfo("device_cleanup", fmt.Sprintf("Cleanup policy `%s` finished in %v", policy.Name, time.Since(start).Round(time.Millisecond)))
+}
+
+func cleanupActionAndroid(ctx context.Context, device *models.Device, action string, policy models.CleanupPolicy) error {
+	switch action {
+	case models.CleanupActionUninstallApps:
+		for _, app := range GetInstalledAppsAndroid(device) {
+			if slices.Contains(cleanupProtectedApps, app) || slices.Contains(policy.AppAllowlist, app) {
+				continue
+			}
+			err := cleanupADB(ctx, device, "uninstall", app)
+			if err != nil {
+				return err
+			}
+		}
+		device.SetInstalledApps(GetInstalledAppsAndroid(device))
+	case models.CleanupActionClearAppData:
+		for _, app := range GetInstalledAppsAndroid(device) {
+			if slices.Contains(cleanupProtectedApps, app) {
+				continue
+			}
+			err := cleanupADB(ctx, device, "shell", "pm", "clear", quoteShellArg(app))
+			if err != nil {
+				return err
+			}
+		}
+	case models.CleanupActionPressHome:
+		return cleanupADB(ctx, device, "shell", "input", "keyevent", "KEYCODE_HOME")
+	case models.CleanupActionResetOrientation:
+		err := cleanupADB(ctx, device, "shell", "settings", "put", "system", "accelerometer_rotation", "0")
+		if err != nil {
+			return err
+		}
+		return cleanupADB(ctx, device, "shell", "settings", "put", "system", "user_rotation", "0")
+	case models.CleanupActionKillForegroundApp:
+		app, err := foregroundAppAndroid(ctx, device)
+		if err != nil {
+			return err
+		}
+		if app == "" || slices.Contains(cleanupProtectedApps, app) {
+			return nil
+		}
+		return cleanupADB(ctx, device, "shell", "am", "force-stop", quoteShellArg(app))
+	}
+	return nil
+}
+
+func cleanupADB(ctx context.Context, device *models.Device, args ...string) error {
+	cmd := exec.CommandContext(ctx, "adb", append([]string{"-s", device.UDID}, args...)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("Failed executing `%s` - %s: %s", cmd.Args, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func cleanupActionIOS(ctx context.Context, device *models.Device, action string, policy models.CleanupPolicy) error {
+	switch action {
+	case models.CleanupActionUninstallApps:
+		for _, app := range GetInstalledAppsIOS(device) {
+			if isWebDriverAgentApp(app) || slices.Contains(policy.AppAllowlist, app) {
+				continue
+			}
+			err := uninstallAppIOS(device, app)
+			if err != nil {
+				return err
+			}
+		}
+		device.SetInstalledApps(GetInstalledAppsIOS(device))
+	case models.CleanupActionClearAppData:
+		return fmt.Errorf("clearing app data is not supported on iOS, use `uninstall_apps` instead")
+	case models.CleanupActionPressHome:
+		return wdaJSONRequest(ctx, device, http.MethodPost, "/wda/homescreen", nil, nil)
+	case models.CleanupActionResetOrientation:
+		return wdaJSONRequest(ctx, device, http.MethodPost, "/session/"+device.WDASessionID+"/orientation", map[string]string{"orientation": "PORTRAIT"}, nil)
+	case models.CleanupActionKillForegroundApp:
+		bundleID, err := foregroundAppIOS(ctx, device)
+		if err != nil {
+			return err
+		}
+		if bundleID == "" || bundleID == "com.apple.springboard" || isWebDriverAgentApp(bundleID) {
+			return nil
+		}
+		return terminateAppIOS(ctx, device, bundleID)
+	}
+	return nil
+}
+
+func isWebDriverAgentApp(bundleID string) bool {
+	return (config.ProviderConfig().WdaBundleID != "" && bundleID == config.ProviderConfig().WdaBundleID) || strings.Contains(bundleID, "WebDriverAgentRunner")
+}
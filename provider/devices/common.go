@@ -14,7 +14,6 @@ import (
 	"slices"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/Masterminds/semver"
@@ -36,147 +35,117 @@ import (
 var netClient = &http.Client{
 	Timeout: time.Second * 120,
 }
-var DBDeviceMap = make(map[string]*models.Device)
 
 func Listener() {
 	Setup()
-	DBDeviceMap = getDBProviderDevices()
+	if config.ProviderConfig().FakeDevices > 0 {
+		err := createFakeDevices(config.ProviderConfig().FakeDevices)
+		if err != nil {
+			logger.ProviderLogger.LogError("provider", fmt.Sprintf("Failed to create fake devices in DB - %s", err))
+		}
+	}
+	Registry.Replace(getDBProviderDevices())
 	setupDevices()
+	lastDevicesSync = time.Now()
 
 	// Create pair record manager for go-ios tunnel handling of iOS 17.4+
-	pm, err := tunnel.NewPairRecordManager(config.ProviderConfig.ProviderFolder)
+	pm, err := tunnel.NewPairRecordManager(config.ProviderConfig().ProviderFolder)
 	if err != nil {
 		os.Exit(1)
 	}
-	config.ProviderConfig.GoIOSPairRecordManager = pm
+	config.UpdateConfig(func(provider *models.Provider) {
+		provider.GoIOSPairRecordManager = pm
+	})
+
+	// Start the emulators that should run together with the provider, they are discovered through adb when booted
+	go startAutoStartEmulators()
+	// Keep the network-attached Android devices connected through adb
+	if config.ProviderConfig().ProvideAndroid {
+		go maintainNetworkDevices()
+	}
 
 	// Start updating devices each 10 seconds in a goroutine
 	go updateDevices()
-	// Start updating the local devices data to the hub in a goroutine
-	go updateProviderHub()
+	// Collect the telemetry of the live devices and take devices breaking the thresholds out of rotation
+	go monitorDevicesTelemetry()
+	// Start pushing the local devices data to the hub over a persistent connection in a goroutine
+	go connectToHub()
 }
 
-func updateProviderHub() {
-	client := &http.Client{
-		Timeout: 5 * time.Second,
+// When provider is started and respective devices are taken from the DB, we do the initial device data setup here
+func setupDevices() {
+	for _, dbDevice := range Registry.All() {
+		prepareDevice(dbDevice)
 	}
-	var updateFailureCounter = 1
-	var mu sync.Mutex
-
-	for {
-		if updateFailureCounter >= 30 {
-			log.Fatalf("Unsuccessfully attempted to update device data in hub for 10 times, killing provider")
-		}
-		time.Sleep(1 * time.Second)
-
-		mu.Lock()
+}
 
-		var properJson models.ProviderData
-		for _, dbDevice := range DBDeviceMap {
-			properJson.DeviceData = append(properJson.DeviceData, *dbDevice)
-			properJson.ProviderData = *config.ProviderConfig
-		}
-		mu.Unlock()
-		jsonData, err := json.Marshal(properJson)
-		if err != nil {
-			updateFailureCounter++
-			logger.ProviderLogger.LogError("update_provider_hub", "Failed marshaling provider data to json - "+err.Error())
-			continue
-		}
-		req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/provider-update", config.ProviderConfig.HubAddress), bytes.NewBuffer(jsonData))
-		if err != nil {
-			updateFailureCounter++
-			logger.ProviderLogger.LogError("update_provider_hub", "Failed to create request to update provider data in hub - "+err.Error())
-			continue
-		}
+// Prepare the provider data of a device from the DB - logger, Appium logs collection, etc.
+func prepareDevice(dbDevice *models.Device) {
+	dbDevice.ResetState("device loaded from DB")
+	dbDevice.SetConnected(false)
+	dbDevice.LastUpdatedTimestamp = 0
+	dbDevice.InitialSetupDone = false
 
-		resp, err := client.Do(req)
-		if err != nil {
-			updateFailureCounter++
-			logger.ProviderLogger.LogError("update_provider_hub", fmt.Sprintf("Failed to execute request to update provider data in hub, hub is probably down, current retry counter is `%v` - %s", updateFailureCounter, err))
-			continue
-		}
+	dbDevice.Host = fmt.Sprintf("%s:%v", config.ProviderConfig().HostAddress, config.ProviderConfig().Port)
 
-		if resp.StatusCode != 200 {
-			updateFailureCounter++
-			logger.ProviderLogger.LogError("update_provider_hub", fmt.Sprintf("Executed request to update provider data in hub but it was not successful, current retry counter is `%v` - %s", updateFailureCounter, err))
-			continue
-		}
-		// Reset the counter if update went well
-		updateFailureCounter = 1
+	semver, err := semver.NewVersion(dbDevice.OSVersion)
+	if err != nil {
+		logger.ProviderLogger.Errorf("updateDevices: Failed to get semver for device `%s` - %s", dbDevice.UDID, err)
+		return
 	}
-}
-
-// When provider is started and respective devices are taken from the DB, we do the initial device data setup here
-func setupDevices() {
-	for _, dbDevice := range DBDeviceMap {
-		dbDevice.ProviderState = "init"
-		dbDevice.Connected = false
-		dbDevice.LastUpdatedTimestamp = 0
-		dbDevice.IsResetting = false
-		dbDevice.InitialSetupDone = false
+	dbDevice.SemVer = semver
 
-		dbDevice.Host = fmt.Sprintf("%s:%v", config.ProviderConfig.HostAddress, config.ProviderConfig.Port)
-
-		semver, err := semver.NewVersion(dbDevice.OSVersion)
-		if err != nil {
-			logger.ProviderLogger.Errorf("updateDevices: Failed to get semver for device `%s` - %s", dbDevice, err)
-			continue
-		}
-		dbDevice.SemVer = semver
+	// Check if a capped Appium logs collection already exists for the current device
+	exists, err := db.CollectionExists("appium_logs", dbDevice.UDID)
+	if err != nil {
+		logger.ProviderLogger.Warnf("Could not check if device collection exists in `appium_logs` db, will attempt to create it either way - %s", err)
+	}
 
-		// Check if a capped Appium logs collection already exists for the current device
-		exists, err := db.CollectionExists("appium_logs", dbDevice.UDID)
+	// If it doesn't exist - attempt to create it
+	if !exists {
+		err = db.CreateCappedCollection("appium_logs", dbDevice.UDID, 30000, 30)
 		if err != nil {
-			logger.ProviderLogger.Warnf("Could not check if device collection exists in `appium_logs` db, will attempt to create it either way - %s", err)
-		}
-
-		// If it doesn't exist - attempt to create it
-		if !exists {
-			err = db.CreateCappedCollection("appium_logs", dbDevice.UDID, 30000, 30)
-			if err != nil {
-				logger.ProviderLogger.Errorf("updateDevices: Failed to create capped collection for device `%s` - %s", dbDevice, err)
-				continue
-			}
+			logger.ProviderLogger.Errorf("updateDevices: Failed to create capped collection for device `%s` - %s", dbDevice.UDID, err)
+			return
 		}
+	}
 
-		// Create an index model and add it to the respective device Appium log collection
-		appiumCollectionIndexModel := mongo.IndexModel{
-			Keys: bson.D{
-				{
-					Key: "ts", Value: constants.SortAscending},
-				{
-					Key: "session_id", Value: constants.SortAscending,
-				},
+	// Create an index model and add it to the respective device Appium log collection
+	appiumCollectionIndexModel := mongo.IndexModel{
+		Keys: bson.D{
+			{
+				Key: "ts", Value: constants.SortAscending},
+			{
+				Key: "session_id", Value: constants.SortAscending,
 			},
-		}
-		db.AddCollectionIndex("appium_logs", dbDevice.UDID, appiumCollectionIndexModel)
-
-		// Create logs directory for the device if it doesn't already exist
-		if _, err := os.Stat(fmt.Sprintf("%s/device_%s", config.ProviderConfig.ProviderFolder, dbDevice.UDID)); os.IsNotExist(err) {
-			err = os.Mkdir(fmt.Sprintf("%s/device_%s", config.ProviderConfig.ProviderFolder, dbDevice.UDID), os.ModePerm)
-			if err != nil {
-				logger.ProviderLogger.Errorf("updateDevices: Could not create logs folder for device `%s` - %s\n", dbDevice.UDID, err)
-				continue
-			}
-		}
+		},
+	}
+	db.AddCollectionIndex("appium_logs", dbDevice.UDID, appiumCollectionIndexModel)
 
-		// Create a custom logger and attach it to the local device
-		deviceLogger, err := logger.CreateCustomLogger(fmt.Sprintf("%s/device_%s/device.log", config.ProviderConfig.ProviderFolder, dbDevice.UDID), dbDevice.UDID)
+	// Create logs directory for the device if it doesn't already exist
+	if _, err := os.Stat(fmt.Sprintf("%s/device_%s", config.ProviderConfig().ProviderFolder, dbDevice.UDID)); os.IsNotExist(err) {
+		err = os.Mkdir(fmt.Sprintf("%s/device_%s", config.ProviderConfig().ProviderFolder, dbDevice.UDID), os.ModePerm)
 		if err != nil {
-			logger.ProviderLogger.Errorf("updateDevices: Could not create custom logger for device `%s` - %s\n", dbDevice.UDID, err)
-			continue
+			logger.ProviderLogger.Errorf("updateDevices: Could not create logs folder for device `%s` - %s\n", dbDevice.UDID, err)
+			return
 		}
-		dbDevice.Logger = *deviceLogger
+	}
 
-		appiumLogger, err := logger.NewAppiumLogger(fmt.Sprintf("%s/device_%s/appium.log", config.ProviderConfig.ProviderFolder, dbDevice.UDID), dbDevice.UDID)
-		if err != nil {
-			logger.ProviderLogger.Errorf("updateDevices: Could not create Appium logger for device `%s` - %s\n", dbDevice.UDID, err)
-			continue
-		}
-		dbDevice.AppiumLogger = appiumLogger
-		dbDevice.InitialSetupDone = true
+	// Create a custom logger and attach it to the local device
+	deviceLogger, err := logger.CreateCustomLogger(fmt.Sprintf("%s/device_%s/device.log", config.ProviderConfig().ProviderFolder, dbDevice.UDID), dbDevice.UDID)
+	if err != nil {
+		logger.ProviderLogger.Errorf("updateDevices: Could not create custom logger for device `%s` - %s\n", dbDevice.UDID, err)
+		return
+	}
+	dbDevice.Logger = *deviceLogger
+
+	appiumLogger, err := logger.NewAppiumLogger(fmt.Sprintf("%s/device_%s/appium.log", config.ProviderConfig().ProviderFolder, dbDevice.UDID), dbDevice.UDID)
+	if err != nil {
+		logger.ProviderLogger.Errorf("updateDevices: Could not create Appium logger for device `%s` - %s\n", dbDevice.UDID, err)
+		return
 	}
+	dbDevice.AppiumLogger = appiumLogger
+	dbDevice.InitialSetupDone = true
 }
 
 func updateDevices() {
@@ -184,33 +153,51 @@ func updateDevices() {
 	defer ticker.Stop()
 
 	for range ticker.C {
-		connectedDevices := GetConnectedDevicesCommon()
+		// Devices are not set up anymore while the provider is shutting down
+		if shuttingDown.Load() {
+			return
+		}
+		connectedDevices := discoverConnectedDevices()
+		syncDBDevices()
+		addRegisteredDevices()
+		registerNewDevices(connectedDevices)
 
 	DEVICE_MAP_LOOP:
-		for dbDeviceUDID, dbDevice := range DBDeviceMap {
+		for _, dbDevice := range Registry.All() {
 			if dbDevice.Usage == "disabled" {
 				continue DEVICE_MAP_LOOP
 			}
-			if slices.Contains(connectedDevices, dbDeviceUDID) {
-				dbDevice.Connected = true
-				if dbDevice.ProviderState != "preparing" && dbDevice.ProviderState != "live" {
-					setContext(dbDevice)
-					dbDevice.AppiumReadyChan = make(chan bool, 1)
-					if dbDevice.OS == "ios" {
-						dbDevice.WdaReadyChan = make(chan bool, 1)
-						go setupIOSDevice(dbDevice)
+			_, connected := connectedDevices[dbDevice.UDID]
+			// Pending devices only report if they are connected, they are set up after an admin approves them
+			if dbDevice.Usage == "pending" {
+				dbDevice.SetConnected(connected)
+				continue DEVICE_MAP_LOOP
+			}
+			if connected {
+				dbDevice.SetConnected(true)
+				forgetStableSetupFailures(dbDevice)
+				// Devices being set up, live or being reset are left alone, as well as devices waiting after failed setups
+				if dbDevice.State() == models.DeviceStateInit && setupAllowed(dbDevice) && deviceProcessesStopped(dbDevice.UDID) {
+					driver, err := GetDeviceDriver(dbDevice)
+					if err != nil {
+						logger.ProviderLogger.LogError("provider", err.Error())
+						continue DEVICE_MAP_LOOP
 					}
-
-					if dbDevice.OS == "android" {
-						go setupAndroidDevice(dbDevice)
+					// The state is changed before starting the setup so the next update does not start it again
+					if !setDeviceState(dbDevice, models.DeviceStatePreparing, "device connected") {
+						continue DEVICE_MAP_LOOP
 					}
+					setContext(dbDevice)
+					dbDevice.AppiumReadyChan = make(chan bool, 1)
+					go setupDeviceWithHooks(driver, dbDevice)
 				}
 			} else {
-				dbDevice.ProviderState = "init"
-				dbDevice.IsResetting = false
-				dbDevice.Connected = false
-				if dbDevice.GoIOSTunnel.Address != "" {
-					dbDevice.GoIOSTunnel.Close()
+				dbDevice.SetConnected(false)
+				// Devices that are set up or live are reset so their context is cancelled and the processes of the setup stop
+				if dbDevice.State() == models.DeviceStateInit {
+					teardownDevice(dbDevice)
+				} else {
+					resetDevice(dbDevice, "")
 				}
 			}
 		}
@@ -218,63 +205,69 @@ func updateDevices() {
 }
 
 func Setup() {
-	if config.ProviderConfig.ProvideAndroid {
+	if config.ProviderConfig().ProvideAndroid {
 		err := providerutil.CheckGadsStreamAndDownload()
 		if err != nil {
 			log.Fatalf("Setup: Could not check availability of and download GADS-stream latest release - %s", err)
 		}
 	}
+
+	if len(config.ProviderConfig().NetworkDevices) > 0 && !config.ProviderConfig().ProvideAndroid {
+		logger.ProviderLogger.LogWarn("provider_setup", "Network devices are configured but the provider does not provide Android devices, they will not be connected")
+	}
+
+	if len(config.ProviderConfig().Emulators) > 0 {
+		if !config.ProviderConfig().ProvideAndroid {
+			logger.ProviderLogger.LogWarn("provider_setup", "Emulators are configured but the provider does not provide Android devices, they will not be discovered")
+		}
+		if !providerutil.EmulatorAvailable() {
+			logger.ProviderLogger.LogWarn("provider_setup", "Emulators are configured but the `emulator` binary of the Android SDK is not available on PATH, they cannot be started")
+		}
+	}
 }
 
 func setupAndroidDevice(device *models.Device) {
-	device.ProviderState = "preparing"
-
 	logger.ProviderLogger.LogInfo("android_device_setup", fmt.Sprintf("Running setup for device `%v`", device.UDID))
 
 	// If Selenium Grid is used attempt to create a TOML file for the grid connection
-	if config.ProviderConfig.UseSeleniumGrid {
+	if config.ProviderConfig().UseSeleniumGrid {
+		device.StartSetupStep("create_grid_config")
 		err := createGridTOML(device)
 		if err != nil {
-			logger.ProviderLogger.LogError("ios_device_setup", fmt.Sprintf("Selenium Grid use is enabled but couldn't create TOML for device `%s` - %s", device.UDID, err))
-			resetLocalDevice(device)
+			failDeviceSetup(device, "ios_device_setup", fmt.Sprintf("Selenium Grid use is enabled but couldn't create TOML for device `%s` - %s", device.UDID, err))
 			return
 		}
 	}
-	getAndroidDeviceHardwareModel(device)
-
-	if device.ScreenHeight == "" || device.ScreenWidth == "" {
-		err := updateAndroidScreenSizeADB(device)
-		if err != nil {
-			logger.ProviderLogger.LogError("android_device_setup", fmt.Sprintf("Failed to update screen dimensions with adb for device `%v` - %v", device.UDID, err))
-			resetLocalDevice(device)
-			return
-		}
+	device.StartSetupStep("update_hardware_info")
+	err := updateAndroidHardwareInfo(device)
+	if err != nil {
+		failDeviceSetup(device, "android_device_setup", fmt.Sprintf("Failed to update hardware info for device `%v` - %v", device.UDID, err))
+		return
 	}
 
-	streamPort, err := providerutil.GetFreePort()
+	device.StartSetupStep("allocate_ports")
+	streamPort, err := providerutil.AllocatePort(models.PortPurposeStream, device.UDID)
 	if err != nil {
-		logger.ProviderLogger.LogError("android_device_setup", fmt.Sprintf("Could not allocate free host port for GADS-stream for device `%v` - %v", device.UDID, err))
-		resetLocalDevice(device)
+		failDeviceSetup(device, "android_device_setup", fmt.Sprintf("Could not allocate free host port for GADS-stream for device `%v` - %v", device.UDID, err))
 		return
 	}
 	device.StreamPort = streamPort
 
-	appiumPort, err := providerutil.GetFreePort()
+	appiumPort, err := providerutil.AllocatePort(models.PortPurposeAppium, device.UDID)
 	if err != nil {
-		logger.ProviderLogger.LogError("android_device_setup", fmt.Sprintf("Could not allocate free host port for Appium for device `%v` - %v", device.UDID, err))
-		resetLocalDevice(device)
+		failDeviceSetup(device, "android_device_setup", fmt.Sprintf("Could not allocate free host port for Appium for device `%v` - %v", device.UDID, err))
 		return
 	}
 	device.AppiumPort = appiumPort
 
+	device.StartSetupStep("install_gads_stream")
 	apps := GetInstalledAppsAndroid(device)
 	if slices.Contains(apps, "com.shamanec.stream") {
 		stopGadsStreamService(device)
 		time.Sleep(3 * time.Second)
 		err = uninstallGadsStream(device)
 		if err != nil {
-			logger.ProviderLogger.LogError("android_device_setup", fmt.Sprintf("Could not uninstall GADS-stream from Android device - %v:\n %v", device.UDID, err))
-			resetLocalDevice(device)
+			failDeviceSetup(device, "android_device_setup", fmt.Sprintf("Could not uninstall GADS-stream from Android device - %v:\n %v", device.UDID, err))
 			return
 		}
 		time.Sleep(3 * time.Second)
@@ -282,64 +275,66 @@ func setupAndroidDevice(device *models.Device) {
 
 	err = installGadsStream(device)
 	if err != nil {
-		logger.ProviderLogger.LogError("android_device_setup", fmt.Sprintf("Could not install GADS-stream on Android device - %v:\n %v", device.UDID, err))
-		resetLocalDevice(device)
+		failDeviceSetup(device, "android_device_setup", fmt.Sprintf("Could not install GADS-stream on Android device - %v:\n %v", device.UDID, err))
 		return
 	}
 	time.Sleep(2 * time.Second)
 
+	device.StartSetupStep("grant_gads_stream_permissions")
 	err = addGadsStreamRecordingPermissions(device)
 	if err != nil {
-		logger.ProviderLogger.LogError("android_device_setup", fmt.Sprintf("Could not set GADS-stream recording permissions on Android device - %v:\n %v", device.UDID, err))
-		resetLocalDevice(device)
+		failDeviceSetup(device, "android_device_setup", fmt.Sprintf("Could not set GADS-stream recording permissions on Android device - %v:\n %v", device.UDID, err))
 		return
 	}
 	time.Sleep(2 * time.Second)
 
+	device.StartSetupStep("start_gads_stream")
 	err = startGadsStreamApp(device)
 	if err != nil {
-		logger.ProviderLogger.LogError("android_device_setup", fmt.Sprintf("Could not start GADS-stream app on Android device - %v:\n %v", device.UDID, err))
-		resetLocalDevice(device)
+		failDeviceSetup(device, "android_device_setup", fmt.Sprintf("Could not start GADS-stream app on Android device - %v:\n %v", device.UDID, err))
 		return
 	}
 	time.Sleep(2 * time.Second)
 
 	pressHomeButton(device)
 
+	device.StartSetupStep("forward_gads_stream")
 	err = forwardGadsStream(device)
 	if err != nil {
-		logger.ProviderLogger.LogError("android_device_setup", fmt.Sprintf("Could not forward GADS-stream port to host port %v for Android device - %v:\n %v", device.StreamPort, device.UDID, err))
-		resetLocalDevice(device)
+		failDeviceSetup(device, "android_device_setup", fmt.Sprintf("Could not forward GADS-stream port to host port %v for Android device - %v:\n %v", device.StreamPort, device.UDID, err))
 		return
 	}
 
-	device.InstalledApps = GetInstalledAppsAndroid(device)
+	device.StartSetupStep("remove_appium_apps")
+	installedApps := GetInstalledAppsAndroid(device)
+	device.SetInstalledApps(installedApps)
 
-	if slices.Contains(device.InstalledApps, "io.appium.settings") {
+	if slices.Contains(installedApps, "io.appium.settings") {
 		logger.ProviderLogger.LogInfo("android_device_setup", "Appium settings found on device, attempting to uninstall")
-		err = UninstallApp(device, "io.appium.settings")
+		err = uninstallAppAndroid(device, "io.appium.settings")
 		if err != nil {
 			logger.ProviderLogger.LogWarn("android_device_setup", fmt.Sprintf("Failed to uninstall Appium settings on device %s - %s", device.UDID, err))
 		}
 	}
 
-	if slices.Contains(device.InstalledApps, "io.appium.uiautomator2.server") {
+	if slices.Contains(installedApps, "io.appium.uiautomator2.server") {
 		logger.ProviderLogger.LogInfo("android_device_setup", "Appium uiautomator2 server found on device, attempting to uninstall")
-		err = UninstallApp(device, "io.appium.uiautomator2.server")
+		err = uninstallAppAndroid(device, "io.appium.uiautomator2.server")
 		if err != nil {
 			logger.ProviderLogger.LogWarn("android_device_setup", fmt.Sprintf("Failed to uninstall Appium uiautomator2 server on device %s - %s", device.UDID, err))
 		}
 	}
 
-	if slices.Contains(device.InstalledApps, "io.appium.uiautomator2.server.test") {
+	if slices.Contains(installedApps, "io.appium.uiautomator2.server.test") {
 		logger.ProviderLogger.LogInfo("android_device_setup", "Appium uiautomator2 server test found on device, attempting to uninstall")
-		err = UninstallApp(device, "io.appium.uiautomator2.server.test")
+		err = uninstallAppAndroid(device, "io.appium.uiautomator2.server.test")
 		if err != nil {
 			logger.ProviderLogger.LogWarn("android_device_setup", fmt.Sprintf("Failed to uninstall Appium uiautomator2 server test on device %s - %s", device.UDID, err))
 		}
 	}
 
-	go startAppium(device)
+	device.StartSetupStep("start_appium")
+	go startAppium(device, androidDriver{}.AppiumCapabilities(device))
 	go checkAppiumUp(device)
 
 	select {
@@ -347,82 +342,70 @@ func setupAndroidDevice(device *models.Device) {
 		logger.ProviderLogger.LogInfo("ios_device_setup", fmt.Sprintf("Successfully started Appium for device `%v` on port %v", device.UDID, device.AppiumPort))
 		break
 	case <-time.After(30 * time.Second):
-		logger.ProviderLogger.LogError("ios_device_setup", fmt.Sprintf("Did not successfully start Appium for device `%v` in 60 seconds", device.UDID))
-		resetLocalDevice(device)
+		failDeviceSetup(device, "ios_device_setup", fmt.Sprintf("Did not successfully start Appium for device `%v` in 60 seconds", device.UDID))
 		return
 	}
 
-	if config.ProviderConfig.UseSeleniumGrid {
+	if config.ProviderConfig().UseSeleniumGrid {
 		go startGridNode(device)
 	}
 
-	// Mark the device as 'live'
-	device.ProviderState = "live"
+	// Run the post-setup hooks and mark the device as 'live'
+	finishDeviceSetup(device)
 }
 
 func setupIOSDevice(device *models.Device) {
-	device.ProviderState = "preparing"
 	logger.ProviderLogger.LogInfo("ios_device_setup", fmt.Sprintf("Running setup for device `%v`", device.UDID))
 
-	if device.SemVer.Major() >= 17 && device.SemVer.Minor() < 4 && config.ProviderConfig.OS != "darwin" {
+	if device.SemVer.Major() >= 17 && device.SemVer.Minor() < 4 && config.ProviderConfig().OS != "darwin" {
 		logger.ProviderLogger.LogInfo("ios_device_setup", fmt.Sprintf("Windows/Linux support only iOS < 17 and iOS >= 17.4, setup for device `%s` will be skipped", device.UDID))
-		device.ProviderState = "init"
+		setDeviceState(device, models.DeviceStateInit, "device setup not supported")
 		return
 	}
 
+	device.StartSetupStep("get_device_entry")
 	goIosDeviceEntry, err := ios.GetDevice(device.UDID)
 	if err != nil {
-		logger.ProviderLogger.LogError("ios_device_setup", fmt.Sprintf("Could not get `go-ios` DeviceEntry for device - %v, err - %v", device.UDID, err))
-		resetLocalDevice(device)
+		failDeviceSetup(device, "ios_device_setup", fmt.Sprintf("Could not get `go-ios` DeviceEntry for device - %v, err - %v", device.UDID, err))
 		return
 	}
 
 	device.GoIOSDeviceEntry = goIosDeviceEntry
 
 	// Pair the device with go-ios
+	device.StartSetupStep("pair")
 	err = pairIOS(device)
 	if err != nil {
-		logger.ProviderLogger.LogError("ios_device_setup", fmt.Sprintf("Failed to pair device `%s` - %v", device.UDID, err))
-		resetLocalDevice(device)
+		failDeviceSetup(device, "ios_device_setup", fmt.Sprintf("Failed to pair device `%s` - %v", device.UDID, err))
 		return
 	}
 
 	// Mount the DDI on the device
+	device.StartSetupStep("mount_ddi")
 	mountDeveloperImageIOS(device)
 
-	// Get device info with go-ios to get the hardware model
-	plistValues, err := ios.GetValuesPlist(device.GoIOSDeviceEntry)
+	// Get device info with go-ios to get the hardware model and screen dimensions
+	device.StartSetupStep("update_hardware_info")
+	err = updateIOSHardwareInfo(device)
 	if err != nil {
-		logger.ProviderLogger.LogError("ios_device_setup", fmt.Sprintf("Could not get info plist values with go-ios `%v` - %v", device.UDID, err))
-		resetLocalDevice(device)
+		failDeviceSetup(device, "ios_device_setup", fmt.Sprintf("Failed to update hardware info for device `%s` - %s", device.UDID, err))
 		return
 	}
-	// Update hardware model got from plist
-	device.HardwareModel = plistValues["HardwareModel"].(string)
-
-	if device.ScreenHeight == "" || device.ScreenWidth == "" {
-		err = updateIOSScreenSize(device, plistValues["ProductType"].(string))
-		if err != nil {
-			logger.ProviderLogger.LogError("ios_device_setup", fmt.Sprintf("Failed to update screen dimensions for device `%s` - %s", device.UDID, err))
-			resetLocalDevice(device)
-			return
-		}
-	}
 
 	// If Selenium Grid is used attempt to create a TOML file for the grid connection
-	if config.ProviderConfig.UseSeleniumGrid {
+	if config.ProviderConfig().UseSeleniumGrid {
+		device.StartSetupStep("create_grid_config")
 		err := createGridTOML(device)
 		if err != nil {
-			logger.ProviderLogger.LogError("ios_device_setup", fmt.Sprintf("Selenium Grid use is enabled but couldn't create TOML for device `%s` - %s", device.UDID, err))
-			resetLocalDevice(device)
+			failDeviceSetup(device, "ios_device_setup", fmt.Sprintf("Selenium Grid use is enabled but couldn't create TOML for device `%s` - %s", device.UDID, err))
 			return
 		}
 	}
 
-	tunnelPort, err := providerutil.GetFreePort()
+	device.StartSetupStep("allocate_tunnel_port")
+	tunnelPort, err := providerutil.AllocatePort(models.PortPurposeTunnel, device.UDID)
 	if err != nil {
-		logger.ProviderLogger.LogError("ios_device_setup", fmt.Sprintf("Could not allocate free WebDriverAgent port for device `%v` - %v", device.UDID, err))
-		resetLocalDevice(device)
+		failDeviceSetup(device, "ios_device_setup", fmt.Sprintf("Could not allocate free go-ios tunnel port for device `%v` - %v", device.UDID, err))
 		return
 	}
 	intTunnelPort, _ := strconv.Atoi(tunnelPort)
@@ -430,10 +413,10 @@ func setupIOSDevice(device *models.Device) {
 
 	// Create userspace tunnel for devices iOS 17.4+
 	if device.SemVer.Major() >= 17 && device.SemVer.Minor() >= 4 {
+		device.StartSetupStep("create_tunnel")
 		deviceTunnel, err := createGoIOSTunnel(device.Context, device)
 		if err != nil {
-			logger.ProviderLogger.LogError("ios_device_setup", fmt.Sprintf("Failed to create userspace tunnel for device `%s` - %v", device.UDID, err))
-			resetLocalDevice(device)
+			failDeviceSetup(device, "ios_device_setup", fmt.Sprintf("Failed to create userspace tunnel for device `%s` - %v", device.UDID, err))
 			return
 		}
 		device.GoIOSTunnel = deviceTunnel
@@ -444,68 +427,67 @@ func setupIOSDevice(device *models.Device) {
 
 		err = goIosDeviceWithRsdProvider(device)
 		if err != nil {
-			logger.ProviderLogger.LogError("ios_device_setup", fmt.Sprintf("Failed to create go-ios device entry with rsd provider for device `%s` - %v", device.UDID, err))
-			resetLocalDevice(device)
+			failDeviceSetup(device, "ios_device_setup", fmt.Sprintf("Failed to create go-ios device entry with rsd provider for device `%s` - %v", device.UDID, err))
 			return
 		}
 	}
 
 	time.Sleep(1 * time.Second)
 
-	wdaPort, err := providerutil.GetFreePort()
+	device.StartSetupStep("allocate_ports")
+	wdaPort, err := providerutil.AllocatePort(models.PortPurposeWDA, device.UDID)
 	if err != nil {
-		logger.ProviderLogger.LogError("ios_device_setup", fmt.Sprintf("Could not allocate free WebDriverAgent port for device `%v` - %v", device.UDID, err))
-		resetLocalDevice(device)
+		failDeviceSetup(device, "ios_device_setup", fmt.Sprintf("Could not allocate free WebDriverAgent port for device `%v` - %v", device.UDID, err))
 		return
 	}
 	device.WDAPort = wdaPort
 
-	streamPort, err := providerutil.GetFreePort()
+	streamPort, err := providerutil.AllocatePort(models.PortPurposeStream, device.UDID)
 	if err != nil {
-		logger.ProviderLogger.LogError("ios_device_setup", fmt.Sprintf("Could not allocate free iOS stream port for device `%v` - %v", device.UDID, err))
-		resetLocalDevice(device)
+		failDeviceSetup(device, "ios_device_setup", fmt.Sprintf("Could not allocate free iOS stream port for device `%v` - %v", device.UDID, err))
 		return
 	}
 	device.StreamPort = streamPort
 
-	wdaStreamPort, err := providerutil.GetFreePort()
+	wdaStreamPort, err := providerutil.AllocatePort(models.PortPurposeStream, device.UDID)
 	if err != nil {
-		logger.ProviderLogger.LogError("ios_device_setup", fmt.Sprintf("Could not allocate free WebDriverAgent stream port for device `%v` - %v", device.UDID, err))
-		resetLocalDevice(device)
+		failDeviceSetup(device, "ios_device_setup", fmt.Sprintf("Could not allocate free WebDriverAgent stream port for device `%v` - %v", device.UDID, err))
 		return
 	}
 	device.WDAStreamPort = wdaStreamPort
 
-	appiumPort, err := providerutil.GetFreePort()
+	appiumPort, err := providerutil.AllocatePort(models.PortPurposeAppium, device.UDID)
 	if err != nil {
-		logger.ProviderLogger.LogError("ios_device_setup", fmt.Sprintf("Could not allocate free Appium port for device `%v` - %v", device.UDID, err))
-		resetLocalDevice(device)
+		failDeviceSetup(device, "ios_device_setup", fmt.Sprintf("Could not allocate free Appium port for device `%v` - %v", device.UDID, err))
 		return
 	}
 	device.AppiumPort = appiumPort
 
 	// Forward the WebDriverAgent server and stream to the host
+	device.StartSetupStep("forward_ports")
 	go goIosForward(device, device.WDAPort, "8100")
 	go goIosForward(device, device.StreamPort, "9500")
 	go goIosForward(device, device.WDAStreamPort, "9100")
 
 	wdaPath := ""
-	if config.ProviderConfig.OS != "darwin" {
-		wdaPath = fmt.Sprintf("%s/%s", config.ProviderConfig.ProviderFolder, config.ProviderConfig.WebDriverBinary)
+	if config.ProviderConfig().OS != "darwin" {
+		wdaPath = fmt.Sprintf("%s/%s", config.ProviderConfig().ProviderFolder, config.ProviderConfig().WebDriverBinary)
 	} else {
-		wdaRepoPath := strings.TrimSuffix(config.ProviderConfig.WdaRepoPath, "/")
+		wdaRepoPath := strings.TrimSuffix(config.ProviderConfig().WdaRepoPath, "/")
 		wdaPath = fmt.Sprintf("%s/build/Build/Products/Debug-iphoneos/WebDriverAgentRunner-Runner.app", wdaRepoPath)
 	}
 
 	if device.SemVer.Major() < 17 || (device.SemVer.Major() >= 17 && device.SemVer.Minor() >= 4) {
+		device.StartSetupStep("install_wda")
 		err = installAppIOS(device, wdaPath)
 		if err != nil {
-			logger.ProviderLogger.LogError("ios_device_setup", fmt.Sprintf("Could not install WebDriverAgent on device `%s` - %s", device.UDID, err))
-			resetLocalDevice(device)
+			failDeviceSetup(device, "ios_device_setup", fmt.Sprintf("Could not install WebDriverAgent on device `%s` - %s", device.UDID, err))
 			return
 		}
+		device.StartSetupStep("start_wda")
 		go runWDAGoIOS(device)
 	} else {
+		device.StartSetupStep("start_wda")
 		go startWdaWithXcodebuild(device)
 	}
 
@@ -517,20 +499,20 @@ func setupIOSDevice(device *models.Device) {
 		logger.ProviderLogger.LogInfo("ios_device_setup", fmt.Sprintf("Successfully started WebDriverAgent for device `%v` forwarded on port %v", device.UDID, device.WDAPort))
 		break
 	case <-time.After(60 * time.Second):
-		logger.ProviderLogger.LogError("ios_device_setup", fmt.Sprintf("Did not successfully start WebDriverAgent on device `%v` in 60 seconds", device.UDID))
-		resetLocalDevice(device)
+		failDeviceSetup(device, "ios_device_setup", fmt.Sprintf("Did not successfully start WebDriverAgent on device `%v` in 60 seconds", device.UDID))
 		return
 	}
 
 	// Create a WebDriverAgent session and update the MJPEG stream settings
+	device.StartSetupStep("update_wda")
 	err = updateWebDriverAgent(device)
 	if err != nil {
-		logger.ProviderLogger.LogError("ios_device_setup", fmt.Sprintf("Did not successfully create WebDriverAgent session or update its stream settings for device `%v` - %v", device.UDID, err))
-		resetLocalDevice(device)
+		failDeviceSetup(device, "ios_device_setup", fmt.Sprintf("Did not successfully create WebDriverAgent session or update its stream settings for device `%v` - %v", device.UDID, err))
 		return
 	}
 
-	go startAppium(device)
+	device.StartSetupStep("start_appium")
+	go startAppium(device, iosDriver{}.AppiumCapabilities(device))
 	go checkAppiumUp(device)
 
 	// Wait until WebDriverAgent successfully starts
@@ -539,38 +521,43 @@ func setupIOSDevice(device *models.Device) {
 		logger.ProviderLogger.LogInfo("ios_device_setup", fmt.Sprintf("Successfully started Appium for device `%v` on port %v", device.UDID, device.AppiumPort))
 		break
 	case <-time.After(30 * time.Second):
-		logger.ProviderLogger.LogError("ios_device_setup", fmt.Sprintf("Did not successfully start Appium for device `%v` in 60 seconds", device.UDID))
-		resetLocalDevice(device)
+		failDeviceSetup(device, "ios_device_setup", fmt.Sprintf("Did not successfully start Appium for device `%v` in 60 seconds", device.UDID))
 		return
 	}
 
-	if config.ProviderConfig.UseSeleniumGrid {
+	if config.ProviderConfig().UseSeleniumGrid {
 		go startGridNode(device)
 	}
 
-	device.InstalledApps = GetInstalledAppsIOS(device)
+	device.StartSetupStep("get_installed_apps")
+	device.SetInstalledApps(GetInstalledAppsIOS(device))
 
-	// Mark the device as 'live'
-	device.ProviderState = "live"
+	// Run the post-setup hooks and mark the device as 'live'
+	finishDeviceSetup(device)
 }
 
-// Gets all connected iOS and Android devices to the host
+// Gets all connected devices to the host from the enabled device drivers
 func GetConnectedDevicesCommon() []string {
 	var connectedDevices []string
 
-	var androidDevices []string
-	var iosDevices []string
-
-	if config.ProviderConfig.ProvideAndroid {
-		androidDevices = getConnectedDevicesAndroid()
+	for udid := range discoverConnectedDevices() {
+		connectedDevices = append(connectedDevices, udid)
 	}
 
-	if config.ProviderConfig.ProvideIOS {
-		iosDevices = getConnectedDevicesIOS()
-	}
+	return connectedDevices
+}
+
+// Get the UDIDs of all connected devices together with the driver that discovered them
+func discoverConnectedDevices() map[string]DeviceDriver {
+	connectedDevices := make(map[string]DeviceDriver)
 
-	connectedDevices = append(connectedDevices, iosDevices...)
-	connectedDevices = append(connectedDevices, androidDevices...)
+	for _, driver := range deviceDrivers {
+		if driver.Enabled() {
+			for _, udid := range driver.Discover() {
+				connectedDevices[udid] = driver
+			}
+		}
+	}
 
 	return connectedDevices
 }
@@ -614,8 +601,10 @@ func getConnectedDevicesAndroid() []string {
 
 	for scanner.Scan() {
 		line := scanner.Text()
-		if !strings.Contains(line, "List of devices") && line != "" && strings.Contains(line, "device") && !strings.Contains(line, "emulator") {
-			connectedDevices = append(connectedDevices, strings.Fields(line)[0])
+		// Only devices in `device` state are usable, skip `offline`, `unauthorized` and booting emulators
+		fields := strings.Fields(line)
+		if !strings.Contains(line, "List of devices") && len(fields) >= 2 && fields[1] == "device" {
+			connectedDevices = append(connectedDevices, fields[0])
 		}
 	}
 
@@ -628,26 +617,61 @@ func getConnectedDevicesAndroid() []string {
 	return connectedDevices
 }
 
+// Cancel the setup of a live device on request so it is set up again on the next devices update
+func ResetDeviceSetup(device *models.Device) error {
+	switch state := device.State(); state {
+	case models.DeviceStateResetting:
+		return fmt.Errorf("Device setup is already being reset")
+	case models.DeviceStateLive:
+	default:
+		return fmt.Errorf("Only devices in `live` state can be reset, current state is `%s`", state)
+	}
+
+	device.Mutex.Lock()
+	defer device.Mutex.Unlock()
+	// The state might have changed since the check, the transition fails in that case
+	err := device.TransitionState(models.DeviceStateResetting, "reset requested")
+	if err != nil {
+		return err
+	}
+	device.CtxCancel()
+	setDeviceState(device, models.DeviceStateInit, "reset requested")
+	return nil
+}
+
 func resetLocalDevice(device *models.Device) {
+	resetDevice(device, "")
+}
+
+// Cancel the setup of a device, release its resources and put it back to `init`
+// A non-empty failure is recorded as a failed setup so the next setup is delayed, returns false if the device was not reset
+func resetDevice(device *models.Device, failure string) bool {
 	device.Mutex.Lock()
 	defer device.Mutex.Unlock()
-	if !device.IsResetting && device.ProviderState != "init" {
-		logger.ProviderLogger.LogInfo("provider", fmt.Sprintf("Resetting LocalDevice for device `%v` after error. Cancelling context, setting ProviderState to `init`, Healthy to `false` and updating the DB", device.UDID))
-
-		device.IsResetting = true
-		device.CtxCancel()
-		device.ProviderState = "init"
-		device.IsResetting = false
-		if device.GoIOSTunnel.Address != "" {
-			device.GoIOSTunnel.Close()
-		}
+	// Devices that are not set up or are already being reset fail the transition and are left alone
+	// Several goroutines of the same device can fail at once so this is expected and not logged
+	if device.TransitionState(models.DeviceStateResetting, "device setup failed or stopped") != nil {
+		return false
+	}
+	logger.ProviderLogger.LogInfo("provider", fmt.Sprintf("Resetting LocalDevice for device `%v` after error. Cancelling context, setting ProviderState to `init`, Healthy to `false` and updating the DB", device.UDID))
 
-		// Free any used ports from the map where we keep them
-		delete(providerutil.UsedPorts, device.WDAPort)
-		delete(providerutil.UsedPorts, device.StreamPort)
-		delete(providerutil.UsedPorts, device.AppiumPort)
-		delete(providerutil.UsedPorts, device.WDAStreamPort)
+	device.CtxCancel()
+	teardownDevice(device)
+	// The failure is recorded before the device is back in `init` so the next devices update already respects the backoff
+	if failure != "" {
+		recordSetupFailure(device, failure)
 	}
+	setDeviceState(device, models.DeviceStateInit, "device reset finished")
+	return true
+}
+
+// Release the host resources of a device using its driver
+func teardownDevice(device *models.Device) {
+	driver, err := GetDeviceDriver(device)
+	if err != nil {
+		return
+	}
+	driver.Teardown(device)
 }
 
 // Set a context for a device to enable cancelling running goroutines related to that device when its disconnected
@@ -657,36 +681,12 @@ func setContext(device *models.Device) {
 	device.Context = ctx
 }
 
-func startAppium(device *models.Device) {
-	var capabilities models.AppiumServerCapabilities
-
-	if device.OS == "ios" {
-		capabilities = models.AppiumServerCapabilities{
-			UDID:                  device.UDID,
-			WdaURL:                "http://localhost:" + device.WDAPort,
-			WdaMjpegPort:          device.WDAStreamPort,
-			WdaLocalPort:          device.WDAPort,
-			WdaLaunchTimeout:      "120000",
-			WdaConnectionTimeout:  "240000",
-			ClearSystemFiles:      "false",
-			PreventWdaAttachments: "true",
-			SimpleIsVisibleCheck:  "false",
-			AutomationName:        "XCUITest",
-			PlatformName:          "iOS",
-			DeviceName:            device.Name,
-		}
-	} else if device.OS == "android" {
-		capabilities = models.AppiumServerCapabilities{
-			UDID:           device.UDID,
-			AutomationName: "UiAutomator2",
-			PlatformName:   "Android",
-			DeviceName:     device.Name,
-		}
-	}
-
+func startAppium(device *models.Device, capabilities models.AppiumServerCapabilities) {
+	// The device context is replaced on the next setup, the exit of this process is only a failure of the setup that started it
+	ctx := device.Context
 	capabilitiesJson, _ := json.Marshal(capabilities)
 	cmd := exec.CommandContext(
-		device.Context,
+		ctx,
 		"appium",
 		"-p",
 		device.AppiumPort,
@@ -695,20 +695,21 @@ func startAppium(device *models.Device) {
 		"--log-no-colors",
 		"--relaxed-security",
 		"--default-capabilities", string(capabilitiesJson))
+	stopProcessGracefully(cmd)
 
 	logger.ProviderLogger.LogDebug("device_setup", fmt.Sprintf("Starting Appium on device `%s` with command `%s`", device.UDID, cmd.Args))
 	// Create a pipe to capture the command's output
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		logger.ProviderLogger.LogError("device_setup", fmt.Sprintf("startAppium: Error creating stdoutpipe on `%s` for device `%v` - %v", cmd.Args, device.UDID, err))
-		resetLocalDevice(device)
+		failDeviceSetup(device, "device_setup", fmt.Sprintf("startAppium: Error creating stdoutpipe on `%s` for device `%v` - %v", cmd.Args, device.UDID, err))
 		return
 	}
 
+	processDone := trackDeviceProcess(device)
+	defer processDone()
 	err = cmd.Start()
 	if err != nil {
-		logger.ProviderLogger.LogError("device_setup", fmt.Sprintf("startAppium: Error executing `%s` for device `%v` - %v", cmd.Args, device.UDID, err))
-		resetLocalDevice(device)
+		failDeviceSetup(device, "device_setup", fmt.Sprintf("startAppium: Error executing `%s` for device `%v` - %v", cmd.Args, device.UDID, err))
 		return
 	}
 
@@ -721,24 +722,26 @@ func startAppium(device *models.Device) {
 	}
 
 	err = cmd.Wait()
-	if err != nil {
-		logger.ProviderLogger.LogError("device_setup", fmt.Sprintf("startAppium: Error waiting for `%s` command to finish, it errored out or device `%v` was disconnected - %v", cmd.Args, device.UDID, err))
-		resetLocalDevice(device)
+	// Appium stopped by cancelling the device context is not a failure, the device may already be set up again
+	if err != nil && ctx.Err() == nil {
+		failDeviceSetup(device, "device_setup", fmt.Sprintf("startAppium: Error waiting for `%s` command to finish, it errored out or device `%v` was disconnected - %v", cmd.Args, device.UDID, err))
 	}
 }
 
 func createGridTOML(device *models.Device) error {
-	automationName := ""
-	if device.OS == "ios" {
-		automationName = "XCUITest"
-	} else {
-		automationName = "UiAutomator2"
+	driver, err := GetDeviceDriver(device)
+	if err != nil {
+		return err
 	}
+	automationName := driver.AppiumCapabilities(device).AutomationName
 
-	url := fmt.Sprintf("http://%s:%v/device/%s/appium", config.ProviderConfig.HostAddress, config.ProviderConfig.Port, device.UDID)
+	url := fmt.Sprintf("http://%s:%v/device/%s/appium", config.ProviderConfig().HostAddress, config.ProviderConfig().Port, device.UDID)
 	configs := fmt.Sprintf(`{"appium:deviceName": "%s", "platformName": "%s", "appium:platformVersion": "%s", "appium:automationName": "%s", "appium:udid": "%s"}`, device.Name, device.OS, device.OSVersion, automationName, device.UDID)
 
-	port, _ := providerutil.GetFreePort()
+	port, err := providerutil.AllocatePort(models.PortPurposeGridNode, device.UDID)
+	if err != nil {
+		return fmt.Errorf("Failed to allocate Selenium Grid node port - %s", err)
+	}
 	portInt, _ := strconv.Atoi(port)
 	conf := models.AppiumTomlConfig{
 		Server: models.AppiumTomlServer{
@@ -762,7 +765,7 @@ func createGridTOML(device *models.Device) error {
 		return fmt.Errorf("Failed marshalling TOML Appium config - %s", err)
 	}
 
-	file, err := os.Create(fmt.Sprintf("%s/%s.toml", config.ProviderConfig.ProviderFolder, device.UDID))
+	file, err := os.Create(fmt.Sprintf("%s/%s.toml", config.ProviderConfig().ProviderFolder, device.UDID))
 	if err != nil {
 		return fmt.Errorf("Failed creating TOML Appium config file - %s", err)
 	}
@@ -777,32 +780,39 @@ func createGridTOML(device *models.Device) error {
 }
 
 func startGridNode(device *models.Device) {
+	ctx := device.Context
 	time.Sleep(5 * time.Second)
-	cmd := exec.CommandContext(device.Context,
+	cmd := exec.CommandContext(ctx,
 		"java",
 		"-jar",
-		fmt.Sprintf("%s/selenium.jar", config.ProviderConfig.ProviderFolder),
+		fmt.Sprintf("%s/selenium.jar", config.ProviderConfig().ProviderFolder),
 		"node",
 		"--host",
-		config.ProviderConfig.HostAddress,
+		config.ProviderConfig().HostAddress,
 		"--config",
-		fmt.Sprintf("%s/%s.toml", config.ProviderConfig.ProviderFolder, device.UDID),
+		fmt.Sprintf("%s/%s.toml", config.ProviderConfig().ProviderFolder, device.UDID),
 		"--grid-url",
-		config.ProviderConfig.SeleniumGrid,
+		config.ProviderConfig().SeleniumGrid,
 	)
 
+	stopProcessGracefully(cmd)
+
 	logger.ProviderLogger.LogInfo("device_setup", fmt.Sprintf("Starting Selenium grid node for device `%s` with command `%s`", device.UDID, cmd.Args))
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		logger.ProviderLogger.LogError("device_setup", fmt.Sprintf("Error creating stdoutpipe while starting Selenium Grid node for device `%v` - %v", device.UDID, err))
-		resetLocalDevice(device)
+		failDeviceSetup(device, "device_setup", fmt.Sprintf("Error creating stdoutpipe while starting Selenium Grid node for device `%v` - %v", device.UDID, err))
 		return
 	}
 
+	processDone := trackDeviceProcess(device)
+	defer processDone()
+	// The device may have been reset while waiting to start the node
 	if err := cmd.Start(); err != nil {
-		logger.ProviderLogger.LogError("device_setup", fmt.Sprintf("Could not start Selenium Grid node for device `%v` - %v", device.UDID, err))
-		resetLocalDevice(device)
+		if ctx.Err() != nil {
+			return
+		}
+		failDeviceSetup(device, "device_setup", fmt.Sprintf("Could not start Selenium Grid node for device `%v` - %v", device.UDID, err))
 		return
 	}
 
@@ -813,49 +823,39 @@ func startGridNode(device *models.Device) {
 		device.Logger.LogDebug("grid-node", strings.TrimSpace(line))
 	}
 
-	if err := cmd.Wait(); err != nil {
-		logger.ProviderLogger.LogError("device_setup", fmt.Sprintf("Error waiting for Selenium Grid node command to finish, it errored out or device `%v` was disconnected - %v", device.UDID, err))
-		resetLocalDevice(device)
+	if err := cmd.Wait(); err != nil && ctx.Err() == nil {
+		failDeviceSetup(device, "device_setup", fmt.Sprintf("Error waiting for Selenium Grid node command to finish, it errored out or device `%v` was disconnected - %v", device.UDID, err))
 	}
 }
 
 func UpdateInstalledApps(device *models.Device) {
-	if device.OS == "ios" {
-		device.InstalledApps = GetInstalledAppsIOS(device)
-	} else {
-		device.InstalledApps = GetInstalledAppsAndroid(device)
+	driver, err := GetDeviceDriver(device)
+	if err != nil {
+		device.Logger.LogError("get_installed_apps", err.Error())
+		return
 	}
+	device.SetInstalledApps(driver.InstalledApps(device))
 }
 
 func UninstallApp(device *models.Device, app string) error {
-	if device.OS == "ios" {
-		err := uninstallAppIOS(device, app)
-		if err != nil {
-			return err
-		}
-	} else {
-		err := uninstallAppAndroid(device, app)
-		if err != nil {
-			return err
-		}
+	driver, err := GetDeviceDriver(device)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return driver.UninstallApp(device, app)
 }
 
 func InstallApp(device *models.Device, app string) error {
-	if device.OS == "ios" {
-		err := installAppDefaultPath(device, app)
-		if err != nil {
-			device.Logger.LogError("install_app_ios", fmt.Sprintf("Failed installing app on device `%s` - %s", device.UDID, err))
-			return err
-		}
-	} else {
-		err := installAppAndroid(device, app)
-		if err != nil {
-			device.Logger.LogError("install_app_android", fmt.Sprintf("Failed installing app on device `%s` - %s", device.UDID, err))
-			return err
-		}
+	driver, err := GetDeviceDriver(device)
+	if err != nil {
+		return err
+	}
+
+	err = driver.InstallApp(device, app)
+	if err != nil {
+		device.Logger.LogError("install_app", fmt.Sprintf("Failed installing app on device `%s` - %s", device.UDID, err))
+		return err
 	}
 
 	return nil
@@ -16,7 +16,7 @@ func getDBProviderDevices() map[string]*models.Device {
 
 	var deviceDataMap = make(map[string]*models.Device)
 
-	filter := bson.M{"provider": config.ProviderConfig.Nickname}
+	filter := bson.M{"provider": config.ProviderConfig().Nickname}
 
 	collection := db.MongoClient().Database("gads").Collection("new_devices")
 
@@ -43,3 +43,18 @@ func getDBProviderDevices() map[string]*models.Device {
 
 	return deviceDataMap
 }
+
+// Get a single device from the DB regardless of the provider it is assigned to
+func getDBDevice(udid string) (*models.Device, error) {
+	ctx, cancel := context.WithCancel(db.MongoCtx())
+	defer cancel()
+
+	collection := db.MongoClient().Database("gads").Collection("new_devices")
+
+	var device models.Device
+	err := collection.FindOne(ctx, bson.M{"udid": udid}).Decode(&device)
+	if err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
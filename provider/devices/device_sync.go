@@ -0,0 +1,101 @@
+package devices
+
+import (
+	"fmt"
+	"time"
+
+	"GADS/common/models"
+	"GADS/provider/logger"
+
+	"github.com/Masterminds/semver"
+)
+
+const devicesSyncInterval = 5 * time.Second
+
+var lastDevicesSync time.Time
+
+// Requests for an immediate sync, e.g. when the hub notifies the provider about a changed device
+var devicesSyncRequests = make(chan struct{}, 1)
+
+// Request syncing the provider devices with the DB on the next devices update instead of waiting for the interval
+func RequestDevicesSync() {
+	select {
+	case devicesSyncRequests <- struct{}{}:
+	default:
+	}
+}
+
+// Apply the device records of the provider in the DB to the provider devices without a restart
+// New devices are added and set up on the next devices update, removed or reassigned devices are stopped
+// and changes to the device configuration like usage are applied immediately
+// It runs in the devices update loop which is the only one adding and removing devices in the registry
+func syncDBDevices() {
+	select {
+	case <-devicesSyncRequests:
+	default:
+		if time.Since(lastDevicesSync) < devicesSyncInterval {
+			return
+		}
+	}
+	lastDevicesSync = time.Now()
+
+	dbDevices := getDBProviderDevices()
+	// Nil means the devices could not be read from the DB, don't remove anything in that case
+	if dbDevices == nil {
+		return
+	}
+
+	for _, device := range Registry.All() {
+		if _, ok := dbDevices[device.UDID]; !ok {
+			logger.ProviderLogger.LogInfo("device_sync", fmt.Sprintf("Device `%s` was removed from the provider in the DB, stopping it", device.UDID))
+			stopDevice(device)
+			Registry.Remove(device.UDID)
+		}
+	}
+
+	for udid, dbDevice := range dbDevices {
+		device, ok := Registry.Get(udid)
+		if !ok {
+			logger.ProviderLogger.LogInfo("device_sync", fmt.Sprintf("Device `%s` was added to the provider in the DB", udid))
+			prepareDevice(dbDevice)
+			Registry.Add(dbDevice)
+			continue
+		}
+		applyDBDeviceChanges(device, dbDevice)
+	}
+}
+
+// Update the DB values of a provider device
+// Devices that need to be set up again for the new values are stopped so the next devices update sets them up
+func applyDBDeviceChanges(device *models.Device, dbDevice *models.Device) {
+	// Only the devices update loop changes the DB values so they can be read here without the lock
+	if device.Usage != dbDevice.Usage {
+		logger.ProviderLogger.LogInfo("device_sync", fmt.Sprintf("Device `%s` usage changed from `%s` to `%s`", device.UDID, device.Usage, dbDevice.Usage))
+		if dbDevice.Usage == "disabled" || dbDevice.Usage == "pending" {
+			stopDevice(device)
+		}
+	}
+
+	// Semantic version is used around the provider and the setup depends on the OS and its version
+	deviceSemVer := device.SemVer
+	restartSetup := device.OS != dbDevice.OS || device.OSVersion != dbDevice.OSVersion
+	if restartSetup {
+		logger.ProviderLogger.LogInfo("device_sync", fmt.Sprintf("Device `%s` OS or OS version changed, setting it up again", device.UDID))
+		stopDevice(device)
+		var err error
+		deviceSemVer, err = semver.NewVersion(dbDevice.OSVersion)
+		if err != nil {
+			logger.ProviderLogger.LogError("device_sync", fmt.Sprintf("Failed to get semver for device `%s` - %s", device.UDID, err))
+		}
+	}
+
+	device.ApplyDBConfig(dbDevice, deviceSemVer)
+}
+
+// Stop a device that is being set up or is live - cancel its context, free its ports and release host resources
+func stopDevice(device *models.Device) {
+	if device.CtxCancel != nil {
+		resetLocalDevice(device)
+	}
+	device.SetConnected(false)
+}
@@ -0,0 +1,76 @@
+package devices
+
+import (
+	"context"
+	"fmt"
+
+	"GADS/common/models"
+)
+
+// Platform specific device handling used by the provider
+// Each driver handles the devices with the respective OS value in the DB
+type DeviceDriver interface {
+	// The device OS this driver handles, e.g. `android`
+	Name() string
+	// If the provider is configured to provide devices with this driver
+	Enabled() bool
+	// UDIDs of the devices currently connected to the host
+	Discover() []string
+	// Prepare a connected device for usage, calls finishDeviceSetup when done or resets the device on failure
+	// It is blocking and runs in its own goroutine for each device
+	Setup(device *models.Device)
+	// Release host resources related to the device after it was disconnected or reset
+	Teardown(device *models.Device)
+	// Stop what the provider started on a live device before the provider shuts down, the device stays connected
+	Stop(device *models.Device)
+	// Install an app from the provider folder on the device
+	InstallApp(device *models.Device, appName string) error
+	UninstallApp(device *models.Device, app string) error
+	InstalledApps(device *models.Device) []string
+	// Start or bring an installed app to the foreground by package name or bundle ID
+	LaunchApp(device *models.Device, app string) error
+	TerminateApp(device *models.Device, app string) error
+	// Open a URL or deep link with the app that handles it
+	OpenURL(device *models.Device, url string) error
+	// Package name or bundle ID of the app in the foreground
+	ForegroundApp(device *models.Device) (string, error)
+	// Take a screenshot of the device screen, returns the raw image bytes
+	Screenshot(device *models.Device) ([]byte, error)
+	// URL of the local source the device screen stream is read from
+	StreamSource(device *models.Device) string
+	// Update the hardware model and screen dimensions of the device if they are missing
+	HardwareInfo(device *models.Device) error
+	// Detect the name, OS version, type and screen dimensions of a connected device that is not registered in the DB yet
+	Identify(device *models.Device) error
+	// Default capabilities for the Appium server or session of the device
+	AppiumCapabilities(device *models.Device) models.AppiumServerCapabilities
+	// Collect the battery, temperature, storage and memory of a live device, values that are not available are left empty
+	Telemetry(device *models.Device) (models.DeviceTelemetry, error)
+	// Run a single action of a cleanup policy on the device, actions that do not apply to the platform return an error
+	Cleanup(ctx context.Context, device *models.Device, action string, policy models.CleanupPolicy) error
+	// Restart the device, returns once the reboot was requested
+	Reboot(device *models.Device) error
+	// List a directory in the device storage, paths are relative to the shared storage on Android
+	// On iOS they are relative to the media directory, or the container of `app` if it is provided
+	ListFiles(device *models.Device, app string, dir string) ([]models.DeviceFile, error)
+	// Copy a local file to the device storage, a file that already exists is replaced
+	PushFile(device *models.Device, app string, localPath string, devicePath string) error
+	// Copy a file from the device storage to a local file, fails for files bigger than MaxDeviceFileSize
+	PullFile(device *models.Device, app string, devicePath string, localPath string) error
+}
+
+var deviceDrivers = []DeviceDriver{
+	androidDriver{},
+	iosDriver{},
+	fakeDriver{},
+}
+
+// Get the driver that handles the device based on its OS
+func GetDeviceDriver(device *models.Device) (DeviceDriver, error) {
+	for _, driver := range deviceDrivers {
+		if driver.Name() == device.OS {
+			return driver, nil
+		}
+	}
+	return nil, fmt.Errorf("No device driver for OS `%s` of device `%s`", device.OS, device.UDID)
+}
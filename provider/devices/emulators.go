@@ -0,0 +1,179 @@
+package devices
+
+import (
+	"bufio"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"GADS/common/models"
+	"GADS/provider/config"
+	"GADS/provider/logger"
+)
+
+type EmulatorStatus struct {
+	AVD       string `json:"avd"`
+	Port      int    `json:"port"`
+	UDID      string `json:"udid"`
+	AutoStart bool   `json:"auto_start"`
+	Running   bool   `json:"running"`    // if the emulator process was started by the provider and is still running
+	Connected bool   `json:"connected"`  // if the emulator is available through adb
+	StartedAt int64  `json:"started_at"` // when the provider started the emulator process
+}
+
+type emulatorProcess struct {
+	cmd       *exec.Cmd
+	startedAt int64
+}
+
+var emulatorsMu sync.Mutex
+var runningEmulators = make(map[string]*emulatorProcess)
+
+func getEmulatorConfig(avd string) (models.ProviderEmulator, error) {
+	for _, emulator := range config.ProviderConfig().Emulators {
+		if emulator.AVD == avd {
+			return emulator, nil
+		}
+	}
+	return models.ProviderEmulator{}, fmt.Errorf("Emulator `%s` is not configured for provider `%s`", avd, config.ProviderConfig().Nickname)
+}
+
+// Start all configured emulators that should run together with the provider
+func startAutoStartEmulators() {
+	for _, emulator := range config.ProviderConfig().Emulators {
+		if !emulator.AutoStart {
+			continue
+		}
+		err := StartEmulator(emulator.AVD)
+		if err != nil {
+			logger.ProviderLogger.LogError("emulators", fmt.Sprintf("Failed to auto start emulator `%s` - %s", emulator.AVD, err))
+		}
+	}
+}
+
+// Start a configured emulator headless with software rendering
+// Its state is never saved on exit so each start is a cold boot from the same snapshot
+// The emulator is then discovered through adb like any other Android device
+func StartEmulator(avd string) error {
+	emulator, err := getEmulatorConfig(avd)
+	if err != nil {
+		return err
+	}
+
+	emulatorsMu.Lock()
+	defer emulatorsMu.Unlock()
+
+	if _, ok := runningEmulators[avd]; ok {
+		return fmt.Errorf("Emulator `%s` is already running", avd)
+	}
+
+	args := []string{
+		"-avd", emulator.AVD,
+		"-port", strconv.Itoa(emulator.Port),
+		"-no-window",
+		"-no-audio",
+		"-no-boot-anim",
+		"-gpu", "swiftshader_indirect",
+		"-no-snapshot-save",
+	}
+	if emulator.Snapshot != "" {
+		args = append(args, "-snapshot", emulator.Snapshot)
+	}
+	cmd := exec.Command("emulator", args...)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("Failed creating stdout pipe for `%s` - %s", cmd.Args, err)
+	}
+	cmd.Stderr = cmd.Stdout
+
+	logger.ProviderLogger.LogInfo("emulators", fmt.Sprintf("Starting emulator `%s` with command `%s`", avd, cmd.Args))
+	err = cmd.Start()
+	if err != nil {
+		return fmt.Errorf("Failed executing `%s` - %s", cmd.Args, err)
+	}
+
+	process := &emulatorProcess{cmd: cmd, startedAt: time.Now().UnixMilli()}
+	runningEmulators[avd] = process
+
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			logger.ProviderLogger.LogDebug("emulators", fmt.Sprintf("%s: %s", avd, scanner.Text()))
+		}
+
+		err := cmd.Wait()
+		if err != nil {
+			logger.ProviderLogger.LogWarn("emulators", fmt.Sprintf("Emulator `%s` exited - %s", avd, err))
+		} else {
+			logger.ProviderLogger.LogInfo("emulators", fmt.Sprintf("Emulator `%s` exited", avd))
+		}
+
+		emulatorsMu.Lock()
+		if runningEmulators[avd] == process {
+			delete(runningEmulators, avd)
+		}
+		emulatorsMu.Unlock()
+	}()
+
+	return nil
+}
+
+// Stop a configured emulator through its console with adb, killing the process if it does not respond
+// Emulators that were not started by the provider can also be stopped as long as they run on the configured port
+func StopEmulator(avd string) error {
+	emulator, err := getEmulatorConfig(avd)
+	if err != nil {
+		return err
+	}
+
+	emulatorsMu.Lock()
+	process, running := runningEmulators[avd]
+	emulatorsMu.Unlock()
+
+	cmd := exec.Command("adb", "-s", emulator.UDID(), "emu", "kill")
+	err = cmd.Run()
+	if err == nil {
+		logger.ProviderLogger.LogInfo("emulators", fmt.Sprintf("Stopped emulator `%s`", avd))
+		return nil
+	}
+
+	if !running {
+		return fmt.Errorf("Failed executing `%s` - %s", cmd.Args, err)
+	}
+
+	logger.ProviderLogger.LogWarn("emulators", fmt.Sprintf("Failed stopping emulator `%s` with adb, killing the process - %s", avd, err))
+	err = process.cmd.Process.Kill()
+	if err != nil {
+		return fmt.Errorf("Failed killing emulator `%s` process - %s", avd, err)
+	}
+	return nil
+}
+
+// Get the state of all configured emulators
+func GetEmulators() []EmulatorStatus {
+	emulatorsMu.Lock()
+	defer emulatorsMu.Unlock()
+
+	emulators := []EmulatorStatus{}
+	for _, emulator := range config.ProviderConfig().Emulators {
+		status := EmulatorStatus{
+			AVD:       emulator.AVD,
+			Port:      emulator.Port,
+			UDID:      emulator.UDID(),
+			AutoStart: emulator.AutoStart,
+		}
+		if process, ok := runningEmulators[emulator.AVD]; ok {
+			status.Running = true
+			status.StartedAt = process.startedAt
+		}
+		if device, ok := Registry.Get(status.UDID); ok {
+			status.Connected = device.IsConnected()
+		}
+		emulators = append(emulators, status)
+	}
+
+	return emulators
+}
@@ -0,0 +1,589 @@
+package devices
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"GADS/common/db"
+	"GADS/common/models"
+	"GADS/provider/config"
+	"GADS/provider/logger"
+	"GADS/provider/providerutil"
+
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+	"github.com/google/uuid"
+)
+
+const (
+	fakeDeviceFrameInterval = 100 * time.Millisecond
+	fakeDeviceScreenWidth   = "390"
+	fakeDeviceScreenHeight  = "844"
+)
+
+// State of a simulated device, it only lives in the provider memory and starts over on provider restart
+type fakeDeviceState struct {
+	mu            sync.Mutex
+	installedApps []string
+	foregroundApp string
+	sessions      map[string]bool
+	files         map[string]map[string]fakeDeviceFile // app (empty for the shared storage) to file path, directories are implied by the paths
+}
+
+type fakeDeviceFile struct {
+	data     []byte
+	modified int64
+}
+
+var fakeDevicesMu sync.Mutex
+var fakeDevices = make(map[string]*fakeDeviceState)
+
+func getFakeDeviceState(udid string) *fakeDeviceState {
+	fakeDevicesMu.Lock()
+	defer fakeDevicesMu.Unlock()
+
+	state, ok := fakeDevices[udid]
+	if !ok {
+		state = &fakeDeviceState{
+			installedApps: []string{"com.gads.fake.settings"},
+			sessions:      make(map[string]bool),
+			files:         make(map[string]map[string]fakeDeviceFile),
+		}
+		fakeDevices[udid] = state
+	}
+	return state
+}
+
+// Add the simulated devices of the provider to the DB if they are not already there
+// Existing devices are not updated so changes made to them through the hub are kept
+func createFakeDevices(count int) error {
+	dbDevices := getDBProviderDevices()
+
+	for i := 1; i <= count; i++ {
+		udid := fmt.Sprintf("%s-fake-%d", config.ProviderConfig().Nickname, i)
+		if _, ok := dbDevices[udid]; ok {
+			continue
+		}
+
+		device := &models.Device{
+			UDID:         udid,
+			OS:           "fake",
+			Name:         fmt.Sprintf("Fake device %d", i),
+			OSVersion:    "1.0.0",
+			Provider:     config.ProviderConfig().Nickname,
+			Usage:        "enabled",
+			ScreenWidth:  fakeDeviceScreenWidth,
+			ScreenHeight: fakeDeviceScreenHeight,
+			DeviceType:   "emulator",
+		}
+		err := db.UpsertDeviceDB(device)
+		if err != nil {
+			return fmt.Errorf("Failed to add fake device `%s` to DB - %s", udid, err)
+		}
+		logger.ProviderLogger.LogInfo("provider", fmt.Sprintf("Added fake device `%s` to DB", udid))
+	}
+
+	return nil
+}
+
+// Simulated devices used to run the provider without any real devices attached, e.g. on CI
+// Each device serves a generated JPEG stream compatible with GADS-stream and a minimal Appium-like endpoint
+type fakeDriver struct{}
+
+func (fakeDriver) Name() string {
+	return "fake"
+}
+
+func (fakeDriver) Enabled() bool {
+	return config.ProviderConfig().FakeDevices > 0
+}
+
+// Fake devices are always connected
+func (fakeDriver) Discover() []string {
+	var connectedDevices []string
+	for _, device := range Registry.All() {
+		if device.OS == "fake" {
+			connectedDevices = append(connectedDevices, device.UDID)
+		}
+	}
+	return connectedDevices
+}
+
+func (d fakeDriver) Setup(device *models.Device) {
+	logger.ProviderLogger.LogInfo("fake_device_setup", fmt.Sprintf("Running setup for device `%v`", device.UDID))
+
+	device.StartSetupStep("update_hardware_info")
+	d.HardwareInfo(device)
+
+	device.StartSetupStep("allocate_ports")
+	streamPort, err := providerutil.AllocatePort(models.PortPurposeStream, device.UDID)
+	if err != nil {
+		failDeviceSetup(device, "fake_device_setup", fmt.Sprintf("Could not allocate free host port for the stream of device `%v` - %v", device.UDID, err))
+		return
+	}
+	device.StreamPort = streamPort
+
+	appiumPort, err := providerutil.AllocatePort(models.PortPurposeAppium, device.UDID)
+	if err != nil {
+		failDeviceSetup(device, "fake_device_setup", fmt.Sprintf("Could not allocate free host port for Appium for device `%v` - %v", device.UDID, err))
+		return
+	}
+	device.AppiumPort = appiumPort
+
+	device.StartSetupStep("start_stream")
+	err = startFakeServer(device, device.StreamPort, fakeStreamHandler(device))
+	if err != nil {
+		failDeviceSetup(device, "fake_device_setup", fmt.Sprintf("Could not start stream server for device `%v` - %v", device.UDID, err))
+		return
+	}
+
+	device.StartSetupStep("start_appium")
+	err = startFakeServer(device, device.AppiumPort, fakeAppiumHandler(device))
+	if err != nil {
+		failDeviceSetup(device, "fake_device_setup", fmt.Sprintf("Could not start Appium server for device `%v` - %v", device.UDID, err))
+		return
+	}
+
+	device.StartSetupStep("get_installed_apps")
+	device.SetInstalledApps(d.InstalledApps(device))
+	finishDeviceSetup(device)
+}
+
+// The fake servers are stopped when the device context is cancelled
+func (fakeDriver) Teardown(device *models.Device) {}
+
+func (fakeDriver) Stop(device *models.Device) {}
+
+func (fakeDriver) InstallApp(device *models.Device, appName string) error {
+	state := getFakeDeviceState(device.UDID)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	app := filepath.Base(appName)
+	app = app[:len(app)-len(filepath.Ext(app))]
+	if !slices.Contains(state.installedApps, app) {
+		state.installedApps = append(state.installedApps, app)
+	}
+	return nil
+}
+
+func (fakeDriver) UninstallApp(device *models.Device, app string) error {
+	state := getFakeDeviceState(device.UDID)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	index := slices.Index(state.installedApps, app)
+	if index == -1 {
+		return fmt.Errorf("App `%s` is not installed on device `%s`", app, device.UDID)
+	}
+	state.installedApps = slices.Delete(state.installedApps, index, index+1)
+	return nil
+}
+
+func (fakeDriver) InstalledApps(device *models.Device) []string {
+	state := getFakeDeviceState(device.UDID)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	return slices.Clone(state.installedApps)
+}
+
+func (fakeDriver) LaunchApp(device *models.Device, app string) error {
+	state := getFakeDeviceState(device.UDID)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if !slices.Contains(state.installedApps, app) {
+		return fmt.Errorf("App `%s` is not installed on device `%s`", app, device.UDID)
+	}
+	state.foregroundApp = app
+	return nil
+}
+
+func (fakeDriver) TerminateApp(device *models.Device, app string) error {
+	state := getFakeDeviceState(device.UDID)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if state.foregroundApp == app {
+		state.foregroundApp = ""
+	}
+	return nil
+}
+
+// Fake devices accept any URL without opening anything
+func (fakeDriver) OpenURL(device *models.Device, url string) error {
+	return nil
+}
+
+func (fakeDriver) ForegroundApp(device *models.Device) (string, error) {
+	state := getFakeDeviceState(device.UDID)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	return state.foregroundApp, nil
+}
+
+func (fakeDriver) Screenshot(device *models.Device) ([]byte, error) {
+	var buffer bytes.Buffer
+	err := png.Encode(&buffer, fakeDeviceFrame(device, int(time.Now().UnixMilli()/fakeDeviceFrameInterval.Milliseconds())))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode fake screenshot - %s", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// The fake stream uses the same websocket format as GADS-stream so it can be consumed through the Android stream endpoints
+func (fakeDriver) StreamSource(device *models.Device) string {
+	return "ws://localhost:" + device.StreamPort
+}
+
+func (fakeDriver) HardwareInfo(device *models.Device) error {
+	device.HardwareModel = "GADS Fake Device"
+	if device.ScreenWidth == "" || device.ScreenHeight == "" {
+		device.ScreenWidth = fakeDeviceScreenWidth
+		device.ScreenHeight = fakeDeviceScreenHeight
+	}
+	return nil
+}
+
+// Fake devices are always created in the DB on provider start so they are never discovered unregistered
+func (fakeDriver) Identify(device *models.Device) error {
+	return fmt.Errorf("Fake devices cannot be registered automatically")
+}
+
+func (fakeDriver) AppiumCapabilities(device *models.Device) models.AppiumServerCapabilities {
+	return models.AppiumServerCapabilities{
+		UDID:           device.UDID,
+		AutomationName: "Fake",
+		PlatformName:   "Fake",
+		DeviceName:     device.Name,
+	}
+}
+
+// Fake devices report a healthy phone on a charger
+func (fakeDriver) Telemetry(device *models.Device) (models.DeviceTelemetry, error) {
+	return models.DeviceTelemetry{
+		BatteryLevel: 100,
+		Charging:     true,
+		Temperature:  30,
+		FreeStorage:  32 * 1024 * 1024 * 1024,
+		TotalStorage: 64 * 1024 * 1024 * 1024,
+		FreeMemory:   2 * 1024 * 1024 * 1024,
+		TotalMemory:  4 * 1024 * 1024 * 1024,
+	}, nil
+}
+
+// Cleanup actions are applied to the fake device state, the orientation of a fake device never changes
+func (fakeDriver) Cleanup(ctx context.Context, device *models.Device, action string, policy models.CleanupPolicy) error {
+	state := getFakeDeviceState(device.UDID)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	switch action {
+	case models.CleanupActionUninstallApps:
+		state.installedApps = slices.DeleteFunc(state.installedApps, func(app string) bool {
+			if slices.Contains(policy.AppAllowlist, app) {
+				return false
+			}
+			delete(state.files, app)
+			return true
+		})
+		if !slices.Contains(state.installedApps, state.foregroundApp) {
+			state.foregroundApp = ""
+		}
+		device.SetInstalledApps(slices.Clone(state.installedApps))
+	case models.CleanupActionClearAppData:
+		for _, app := range state.installedApps {
+			delete(state.files, app)
+		}
+	case models.CleanupActionPressHome, models.CleanupActionKillForegroundApp:
+		state.foregroundApp = ""
+	}
+	return nil
+}
+
+// Fake devices stay connected, rebooting only sets them up again
+func (fakeDriver) Reboot(device *models.Device) error {
+	return nil
+}
+
+// Files of the shared storage or an installed app, the caller should hold the state lock
+func (state *fakeDeviceState) appFiles(app string) (map[string]fakeDeviceFile, error) {
+	if app != "" && !slices.Contains(state.installedApps, app) {
+		return nil, fmt.Errorf("App `%s` is not installed", app)
+	}
+	if state.files[app] == nil {
+		state.files[app] = make(map[string]fakeDeviceFile)
+	}
+	return state.files[app], nil
+}
+
+func (fakeDriver) ListFiles(device *models.Device, app string, dir string) ([]models.DeviceFile, error) {
+	state := getFakeDeviceState(device.UDID)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	files, err := state.appFiles(app)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := files[dir]; ok {
+		return nil, fmt.Errorf("`%s` is not a directory", dir)
+	}
+
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	children := make(map[string]models.DeviceFile)
+	for filePath, file := range files {
+		relativePath, ok := strings.CutPrefix(filePath, prefix)
+		if !ok {
+			continue
+		}
+		if name, _, nested := strings.Cut(relativePath, "/"); nested {
+			children[name] = models.DeviceFile{Name: name, Path: prefix + name, IsDir: true}
+		} else {
+			children[name] = models.DeviceFile{Name: name, Path: filePath, Size: int64(len(file.data)), Modified: file.modified}
+		}
+	}
+	if len(children) == 0 && dir != "/" {
+		return nil, ErrDeviceFileNotFound
+	}
+
+	result := []models.DeviceFile{}
+	for _, file := range children {
+		result = append(result, file)
+	}
+	slices.SortFunc(result, func(a, b models.DeviceFile) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return result, nil
+}
+
+func (fakeDriver) PushFile(device *models.Device, app string, localPath string, devicePath string) error {
+	err := checkLocalFileSize(localPath)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		return fmt.Errorf("Failed to read local file `%s` - %s", localPath, err)
+	}
+
+	state := getFakeDeviceState(device.UDID)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	files, err := state.appFiles(app)
+	if err != nil {
+		return err
+	}
+	for filePath := range files {
+		if strings.HasPrefix(filePath, devicePath+"/") {
+			return ErrDeviceFileIsDir
+		}
+	}
+	files[devicePath] = fakeDeviceFile{data: data, modified: time.Now().UnixMilli()}
+	return nil
+}
+
+func (fakeDriver) PullFile(device *models.Device, app string, devicePath string, localPath string) error {
+	state := getFakeDeviceState(device.UDID)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	files, err := state.appFiles(app)
+	if err != nil {
+		return err
+	}
+	file, ok := files[devicePath]
+	if !ok {
+		return ErrDeviceFileNotFound
+	}
+	return os.WriteFile(localPath, file.data, 0644)
+}
+
+// Serve the handler on localhost on the provided port until the device context is cancelled
+func startFakeServer(device *models.Device, port string, handler http.Handler) error {
+	listener, err := net.Listen("tcp", "localhost:"+port)
+	if err != nil {
+		return fmt.Errorf("Failed to listen on port %s - %s", port, err)
+	}
+
+	server := &http.Server{Handler: handler}
+	go server.Serve(listener)
+	go func() {
+		<-device.Context.Done()
+		server.Close()
+	}()
+
+	return nil
+}
+
+// Generate a frame of the fake device screen, a bar moving down the screen on a background that slowly changes color
+func fakeDeviceFrame(device *models.Device, frameNumber int) image.Image {
+	width, err := strconv.Atoi(device.ScreenWidth)
+	if err != nil || width <= 0 {
+		width, _ = strconv.Atoi(fakeDeviceScreenWidth)
+	}
+	height, err := strconv.Atoi(device.ScreenHeight)
+	if err != nil || height <= 0 {
+		height, _ = strconv.Atoi(fakeDeviceScreenHeight)
+	}
+
+	frame := image.NewRGBA(image.Rect(0, 0, width, height))
+	background := color.RGBA{R: uint8(frameNumber % 256), G: 64, B: uint8(255 - frameNumber%256), A: 255}
+	draw.Draw(frame, frame.Bounds(), &image.Uniform{C: background}, image.Point{}, draw.Src)
+
+	barHeight := max(height/20, 1)
+	barTop := (frameNumber * barHeight / 2) % height
+	draw.Draw(frame, image.Rect(0, barTop, width, barTop+barHeight), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+
+	return frame
+}
+
+// Websocket server that sends a JPEG frame of the fake device screen on each interval
+func fakeStreamHandler(device *models.Device) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, _, err := ws.UpgradeHTTP(r, w)
+		if err != nil {
+			device.Logger.LogError("fake_stream", fmt.Sprintf("Failed upgrading fake stream connection - %s", err))
+			return
+		}
+		defer conn.Close()
+
+		ticker := time.NewTicker(fakeDeviceFrameInterval)
+		defer ticker.Stop()
+
+		for frameNumber := 0; ; frameNumber++ {
+			var buffer bytes.Buffer
+			err = jpeg.Encode(&buffer, fakeDeviceFrame(device, frameNumber), &jpeg.Options{Quality: 70})
+			if err != nil {
+				device.Logger.LogError("fake_stream", fmt.Sprintf("Failed encoding fake stream frame - %s", err))
+				return
+			}
+
+			err = wsutil.WriteServerBinary(conn, buffer.Bytes())
+			if err != nil {
+				return
+			}
+
+			select {
+			case <-device.Context.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+func writeFakeAppiumResponse(w http.ResponseWriter, statusCode int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]interface{}{"value": value})
+}
+
+func writeFakeAppiumError(w http.ResponseWriter, statusCode int, errorCode, message string) {
+	writeFakeAppiumResponse(w, statusCode, map[string]string{
+		"error":      errorCode,
+		"message":    message,
+		"stacktrace": "",
+	})
+}
+
+// Minimal W3C WebDriver endpoint that accepts sessions and answers every command of an existing session successfully
+func fakeAppiumHandler(device *models.Device) http.Handler {
+	state := getFakeDeviceState(device.UDID)
+	capabilities := map[string]interface{}{
+		"platformName":          "Fake",
+		"appium:automationName": "Fake",
+		"appium:udid":           device.UDID,
+		"appium:deviceName":     device.Name,
+	}
+
+	sessionExists := func(sessionID string) bool {
+		state.mu.Lock()
+		defer state.mu.Unlock()
+		return state.sessions[sessionID]
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		writeFakeAppiumResponse(w, http.StatusOK, map[string]interface{}{
+			"ready":   true,
+			"message": "GADS fake Appium server is ready",
+		})
+	})
+	mux.HandleFunc("GET /sessions", func(w http.ResponseWriter, r *http.Request) {
+		state.mu.Lock()
+		sessions := []map[string]interface{}{}
+		for sessionID := range state.sessions {
+			sessions = append(sessions, map[string]interface{}{"id": sessionID, "capabilities": capabilities})
+		}
+		state.mu.Unlock()
+		writeFakeAppiumResponse(w, http.StatusOK, sessions)
+	})
+	mux.HandleFunc("POST /session", func(w http.ResponseWriter, r *http.Request) {
+		sessionID := uuid.New().String()
+		state.mu.Lock()
+		state.sessions[sessionID] = true
+		state.mu.Unlock()
+		writeFakeAppiumResponse(w, http.StatusOK, map[string]interface{}{
+			"sessionId":    sessionID,
+			"capabilities": capabilities,
+		})
+	})
+	mux.HandleFunc("DELETE /session/{id}", func(w http.ResponseWriter, r *http.Request) {
+		state.mu.Lock()
+		delete(state.sessions, r.PathValue("id"))
+		state.mu.Unlock()
+		writeFakeAppiumResponse(w, http.StatusOK, nil)
+	})
+	mux.HandleFunc("GET /session/{id}/screenshot", func(w http.ResponseWriter, r *http.Request) {
+		if !sessionExists(r.PathValue("id")) {
+			writeFakeAppiumError(w, http.StatusNotFound, "invalid session id", "The session does not exist")
+			return
+		}
+		screenshot, err := fakeDriver{}.Screenshot(device)
+		if err != nil {
+			writeFakeAppiumError(w, http.StatusInternalServerError, "unknown error", err.Error())
+			return
+		}
+		writeFakeAppiumResponse(w, http.StatusOK, base64.StdEncoding.EncodeToString(screenshot))
+	})
+	mux.HandleFunc("GET /session/{id}/source", func(w http.ResponseWriter, r *http.Request) {
+		if !sessionExists(r.PathValue("id")) {
+			writeFakeAppiumError(w, http.StatusNotFound, "invalid session id", "The session does not exist")
+			return
+		}
+		writeFakeAppiumResponse(w, http.StatusOK, fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?><hierarchy width="%s" height="%s"/>`, device.ScreenWidth, device.ScreenHeight))
+	})
+	mux.HandleFunc("/session/{id}/{command...}", func(w http.ResponseWriter, r *http.Request) {
+		if !sessionExists(r.PathValue("id")) {
+			writeFakeAppiumError(w, http.StatusNotFound, "invalid session id", "The session does not exist")
+			return
+		}
+		writeFakeAppiumResponse(w, http.StatusOK, nil)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeFakeAppiumError(w, http.StatusNotFound, "unknown command", fmt.Sprintf("The command `%s %s` is not supported by the fake driver", r.Method, r.URL.Path))
+	})
+
+	return mux
+}
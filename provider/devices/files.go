@@ -0,0 +1,153 @@
+package devices
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"GADS/common/models"
+)
+
+const (
+	// Largest file that can be pushed to or pulled from a device
+	MaxDeviceFileSize int64 = 512 << 20
+	// Limit for a single file transfer or directory listing
+	fileTransferTimeout = 10 * time.Minute
+	// Root of the shared storage on Android devices that paths are relative to
+	androidStorageRoot = "/sdcard"
+)
+
+var (
+	ErrDeviceFileNotFound   = errors.New("file or directory does not exist")
+	ErrDeviceFileTooLarge   = fmt.Errorf("file is bigger than the %d MB limit", MaxDeviceFileSize>>20)
+	ErrDeviceFileIsDir      = errors.New("path is a directory")
+	ErrAppFilesNotSupported = errors.New("app containers are not accessible on this device")
+)
+
+// Clean a device file path relative to the storage root so it can't point outside of it
+func CleanDeviceFilePath(filePath string) (string, error) {
+	if strings.ContainsAny(filePath, "\x00\n\r") {
+		return "", fmt.Errorf("Invalid path `%s`", filePath)
+	}
+	return path.Clean("/" + filePath), nil
+}
+
+// Quote an argument for the device shell, the arguments of `adb shell` are joined and run by it
+func quoteShellArg(arg string) string {
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// Output format of `stat` for a file on an Android device, the name is last as it can contain the separator
+const androidStatFormat = "%F|%s|%Y|%n"
+
+func parseAndroidStat(line string, dir string) (models.DeviceFile, bool) {
+	fields := strings.SplitN(line, "|", 4)
+	if len(fields) != 4 {
+		return models.DeviceFile{}, false
+	}
+	size, _ := strconv.ParseInt(fields[1], 10, 64)
+	modified, _ := strconv.ParseInt(fields[2], 10, 64)
+	name := path.Base(fields[3])
+	return models.DeviceFile{
+		Name:     name,
+		Path:     path.Join(dir, name),
+		IsDir:    fields[0] == "directory",
+		Size:     size,
+		Modified: modified * 1000,
+	}, true
+}
+
+func statAndroidFile(ctx context.Context, device *models.Device, filePath string) (models.DeviceFile, error) {
+	output, err := exec.CommandContext(ctx, "adb", "-s", device.UDID, "shell", "stat", "-L", "-c", quoteShellArg(androidStatFormat), quoteShellArg(androidStorageRoot+filePath)).CombinedOutput()
+	if err != nil {
+		if strings.Contains(string(output), "No such file") {
+			return models.DeviceFile{}, ErrDeviceFileNotFound
+		}
+		return models.DeviceFile{}, fmt.Errorf("Failed to stat `%s` - %v: %s", filePath, err, strings.TrimSpace(string(output)))
+	}
+	file, ok := parseAndroidStat(strings.TrimSpace(string(output)), path.Dir(filePath))
+	if !ok {
+		return models.DeviceFile{}, fmt.Errorf("Unexpected stat output for `%s` - %s", filePath, output)
+	}
+	file.Path = filePath
+	return file, nil
+}
+
+func listFilesAndroid(ctx context.Context, device *models.Device, app string, dir string) ([]models.DeviceFile, error) {
+	if app != "" {
+		return nil, ErrAppFilesNotSupported
+	}
+	dirInfo, err := statAndroidFile(ctx, device, dir)
+	if err != nil {
+		return nil, err
+	}
+	if !dirInfo.IsDir {
+		return nil, fmt.Errorf("`%s` is not a directory", dir)
+	}
+
+	// The trailing slash resolves the storage root which is a symlink
+	output, err := exec.CommandContext(ctx, "adb", "-s", device.UDID, "shell", "find", quoteShellArg(androidStorageRoot+dir+"/"), "-mindepth", "1", "-maxdepth", "1", "-exec", "stat", "-L", "-c", quoteShellArg(androidStatFormat), "{}", "+").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list directory `%s` - %v: %s", dir, err, strings.TrimSpace(string(output)))
+	}
+
+	files := []models.DeviceFile{}
+	for _, line := range strings.Split(string(output), "\n") {
+		if file, ok := parseAndroidStat(strings.TrimSpace(line), dir); ok {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// Push a local file with adb, the media scanner is notified so images and videos show up in the gallery
+func pushFileAndroid(ctx context.Context, device *models.Device, app string, localPath string, devicePath string) error {
+	if app != "" {
+		return ErrAppFilesNotSupported
+	}
+	err := cleanupADB(ctx, device, "push", localPath, androidStorageRoot+devicePath)
+	if err != nil {
+		return err
+	}
+
+	err = cleanupADB(ctx, device, "shell", "am", "broadcast", "-a", "android.intent.action.MEDIA_SCANNER_SCAN_FILE", "-d", quoteShellArg("file://"+androidStorageRoot+devicePath))
+	if err != nil {
+		device.Logger.LogWarn("file_transfer", fmt.Sprintf("Failed to notify the media scanner about `%s` - %s", devicePath, err))
+	}
+	return nil
+}
+
+func pullFileAndroid(ctx context.Context, device *models.Device, app string, devicePath string, localPath string) error {
+	if app != "" {
+		return ErrAppFilesNotSupported
+	}
+	file, err := statAndroidFile(ctx, device, devicePath)
+	if err != nil {
+		return err
+	}
+	if file.IsDir {
+		return ErrDeviceFileIsDir
+	}
+	if file.Size > MaxDeviceFileSize {
+		return ErrDeviceFileTooLarge
+	}
+	return cleanupADB(ctx, device, "pull", androidStorageRoot+devicePath, localPath)
+}
+
+// Check the size of a local file before it is pushed to a device
+func checkLocalFileSize(localPath string) error {
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return fmt.Errorf("Failed to stat local file `%s` - %s", localPath, err)
+	}
+	if info.Size() > MaxDeviceFileSize {
+		return ErrDeviceFileTooLarge
+	}
+	return nil
+}
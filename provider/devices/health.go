@@ -17,19 +17,19 @@ func GetDeviceHealth(device *models.Device) (bool, error) {
 		return false, err
 	}
 
-	return device.Connected, nil
+	return device.IsConnected(), nil
 }
 
 func checkAppiumSession(device *models.Device) error {
 	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://localhost:%s/sessions", device.AppiumPort), nil)
 	if err != nil {
-		device.AppiumSessionID = ""
+		device.SetAppiumSession("")
 		return fmt.Errorf("checkAppiumSession: Failed creating request - %s", err)
 	}
 
 	response, err := netClient.Do(req)
 	if err != nil {
-		device.AppiumSessionID = ""
+		device.SetAppiumSession("")
 		return fmt.Errorf("checkAppiumSession: Failed executing request `%s` - %s", req.URL, err)
 	}
 	responseBody, _ := io.ReadAll(response.Body)
@@ -37,31 +37,34 @@ func checkAppiumSession(device *models.Device) error {
 	var responseJson AppiumGetSessionsResponse
 	err = json.Unmarshal(responseBody, &responseJson)
 	if err != nil {
-		device.AppiumSessionID = ""
+		device.SetAppiumSession("")
 		return fmt.Errorf("checkAppiumSession: Failed unmarshaling response json - %s", err)
 	}
 
 	if len(responseJson.Value) == 0 {
 		sessionID, err := createAppiumSession(device)
 		if err != nil {
-			device.AppiumSessionID = ""
+			device.SetAppiumSession("")
 			return fmt.Errorf("checkAppiumSession: Could not create new Appium session - %s", err)
 		}
-		device.AppiumSessionID = sessionID
+		device.SetAppiumSession(sessionID)
 		return nil
 	}
 
-	device.AppiumSessionID = responseJson.Value[0].ID
+	device.SetAppiumSession(responseJson.Value[0].ID)
 	return nil
 }
 
 func createAppiumSession(device *models.Device) (string, error) {
-	var automationName = "UiAutomator2"
-	var platformName = "Android"
+	driver, err := GetDeviceDriver(device)
+	if err != nil {
+		return "", fmt.Errorf("createAppiumSession: %s", err)
+	}
+	capabilities := driver.AppiumCapabilities(device)
+	automationName := capabilities.AutomationName
+	platformName := capabilities.PlatformName
 	var waitForIdleTimeout = 10
-	if device.OS == "ios" {
-		automationName = "XCUITest"
-		platformName = "iOS"
+	if automationName == "XCUITest" {
 		waitForIdleTimeout = 0
 	}
 
@@ -0,0 +1,178 @@
+package devices
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"GADS/common/models"
+	"GADS/provider/config"
+)
+
+// Stages the provider hooks run at
+const (
+	hookStagePreSetup    = "pre_setup"
+	hookStagePostSetup   = "post_setup"
+	hookStagePostSession = "post_session"
+)
+
+// Configured hooks for a stage, read on each run so reloaded hooks apply right away
+func stageHooks(stage string) []models.DeviceHook {
+	hooks := config.ProviderConfig().Hooks
+	if hooks == nil {
+		return nil
+	}
+	switch stage {
+	case hookStagePreSetup:
+		return hooks.PreSetup
+	case hookStagePostSetup:
+		return hooks.PostSetup
+	case hookStagePostSession:
+		return hooks.PostSession
+	}
+	return nil
+}
+
+// Run the hooks of a stage for a device in order
+// A failed hook stops the remaining hooks and its error is returned unless the hook has `continue_on_error`
+func runDeviceHooks(device *models.Device, stage string) error {
+	for _, hook := range stageHooks(stage) {
+		if hook.OS != "" && hook.OS != device.OS {
+			continue
+		}
+		// Hooks around the setup are reported as setup steps so it is visible when a device is stuck on one
+		if stage != hookStagePostSession {
+			device.StartSetupStep(fmt.Sprintf("%s_hook:%s", stage, hook.Name))
+		}
+
+		start := time.Now()
+		err := runDeviceHook(device, hook)
+		if err != nil {
+			if hook.ContinueOnError {
+				device.Logger.LogWarn("device_hook", fmt.Sprintf("Hook `%s` of stage `%s` failed, continuing - %s", hook.Name, stage, err))
+				if stage != hookStagePostSession {
+					device.FailSetupStep(err.Error())
+				}
+				continue
+			}
+			return fmt.Errorf("Hook `%s` of stage `%s` failed - %s", hook.Name, stage, err)
+		}
+		device.Logger.LogInfo("device_hook", fmt.Sprintf("Hook `%s` of stage `%s` finished in %v", hook.Name, stage, time.Since(start).Round(time.Millisecond)))
+	}
+	return nil
+}
+
+func runDeviceHook(device *models.Device, hook models.DeviceHook) error {
+	timeout := hook.Timeout
+	if timeout == 0 {
+		timeout = models.DefaultHookTimeout
+	}
+	ctx, cancel := context.WithTimeout(device.Context, time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	switch {
+	case hook.Command != "":
+		if runtime.GOOS == "windows" {
+			cmd = exec.CommandContext(ctx, "cmd", "/C", hook.Command)
+		} else {
+			cmd = exec.CommandContext(ctx, "sh", "-c", hook.Command)
+		}
+	case len(hook.ADB) > 0:
+		cmd = exec.CommandContext(ctx, "adb", append([]string{"-s", device.UDID}, hook.ADB...)...)
+	default:
+		return runHookAction(ctx, device, hook)
+	}
+
+	cmd.Dir = config.ProviderConfig().ProviderFolder
+	cmd.Env = append(os.Environ(), hookEnv(device)...)
+	// Processes started by the command can keep its output open after it is killed on timeout
+	cmd.WaitDelay = time.Second
+	output, err := cmd.CombinedOutput()
+	logHookOutput(device, hook, output)
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %d seconds", timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("`%s` - %s", cmd.Args, err)
+	}
+	return nil
+}
+
+// Declarative actions, app installation goes through the device driver and is not limited by the hook timeout
+func runHookAction(ctx context.Context, device *models.Device, hook models.DeviceHook) error {
+	switch hook.Action {
+	case models.HookActionInstallApp, models.HookActionUninstallApp:
+		driver, err := GetDeviceDriver(device)
+		if err != nil {
+			return err
+		}
+		if hook.Action == models.HookActionInstallApp {
+			return driver.InstallApp(device, hook.App)
+		}
+		return driver.UninstallApp(device, hook.App)
+	case models.HookActionDisableAnimations:
+		for _, setting := range []string{"window_animation_scale", "transition_animation_scale", "animator_duration_scale"} {
+			output, err := exec.CommandContext(ctx, "adb", "-s", device.UDID, "shell", "settings", "put", "global", setting, "0").CombinedOutput()
+			if err != nil {
+				return fmt.Errorf("Failed to set `%s` - %s: %s", setting, err, output)
+			}
+		}
+		return nil
+	case models.HookActionWakeScreen:
+		output, err := exec.CommandContext(ctx, "adb", "-s", device.UDID, "shell", "input", "keyevent", "KEYCODE_WAKEUP").CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("Failed to wake the screen - %s: %s", err, output)
+		}
+		return nil
+	}
+	return fmt.Errorf("Unknown hook action `%s`", hook.Action)
+}
+
+// Environment variables describing the device for hook commands, ports are empty before the device setup allocated them
+func hookEnv(device *models.Device) []string {
+	return []string{
+		"GADS_DEVICE_UDID=" + device.UDID,
+		"GADS_DEVICE_OS=" + device.OS,
+		"GADS_DEVICE_NAME=" + device.Name,
+		"GADS_DEVICE_OS_VERSION=" + device.OSVersion,
+		"GADS_DEVICE_APPIUM_PORT=" + device.AppiumPort,
+		"GADS_DEVICE_STREAM_PORT=" + device.StreamPort,
+		"GADS_DEVICE_WDA_PORT=" + device.WDAPort,
+		"GADS_PROVIDER_NICKNAME=" + config.ProviderConfig().Nickname,
+		"GADS_PROVIDER_FOLDER=" + config.ProviderConfig().ProviderFolder,
+	}
+}
+
+// Store the hook output in the device log line by line
+func logHookOutput(device *models.Device, hook models.DeviceHook, output []byte) {
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		device.Logger.LogInfo("device_hook", fmt.Sprintf("[%s] %s", hook.Name, scanner.Text()))
+	}
+}
+
+// Run the pre-setup hooks and then the driver setup of a device
+func setupDeviceWithHooks(driver DeviceDriver, device *models.Device) {
+	err := runDeviceHooks(device, hookStagePreSetup)
+	if err != nil {
+		failDeviceSetup(device, "device_setup", fmt.Sprintf("Pre-setup hooks failed for device `%s` - %s", device.UDID, err))
+		return
+	}
+	driver.Setup(device)
+}
+
+// Run the post-setup hooks and mark the device as `live`, called by the device drivers when their setup finished
+func finishDeviceSetup(device *models.Device) {
+	err := runDeviceHooks(device, hookStagePostSetup)
+	if err != nil {
+		failDeviceSetup(device, "device_setup", fmt.Sprintf("Post-setup hooks failed for device `%s` - %s", device.UDID, err))
+		return
+	}
+	setDeviceState(device, models.DeviceStateLive, "device setup finished")
+}
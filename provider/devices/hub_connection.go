@@ -0,0 +1,295 @@
+package devices
+
+import (
+	"GADS/common/models"
+	"GADS/provider/config"
+	"GADS/provider/logger"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+)
+
+const (
+	hubReconnectInitialBackoff = 1 * time.Second
+	hubReconnectMaxBackoff     = 30 * time.Second
+	hubWriteTimeout            = 5 * time.Second
+)
+
+// Keep a persistent websocket connection to the hub
+// Device changes are pushed as deltas each second with a heartbeat in between, and the hub can send commands back on the same connection
+// If the hub is unreachable we keep reconnecting with backoff instead of stopping the provider
+func connectToHub() {
+	backoff := hubReconnectInitialBackoff
+	for {
+		connectedAt := time.Now()
+		err := runHubConnection()
+		if shuttingDown.Load() {
+			return
+		}
+		// Start over with the initial backoff if the connection was up for a while, the hub was probably restarted
+		if time.Since(connectedAt) > hubReconnectMaxBackoff {
+			backoff = hubReconnectInitialBackoff
+		}
+		logger.ProviderLogger.LogError("hub_connection", fmt.Sprintf("Connection to hub lost, reconnecting in %v - %s", backoff, err))
+		time.Sleep(backoff)
+		backoff = min(backoff*2, hubReconnectMaxBackoff)
+	}
+}
+
+type hubConnection struct {
+	conn    net.Conn
+	reader  io.Reader
+	writeMu sync.Mutex
+}
+
+var activeHubConnectionMu sync.Mutex
+var activeHubConnection *hubConnection
+
+// Tell the hub the provider is going offline so it marks its devices disconnected right away and close the connection
+func notifyHubShutdown() {
+	activeHubConnectionMu.Lock()
+	hc := activeHubConnection
+	activeHubConnectionMu.Unlock()
+	if hc == nil {
+		return
+	}
+
+	err := hc.send(models.ProviderMessage{Type: models.ProviderMessageShutdown})
+	if err != nil {
+		logger.ProviderLogger.LogWarn("hub_connection", fmt.Sprintf("Failed to notify hub about provider shutdown - %s", err))
+	}
+
+	hc.writeMu.Lock()
+	defer hc.writeMu.Unlock()
+	hc.conn.SetWriteDeadline(time.Now().Add(hubWriteTimeout))
+	wsutil.WriteClientMessage(hc.conn, ws.OpClose, ws.NewCloseFrameBody(ws.StatusGoingAway, "provider shutdown"))
+	hc.conn.Close()
+}
+
+func (hc *hubConnection) send(message interface{}) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal message - %s", err)
+	}
+
+	hc.writeMu.Lock()
+	defer hc.writeMu.Unlock()
+	hc.conn.SetWriteDeadline(time.Now().Add(hubWriteTimeout))
+	return wsutil.WriteClientText(hc.conn, data)
+}
+
+func hubWebsocketURL() string {
+	hubAddress := strings.TrimSuffix(config.ProviderConfig().HubAddress, "/")
+	if strings.HasPrefix(hubAddress, "https://") {
+		return "wss://" + strings.TrimPrefix(hubAddress, "https://") + "/provider-ws"
+	}
+	return "ws://" + strings.TrimPrefix(hubAddress, "http://") + "/provider-ws"
+}
+
+func runHubConnection() error {
+	dialer := ws.Dialer{
+		Header: ws.HandshakeHeaderHTTP(http.Header{
+			"X-Provider-Nickname": []string{config.ProviderConfig().Nickname},
+			"X-Provider-Token":    []string{config.ProviderConfig().HubToken},
+		}),
+		Timeout: 10 * time.Second,
+	}
+	conn, br, _, err := dialer.Dial(context.Background(), hubWebsocketURL())
+	if err != nil {
+		return fmt.Errorf("Failed to connect to hub websocket - %s", err)
+	}
+	defer conn.Close()
+
+	hc := &hubConnection{conn: conn, reader: conn}
+	// The hub might have sent data right after the handshake, it has to be read before the connection
+	if br != nil {
+		hc.reader = io.MultiReader(br, conn)
+	}
+	logger.ProviderLogger.LogInfo("hub_connection", fmt.Sprintf("Connected to hub on `%s`", hubWebsocketURL()))
+
+	activeHubConnectionMu.Lock()
+	activeHubConnection = hc
+	activeHubConnectionMu.Unlock()
+	defer func() {
+		activeHubConnectionMu.Lock()
+		activeHubConnection = nil
+		activeHubConnectionMu.Unlock()
+	}()
+
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- readHubCommands(hc)
+	}()
+
+	// Send all devices on connect, then only what changed
+	lastSent := make(map[string][]byte)
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+	for {
+		// The last message on shutdown is sent by notifyHubShutdown
+		if shuttingDown.Load() {
+			return nil
+		}
+		err = sendDeviceDeltas(hc, lastSent)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case err = <-readErr:
+			return err
+		case <-ticker.C:
+		}
+	}
+}
+
+// Compare the current devices with the last sent state and push only the changes
+// When nothing changed a heartbeat is sent so the hub knows the provider is alive
+func sendDeviceDeltas(hc *hubConnection, lastSent map[string][]byte) error {
+	message := models.ProviderMessage{Type: models.ProviderMessageDevices}
+	current := make(map[string][]byte)
+	for _, device := range Registry.All() {
+		udid := device.UDID
+		deviceJSON, err := json.Marshal(device)
+		if err != nil {
+			logger.ProviderLogger.LogError("hub_connection", fmt.Sprintf("Failed to marshal device `%s` - %s", udid, err))
+			continue
+		}
+		current[udid] = deviceJSON
+		if string(lastSent[udid]) != string(deviceJSON) {
+			message.Devices = append(message.Devices, device)
+		}
+	}
+	for udid := range lastSent {
+		if _, ok := current[udid]; !ok {
+			message.RemovedDevices = append(message.RemovedDevices, udid)
+		}
+	}
+
+	if len(message.Devices) == 0 && len(message.RemovedDevices) == 0 {
+		message.Type = models.ProviderMessageHeartbeat
+	}
+
+	err := hc.send(message)
+	if err != nil {
+		return fmt.Errorf("Failed to send %s message to hub - %s", message.Type, err)
+	}
+
+	clear(lastSent)
+	for udid, deviceJSON := range current {
+		lastSent[udid] = deviceJSON
+	}
+	return nil
+}
+
+func readHubCommands(hc *hubConnection) error {
+	rw := struct {
+		io.Reader
+		io.Writer
+	}{bufio.NewReader(hc.reader), hc.conn}
+
+	for {
+		data, op, err := wsutil.ReadServerData(rw)
+		if err != nil {
+			return fmt.Errorf("Failed to read from hub websocket - %s", err)
+		}
+		if op == ws.OpClose {
+			return fmt.Errorf("Hub closed the websocket")
+		}
+
+		var command models.ProviderCommand
+		err = json.Unmarshal(data, &command)
+		if err != nil {
+			logger.ProviderLogger.LogError("hub_connection", fmt.Sprintf("Failed to unmarshal command from hub - %s", err))
+			continue
+		}
+
+		go func() {
+			result := executeHubCommand(command)
+			err := hc.send(models.ProviderMessage{Type: models.ProviderMessageCommandResult, CommandResult: &result})
+			if err != nil {
+				logger.ProviderLogger.LogError("hub_connection", fmt.Sprintf("Failed to send result of command `%s` to hub - %s", command.ID, err))
+			}
+		}()
+	}
+}
+
+func executeHubCommand(command models.ProviderCommand) models.ProviderCommandResult {
+	logger.ProviderLogger.LogInfo("hub_connection", fmt.Sprintf("Received `%s` command from hub", command.Type))
+	result := models.ProviderCommandResult{ID: command.ID}
+
+	switch command.Type {
+	case models.ProviderCommandResetDevice:
+		device, ok := Registry.Get(command.UDID)
+		if !ok {
+			result.Error = fmt.Sprintf("Device with udid `%s` does not exist", command.UDID)
+			return result
+		}
+		err := ResetDeviceSetup(device)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+	case models.ProviderCommandClearQuarantine:
+		device, ok := Registry.Get(command.UDID)
+		if !ok {
+			result.Error = fmt.Sprintf("Device with udid `%s` does not exist", command.UDID)
+			return result
+		}
+		err := ClearQuarantine(device)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+	case models.ProviderCommandRebootDevice:
+		device, ok := Registry.Get(command.UDID)
+		if !ok {
+			result.Error = fmt.Sprintf("Device with udid `%s` does not exist", command.UDID)
+			return result
+		}
+		err := RebootDevice(device)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+	case models.ProviderCommandStartEmulator:
+		err := StartEmulator(command.AVD)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+	case models.ProviderCommandStopEmulator:
+		err := StopEmulator(command.AVD)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+	case models.ProviderCommandSyncDevices:
+		RequestDevicesSync()
+	case models.ProviderCommandReloadConfig:
+		restartRequired, err := config.ReloadConfig()
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		if len(restartRequired) > 0 {
+			logger.ProviderLogger.LogWarn("hub_connection", fmt.Sprintf("Reloaded provider config but changes to %s require a provider restart", strings.Join(restartRequired, ", ")))
+		}
+	default:
+		result.Error = fmt.Sprintf("Unknown command type `%s`", command.Type)
+		return result
+	}
+
+	result.Success = true
+	return result
+}
@@ -20,9 +20,11 @@ import (
 	"GADS/provider/logger"
 
 	"github.com/danielpaulus/go-ios/ios"
+	"github.com/danielpaulus/go-ios/ios/diagnostics"
 	"github.com/danielpaulus/go-ios/ios/forward"
 	"github.com/danielpaulus/go-ios/ios/imagemounter"
 	"github.com/danielpaulus/go-ios/ios/installationproxy"
+	"github.com/danielpaulus/go-ios/ios/instruments"
 	"github.com/danielpaulus/go-ios/ios/testmanagerd"
 	"github.com/danielpaulus/go-ios/ios/tunnel"
 	"github.com/danielpaulus/go-ios/ios/zipconduit"
@@ -34,8 +36,7 @@ func goIosForward(device *models.Device, hostPort string, devicePort string) {
 
 	cl, err := forward.Forward(device.GoIOSDeviceEntry, uint16(hostPortInt), uint16(devicePortInt))
 	if err != nil {
-		logger.ProviderLogger.LogError("ios_device_setup", fmt.Sprintf("Failed to forward device port %s to host port %s for device `%s` - %s", devicePort, hostPort, device.UDID, err))
-		resetLocalDevice(device)
+		failDeviceSetup(device, "ios_device_setup", fmt.Sprintf("Failed to forward device port %s to host port %s for device `%s` - %s", devicePort, hostPort, device.UDID, err))
 		return
 	}
 
@@ -49,25 +50,31 @@ func goIosForward(device *models.Device, hostPort string, devicePort string) {
 
 // Start the prebuilt WebDriverAgent with `xcodebuild`
 func startWdaWithXcodebuild(device *models.Device) {
-	cmd := exec.CommandContext(device.Context, "xcodebuild",
+	ctx := device.Context
+	cmd := exec.CommandContext(ctx, "xcodebuild",
 		"-project", "WebDriverAgent.xcodeproj",
 		"-scheme", "WebDriverAgentRunner",
 		"-destination", "platform=iOS,id="+device.UDID,
 		"-derivedDataPath", "./build",
 		"test-without-building")
-	cmd.Dir = config.ProviderConfig.WdaRepoPath
+	cmd.Dir = config.ProviderConfig().WdaRepoPath
+	stopProcessGracefully(cmd)
 	logger.ProviderLogger.LogDebug("webdriveragent_xcodebuild", fmt.Sprintf("startWdaWithXcodebuild: Starting WebDriverAgent with command `%v`", cmd.Args))
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		device.Logger.LogError("webdriveragent_xcodebuild", fmt.Sprintf("startWdaWithXcodebuild: Error creating stdoutpipe while running WebDriverAgent with xcodebuild for device `%v` - %v", device.UDID, err))
-		resetLocalDevice(device)
+		message := fmt.Sprintf("startWdaWithXcodebuild: Error creating stdoutpipe while running WebDriverAgent with xcodebuild for device `%v` - %v", device.UDID, err)
+		device.Logger.LogError("webdriveragent_xcodebuild", message)
+		resetDevice(device, message)
 		return
 	}
 
+	processDone := trackDeviceProcess(device)
+	defer processDone()
 	if err := cmd.Start(); err != nil {
-		device.Logger.LogError("webdriveragent_xcodebuild", fmt.Sprintf("startWdaWithXcodebuild: Could not start WebDriverAgent with xcodebuild for device `%v` - %v", device.UDID, err))
-		resetLocalDevice(device)
+		message := fmt.Sprintf("startWdaWithXcodebuild: Could not start WebDriverAgent with xcodebuild for device `%v` - %v", device.UDID, err)
+		device.Logger.LogError("webdriveragent_xcodebuild", message)
+		resetDevice(device, message)
 		return
 	}
 
@@ -79,14 +86,20 @@ func startWdaWithXcodebuild(device *models.Device) {
 		//device.Logger.LogInfo("webdriveragent", strings.TrimSpace(line))
 
 		if strings.Contains(line, "Restarting after") {
-			resetLocalDevice(device)
+			message := fmt.Sprintf("startWdaWithXcodebuild: WebDriverAgent(xcodebuild) is restarting on device `%v`", device.UDID)
+			device.Logger.LogError("webdriveragent_xcodebuild", message)
+			resetDevice(device, message)
+			// The reset cancelled the device context, wait for xcodebuild to exit so the process is not counted as stopped too early
+			cmd.Wait()
 			return
 		}
 	}
 
-	if err := cmd.Wait(); err != nil {
-		device.Logger.LogError("webdriveragent_xcodebuild", fmt.Sprintf("startWdaWithXcodebuild: Error waiting for WebDriverAgent(xcodebuild) command to finish, it errored out or device `%v` was disconnected - %v", device.UDID, err))
-		resetLocalDevice(device)
+	// WebDriverAgent stopped by cancelling the device context is not a failure, the device may already be set up again
+	if err := cmd.Wait(); err != nil && ctx.Err() == nil {
+		message := fmt.Sprintf("startWdaWithXcodebuild: Error waiting for WebDriverAgent(xcodebuild) command to finish, it errored out or device `%v` was disconnected - %v", device.UDID, err)
+		device.Logger.LogError("webdriveragent_xcodebuild", message)
+		resetDevice(device, message)
 	}
 }
 
@@ -170,19 +183,17 @@ func createWebDriverAgentSession(device *models.Device) error {
 }
 
 func mountDeveloperImageIOS(device *models.Device) {
-	basedir := fmt.Sprintf("%s/devimages", config.ProviderConfig.ProviderFolder)
+	basedir := fmt.Sprintf("%s/devimages", config.ProviderConfig().ProviderFolder)
 
 	path, err := imagemounter.DownloadImageFor(device.GoIOSDeviceEntry, basedir)
 	if err != nil {
-		logger.ProviderLogger.LogError("ios_device_setup", fmt.Sprintf("Failed to download DDI for device `%s` to path `%s` - %s", device.UDID, basedir, err))
-		resetLocalDevice(device)
+		failDeviceSetup(device, "ios_device_setup", fmt.Sprintf("Failed to download DDI for device `%s` to path `%s` - %s", device.UDID, basedir, err))
 		return
 	}
 
 	err = imagemounter.MountImage(device.GoIOSDeviceEntry, path)
 	if err != nil {
-		logger.ProviderLogger.LogError("ios_device_setup", fmt.Sprintf("Failed to mount DDI on device `%s` from path `%s` - %s", device.UDID, path, err))
-		resetLocalDevice(device)
+		failDeviceSetup(device, "ios_device_setup", fmt.Sprintf("Failed to mount DDI on device `%s` from path `%s` - %s", device.UDID, path, err))
 	}
 }
 
@@ -190,7 +201,7 @@ func mountDeveloperImageIOS(device *models.Device) {
 func pairIOS(device *models.Device) error {
 	logger.ProviderLogger.LogInfo("ios_device_setup", fmt.Sprintf("Pairing device `%s`", device.UDID))
 
-	p12, err := os.ReadFile(fmt.Sprintf("%s/supervision.p12", config.ProviderConfig.ProviderFolder))
+	p12, err := os.ReadFile(fmt.Sprintf("%s/supervision.p12", config.ProviderConfig().ProviderFolder))
 	if err != nil {
 		logger.ProviderLogger.LogWarn("ios_device_setup", fmt.Sprintf("Could not read supervision.p12 file when pairing device with UDID: %s, falling back to unsupervised pairing - %s", device.UDID, err))
 		err = ios.Pair(device.GoIOSDeviceEntry)
@@ -200,7 +211,7 @@ func pairIOS(device *models.Device) error {
 		return nil
 	}
 
-	err = ios.PairSupervised(device.GoIOSDeviceEntry, p12, config.ProviderConfig.SupervisionPassword)
+	err = ios.PairSupervised(device.GoIOSDeviceEntry, p12, config.ProviderConfig().SupervisionPassword)
 	if err != nil {
 		return fmt.Errorf("Could not perform supervised pairing successfully - %s", err)
 	}
@@ -232,7 +243,7 @@ func GetInstalledAppsIOS(device *models.Device) []string {
 func uninstallAppIOS(device *models.Device, bundleID string) error {
 	svc, err := installationproxy.New(device.GoIOSDeviceEntry)
 	if err != nil {
-		device.Logger.LogError("uninstall_app", fmt.Sprintf("uninstallAppIOS: Failed creating installation proxy connection - %v", bundleID, err))
+		device.Logger.LogError("uninstall_app", fmt.Sprintf("uninstallAppIOS: Failed creating installation proxy connection for `%s` - %v", bundleID, err))
 		return err
 	}
 	err = svc.Uninstall(bundleID)
@@ -245,13 +256,13 @@ func uninstallAppIOS(device *models.Device, bundleID string) error {
 }
 
 func installAppDefaultPath(device *models.Device, appName string) error {
-	appPath := fmt.Sprintf("%s/%s", config.ProviderConfig.ProviderFolder, appName)
+	appPath := fmt.Sprintf("%s/%s", config.ProviderConfig().ProviderFolder, appName)
 
 	return installAppIOS(device, appPath)
 }
 
 func installAppIOS(device *models.Device, appPath string) error {
-	if config.ProviderConfig.OS == "windows" {
+	if config.ProviderConfig().OS == "windows" {
 		appPath = strings.TrimPrefix(appPath, "./")
 	}
 
@@ -324,8 +335,8 @@ func goIosDeviceWithRsdProvider(device *models.Device) error {
 
 func runWDAGoIOS(device *models.Device) {
 	_, err := testmanagerd.RunXCUITest(
-		config.ProviderConfig.WdaBundleID,
-		config.ProviderConfig.WdaBundleID,
+		config.ProviderConfig().WdaBundleID,
+		config.ProviderConfig().WdaBundleID,
 		"WebDriverAgentRunner.xctest",
 		device.GoIOSDeviceEntry,
 		nil,
@@ -333,7 +344,7 @@ func runWDAGoIOS(device *models.Device) {
 		nil,
 		testmanagerd.NewTestListener(io.Discard, io.Discard, os.TempDir()))
 	if err != nil {
-		resetLocalDevice(device)
+		failDeviceSetup(device, "ios_device_setup", fmt.Sprintf("Failed to run WebDriverAgent with go-ios on device `%s` - %s", device.UDID, err))
 	}
 }
 
@@ -353,3 +364,191 @@ func updateIOSScreenSize(device *models.Device, deviceMachineCode string) error
 
 	return nil
 }
+
+// Get the iOS device hardware model and screen size if it is not already set in the DB using the device info plist
+func updateIOSHardwareInfo(device *models.Device) error {
+	plistValues, err := ios.GetValuesPlist(device.GoIOSDeviceEntry)
+	if err != nil {
+		return fmt.Errorf("Could not get info plist values with go-ios - %s", err)
+	}
+	device.HardwareModel = plistValues["HardwareModel"].(string)
+
+	if device.ScreenHeight == "" || device.ScreenWidth == "" {
+		err = updateIOSScreenSize(device, plistValues["ProductType"].(string))
+		if err != nil {
+			return fmt.Errorf("Failed to update screen dimensions - %s", err)
+		}
+	}
+
+	return nil
+}
+
+// Detect the data of a connected iOS device that is not registered in the DB yet using the device info plist
+func identifyIOSDevice(device *models.Device) error {
+	goIosDeviceEntry, err := ios.GetDevice(device.UDID)
+	if err != nil {
+		return fmt.Errorf("Could not get `go-ios` DeviceEntry - %s", err)
+	}
+	plistValues, err := ios.GetValuesPlist(goIosDeviceEntry)
+	if err != nil {
+		return fmt.Errorf("Could not get info plist values with go-ios - %s", err)
+	}
+
+	device.Name, _ = plistValues["DeviceName"].(string)
+	device.OSVersion, _ = plistValues["ProductVersion"].(string)
+	device.DeviceType = "real"
+	productType, _ := plistValues["ProductType"].(string)
+
+	return updateIOSScreenSize(device, productType)
+}
+
+// Take a PNG screenshot of the device screen using the go-ios instruments screenshot service
+func screenshotIOS(device *models.Device) ([]byte, error) {
+	svc, err := instruments.NewScreenshotService(device.GoIOSDeviceEntry)
+	if err != nil {
+		return nil, fmt.Errorf("screenshotIOS: Failed creating screenshot service connection - %s", err)
+	}
+	defer svc.Close()
+
+	return svc.TakeScreenshot()
+}
+
+type iosDriver struct{}
+
+func (iosDriver) Name() string {
+	return "ios"
+}
+
+func (iosDriver) Enabled() bool {
+	return config.ProviderConfig().ProvideIOS
+}
+
+func (iosDriver) Discover() []string {
+	return getConnectedDevicesIOS()
+}
+
+func (iosDriver) Setup(device *models.Device) {
+	device.WdaReadyChan = make(chan bool, 1)
+	setupIOSDevice(device)
+}
+
+// Close the userspace tunnel of iOS 17.4+ devices
+func (iosDriver) Teardown(device *models.Device) {
+	if device.GoIOSTunnel.Address != "" {
+		device.GoIOSTunnel.Close()
+	}
+}
+
+// WebDriverAgent is stopped with the device context
+func (iosDriver) Stop(device *models.Device) {}
+
+func (iosDriver) InstallApp(device *models.Device, appName string) error {
+	return installAppDefaultPath(device, appName)
+}
+
+func (iosDriver) UninstallApp(device *models.Device, app string) error {
+	return uninstallAppIOS(device, app)
+}
+
+func (iosDriver) InstalledApps(device *models.Device) []string {
+	return GetInstalledAppsIOS(device)
+}
+
+func (iosDriver) LaunchApp(device *models.Device, app string) error {
+	ctx, cancel := context.WithTimeout(device.Context, appCommandTimeout)
+	defer cancel()
+	return launchAppIOS(ctx, device, app)
+}
+
+func (iosDriver) TerminateApp(device *models.Device, app string) error {
+	ctx, cancel := context.WithTimeout(device.Context, appCommandTimeout)
+	defer cancel()
+	return terminateAppIOS(ctx, device, app)
+}
+
+func (iosDriver) OpenURL(device *models.Device, url string) error {
+	ctx, cancel := context.WithTimeout(device.Context, appCommandTimeout)
+	defer cancel()
+	return openURLIOS(ctx, device, url)
+}
+
+func (iosDriver) ForegroundApp(device *models.Device) (string, error) {
+	ctx, cancel := context.WithTimeout(device.Context, appCommandTimeout)
+	defer cancel()
+	return foregroundAppIOS(ctx, device)
+}
+
+func (iosDriver) Screenshot(device *models.Device) ([]byte, error) {
+	return screenshotIOS(device)
+}
+
+// GADS iOS stream serves raw JPEG data over a plain TCP connection, WebDriverAgent serves an MJPEG stream over HTTP
+func (iosDriver) StreamSource(device *models.Device) string {
+	if config.ProviderConfig().UseGadsIosStream {
+		return "tcp://localhost:" + device.StreamPort
+	}
+	return "http://localhost:" + device.WDAStreamPort
+}
+
+func (iosDriver) HardwareInfo(device *models.Device) error {
+	return updateIOSHardwareInfo(device)
+}
+
+func (iosDriver) Identify(device *models.Device) error {
+	return identifyIOSDevice(device)
+}
+
+func (iosDriver) Telemetry(device *models.Device) (models.DeviceTelemetry, error) {
+	return telemetryIOS(device)
+}
+
+func (iosDriver) Cleanup(ctx context.Context, device *models.Device, action string, policy models.CleanupPolicy) error {
+	return cleanupActionIOS(ctx, device, action, policy)
+}
+
+func (iosDriver) Reboot(device *models.Device) error {
+	err := diagnostics.Reboot(device.GoIOSDeviceEntry)
+	if err != nil {
+		return fmt.Errorf("Failed to reboot device with go-ios - %s", err)
+	}
+	return nil
+}
+
+func (iosDriver) ListFiles(device *models.Device, app string, dir string) ([]models.DeviceFile, error) {
+	ctx, cancel := context.WithTimeout(device.Context, fileTransferTimeout)
+	defer cancel()
+	return listFilesIOS(ctx, device, app, dir)
+}
+
+func (iosDriver) PushFile(device *models.Device, app string, localPath string, devicePath string) error {
+	err := checkLocalFileSize(localPath)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(device.Context, fileTransferTimeout)
+	defer cancel()
+	return pushFileIOS(ctx, device, app, localPath, devicePath)
+}
+
+func (iosDriver) PullFile(device *models.Device, app string, devicePath string, localPath string) error {
+	ctx, cancel := context.WithTimeout(device.Context, fileTransferTimeout)
+	defer cancel()
+	return pullFileIOS(ctx, device, app, devicePath, localPath)
+}
+
+func (iosDriver) AppiumCapabilities(device *models.Device) models.AppiumServerCapabilities {
+	return models.AppiumServerCapabilities{
+		UDID:                  device.UDID,
+		WdaURL:                "http://localhost:" + device.WDAPort,
+		WdaMjpegPort:          device.WDAStreamPort,
+		WdaLocalPort:          device.WDAPort,
+		WdaLaunchTimeout:      "120000",
+		WdaConnectionTimeout:  "240000",
+		ClearSystemFiles:      "false",
+		PreventWdaAttachments: "true",
+		SimpleIsVisibleCheck:  "false",
+		AutomationName:        "XCUITest",
+		PlatformName:          "iOS",
+		DeviceName:            device.Name,
+	}
+}
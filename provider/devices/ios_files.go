@@ -0,0 +1,353 @@
+package devices
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"GADS/common/models"
+
+	"github.com/danielpaulus/go-ios/ios"
+	"github.com/danielpaulus/go-ios/ios/afc"
+)
+
+const (
+	afcServiceName         = "com.apple.afc"
+	houseArrestServiceName = "com.apple.mobile.house_arrest"
+	afcChunkSize           = 64 * 1024
+)
+
+// Minimal AFC client for the file browser
+// The go-ios AFC connection does not expose file sizes and only pulls to local paths, so the packets are sent directly
+type afcClient struct {
+	conn      ios.DeviceConnectionInterface
+	packetNum uint64
+	ctx       context.Context
+	stopClose func() bool
+}
+
+// Connect to the media directory of the device or the container of an app
+// App containers are only accessible for apps signed with a development profile
+// The connection is closed when the context is done so a blocked transfer fails instead of hanging
+func newAFCClient(ctx context.Context, device *models.Device, app string) (*afcClient, error) {
+	if app == "" {
+		conn, err := ios.ConnectToService(device.GoIOSDeviceEntry, afcServiceName)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to connect to the AFC service - %s", err)
+		}
+		return &afcClient{conn: conn, ctx: ctx, stopClose: context.AfterFunc(ctx, func() { conn.Close() })}, nil
+	}
+
+	conn, err := ios.ConnectToService(device.GoIOSDeviceEntry, houseArrestServiceName)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to connect to the house arrest service - %s", err)
+	}
+	client := &afcClient{conn: conn, ctx: ctx, stopClose: context.AfterFunc(ctx, func() { conn.Close() })}
+	err = vendContainer(conn, app)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("Failed to access the container of app `%s` - %s", app, client.contextError(err))
+	}
+	return client, nil
+}
+
+// Ask house arrest for the app container, the connection is an AFC connection to it afterwards
+func vendContainer(conn ios.DeviceConnectionInterface, app string) error {
+	plistCodec := ios.NewPlistCodec()
+	message, err := plistCodec.Encode(map[string]interface{}{"Command": "VendContainer", "Identifier": app})
+	if err != nil {
+		return err
+	}
+	err = conn.Send(message)
+	if err != nil {
+		return err
+	}
+	responseBytes, err := plistCodec.Decode(conn.Reader())
+	if err != nil {
+		return err
+	}
+	response, err := ios.ParsePlist(responseBytes)
+	if err != nil {
+		return err
+	}
+	if response["Status"] == "Complete" {
+		return nil
+	}
+	if errorMessage, ok := response["Error"].(string); ok {
+		return errors.New(errorMessage)
+	}
+	return errors.New("unknown VendContainer response")
+}
+
+func (c *afcClient) Close() {
+	if c.stopClose() {
+		c.conn.Close()
+	}
+}
+
+// Report the context error instead of the connection error if the connection was closed because the context is done
+func (c *afcClient) contextError(err error) error {
+	if c.ctx.Err() != nil {
+		return c.ctx.Err()
+	}
+	return err
+}
+
+func (c *afcClient) request(operation uint64, headerPayload []byte, payload []byte) (afc.AfcPacket, error) {
+	thisLength := afc.Afc_header_size + uint64(len(headerPayload))
+	packet := afc.AfcPacket{
+		Header: afc.AfcPacketHeader{
+			Magic:         afc.Afc_magic,
+			Packet_num:    c.packetNum,
+			Operation:     operation,
+			This_length:   thisLength,
+			Entire_length: thisLength + uint64(len(payload)),
+		},
+		HeaderPayload: headerPayload,
+		Payload:       payload,
+	}
+	c.packetNum++
+
+	err := afc.Encode(packet, c.conn.Writer())
+	if err != nil {
+		return afc.AfcPacket{}, c.contextError(err)
+	}
+	response, err := afc.Decode(c.conn.Reader())
+	if err != nil {
+		return afc.AfcPacket{}, c.contextError(err)
+	}
+	if response.Header.Operation == afc.Afc_operation_status && len(response.HeaderPayload) >= 8 {
+		switch code := binary.LittleEndian.Uint64(response.HeaderPayload); code {
+		case afc.Afc_Err_Success:
+		case afc.Afc_Err_ObjectNotFound:
+			return response, ErrDeviceFileNotFound
+		case afc.Afc_Err_ObjectIsDir:
+			return response, ErrDeviceFileIsDir
+		default:
+			return response, fmt.Errorf("AFC error code %d", code)
+		}
+	}
+	return response, nil
+}
+
+// Parse the NUL separated key/value pairs of AFC responses
+func parseAFCDictionary(payload []byte) map[string]string {
+	values := make(map[string]string)
+	fields := strings.Split(string(payload), "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		values[fields[i]] = fields[i+1]
+	}
+	return values
+}
+
+func (c *afcClient) stat(filePath string) (models.DeviceFile, error) {
+	response, err := c.request(afc.Afc_operation_file_info, []byte(filePath), nil)
+	if err != nil {
+		return models.DeviceFile{}, err
+	}
+	info := parseAFCDictionary(response.Payload)
+	size, _ := strconv.ParseInt(info["st_size"], 10, 64)
+	modified, _ := strconv.ParseInt(info["st_mtime"], 10, 64)
+	return models.DeviceFile{
+		Name:     path.Base(filePath),
+		Path:     filePath,
+		IsDir:    info["st_ifmt"] == "S_IFDIR",
+		Size:     size,
+		Modified: modified / 1000000, // nanoseconds
+	}, nil
+}
+
+func (c *afcClient) readDir(dir string) ([]string, error) {
+	response, err := c.request(afc.Afc_operation_read_dir, []byte(dir), nil)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, name := range strings.Split(string(response.Payload), "\x00") {
+		if name != "" && name != "." && name != ".." {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func (c *afcClient) open(filePath string, mode uint64) (uint64, error) {
+	headerPayload := binary.LittleEndian.AppendUint64(nil, mode)
+	headerPayload = append(headerPayload, []byte(filePath)...)
+	headerPayload = append(headerPayload, 0)
+	response, err := c.request(afc.Afc_operation_file_open, headerPayload, nil)
+	if err != nil {
+		return 0, err
+	}
+	if response.Header.Operation != afc.Afc_operation_file_open_result || len(response.HeaderPayload) < 8 {
+		return 0, fmt.Errorf("Unexpected AFC response when opening `%s`", filePath)
+	}
+	return binary.LittleEndian.Uint64(response.HeaderPayload), nil
+}
+
+func (c *afcClient) closeFile(fd uint64) error {
+	_, err := c.request(afc.Afc_operation_file_close, binary.LittleEndian.AppendUint64(nil, fd), nil)
+	return err
+}
+
+// Create a directory and its missing parents
+func (c *afcClient) mkdir(dir string) error {
+	_, err := c.request(afc.Afc_operation_make_dir, append([]byte(dir), 0), nil)
+	return err
+}
+
+// Copy a device file to a writer in chunks, fails once more than MaxDeviceFileSize bytes were read
+// The size is checked while reading as the file can grow after it was checked with stat
+func (c *afcClient) readFile(filePath string, writer io.Writer) error {
+	fd, err := c.open(filePath, afc.Afc_Mode_RDONLY)
+	if err != nil {
+		return err
+	}
+	defer c.closeFile(fd)
+
+	var read int64
+	for {
+		headerPayload := binary.LittleEndian.AppendUint64(nil, fd)
+		headerPayload = binary.LittleEndian.AppendUint64(headerPayload, afcChunkSize)
+		response, err := c.request(afc.Afc_operation_file_read, headerPayload, nil)
+		if err != nil {
+			return err
+		}
+		if len(response.Payload) == 0 {
+			return nil
+		}
+		read += int64(len(response.Payload))
+		if read > MaxDeviceFileSize {
+			return ErrDeviceFileTooLarge
+		}
+		_, err = writer.Write(response.Payload)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Write a device file from a reader in chunks, an existing file is replaced
+func (c *afcClient) writeFile(filePath string, reader io.Reader) error {
+	fd, err := c.open(filePath, afc.Afc_Mode_WR)
+	if err != nil {
+		return err
+	}
+	defer c.closeFile(fd)
+
+	chunk := make([]byte, afcChunkSize)
+	for {
+		n, err := reader.Read(chunk)
+		if n > 0 {
+			_, writeErr := c.request(afc.Afc_operation_file_write, binary.LittleEndian.AppendUint64(nil, fd), chunk[:n])
+			if writeErr != nil {
+				return writeErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func listFilesIOS(ctx context.Context, device *models.Device, app string, dir string) ([]models.DeviceFile, error) {
+	client, err := newAFCClient(ctx, device, app)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	dirInfo, err := client.stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !dirInfo.IsDir {
+		return nil, fmt.Errorf("`%s` is not a directory", dir)
+	}
+
+	names, err := client.readDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list directory `%s` - %s", dir, err)
+	}
+	files := []models.DeviceFile{}
+	for _, name := range names {
+		file, err := client.stat(path.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// Push a local file to the media directory or an app container
+// Files in the media directory are not added to the Photos library, they are only visible through AFC
+func pushFileIOS(ctx context.Context, device *models.Device, app string, localPath string, devicePath string) error {
+	localFile, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("Failed to open local file `%s` - %s", localPath, err)
+	}
+	defer localFile.Close()
+
+	client, err := newAFCClient(ctx, device, app)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if existing, err := client.stat(devicePath); err == nil && existing.IsDir {
+		return ErrDeviceFileIsDir
+	}
+	if dir := path.Dir(devicePath); dir != "/" {
+		err = client.mkdir(dir)
+		if err != nil {
+			return fmt.Errorf("Failed to create directory `%s` - %s", dir, err)
+		}
+	}
+
+	err = client.writeFile(devicePath, localFile)
+	if err != nil {
+		return fmt.Errorf("Failed to write `%s` - %s", devicePath, err)
+	}
+	return nil
+}
+
+func pullFileIOS(ctx context.Context, device *models.Device, app string, devicePath string, localPath string) error {
+	client, err := newAFCClient(ctx, device, app)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	file, err := client.stat(devicePath)
+	if err != nil {
+		return err
+	}
+	if file.IsDir {
+		return ErrDeviceFileIsDir
+	}
+	if file.Size > MaxDeviceFileSize {
+		return ErrDeviceFileTooLarge
+	}
+
+	localFile, err := os.Create(localPath)
+	if err != nil {
+		return fmt.Errorf("Failed to create local file `%s` - %s", localPath, err)
+	}
+	defer localFile.Close()
+
+	err = client.readFile(devicePath, localFile)
+	if err != nil {
+		return fmt.Errorf("Failed to read `%s` - %w", devicePath, err)
+	}
+	return nil
+}
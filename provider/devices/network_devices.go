@@ -0,0 +1,151 @@
+package devices
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"GADS/common/models"
+	"GADS/provider/config"
+	"GADS/provider/logger"
+)
+
+const (
+	networkDevicesCheckInterval = 5 * time.Second
+	networkDeviceMaxBackoff     = 60 * time.Second
+	adbConnectTimeout           = 10 * time.Second
+)
+
+var networkDevicesMu sync.Mutex
+var networkDevices = make(map[string]*models.NetworkConnectionStatus)
+
+// Keep the configured network-attached Android devices connected with `adb connect`
+// The `host:port` target is the adb serial and the device UDID, failing targets are retried with backoff
+func maintainNetworkDevices() {
+	for {
+		// The list can change when the provider configuration is reloaded
+		targets := slices.Clone(config.ProviderConfig().NetworkDevices)
+		if len(targets) > 0 {
+			onlineDevices := getConnectedDevicesAndroid()
+			for _, target := range targets {
+				checkNetworkDevice(target, slices.Contains(onlineDevices, target))
+			}
+		}
+		forgetRemovedNetworkDevices(targets)
+
+		time.Sleep(networkDevicesCheckInterval)
+	}
+}
+
+func checkNetworkDevice(target string, online bool) {
+	now := time.Now().UnixMilli()
+
+	networkDevicesMu.Lock()
+	status, ok := networkDevices[target]
+	if !ok {
+		status = &models.NetworkConnectionStatus{Target: target}
+		networkDevices[target] = status
+	}
+
+	if online {
+		status.Connected = true
+		status.LastConnected = now
+		status.FailedAttempts = 0
+		status.LastError = ""
+		publishNetworkConnectionStatus(status)
+		networkDevicesMu.Unlock()
+		return
+	}
+
+	if status.Connected {
+		logger.ProviderLogger.LogWarn("network_devices", fmt.Sprintf("Network device `%s` went offline, reconnecting", target))
+	}
+	status.Connected = false
+
+	// Back off from repeatedly connecting to targets that keep failing
+	backoff := min(networkDevicesCheckInterval<<min(status.FailedAttempts, 4), networkDeviceMaxBackoff)
+	if status.FailedAttempts > 0 && time.Since(time.UnixMilli(status.LastAttempt)) < backoff {
+		publishNetworkConnectionStatus(status)
+		networkDevicesMu.Unlock()
+		return
+	}
+	status.LastAttempt = now
+	networkDevicesMu.Unlock()
+
+	err := adbConnect(target)
+
+	networkDevicesMu.Lock()
+	defer networkDevicesMu.Unlock()
+	if err != nil {
+		status.FailedAttempts++
+		status.LastError = err.Error()
+		logger.ProviderLogger.LogDebug("network_devices", fmt.Sprintf("Failed to connect network device `%s`, attempt %d - %s", target, status.FailedAttempts, err))
+	} else {
+		logger.ProviderLogger.LogInfo("network_devices", fmt.Sprintf("Connected network device `%s`", target))
+	}
+	publishNetworkConnectionStatus(status)
+}
+
+// Connect to a network device with adb, a stale offline connection is dropped first
+// `adb connect` exits with 0 even when it fails to connect so the output is checked as well
+func adbConnect(target string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), adbConnectTimeout)
+	defer cancel()
+
+	exec.CommandContext(ctx, "adb", "disconnect", target).Run()
+
+	cmd := exec.CommandContext(ctx, "adb", "connect", target)
+	output, err := cmd.CombinedOutput()
+	outputString := strings.TrimSpace(string(output))
+	if err != nil {
+		return fmt.Errorf("Error executing `%s` - %s %s", cmd.Args, err, outputString)
+	}
+	if !strings.Contains(outputString, "connected to") || strings.Contains(outputString, "failed") || strings.Contains(outputString, "cannot") {
+		return fmt.Errorf("%s", outputString)
+	}
+
+	return nil
+}
+
+// Attach a copy of the connection status to the respective device so it is reported to the hub with the device data
+func publishNetworkConnectionStatus(status *models.NetworkConnectionStatus) {
+	if device, ok := Registry.Get(status.Target); ok {
+		statusCopy := *status
+		device.SetNetworkConnection(&statusCopy)
+	}
+}
+
+func forgetRemovedNetworkDevices(targets []string) {
+	networkDevicesMu.Lock()
+	defer networkDevicesMu.Unlock()
+
+	for target := range networkDevices {
+		if !slices.Contains(targets, target) {
+			delete(networkDevices, target)
+			if device, ok := Registry.Get(target); ok {
+				device.SetNetworkConnection(nil)
+			}
+		}
+	}
+}
+
+// Get the connection state of all configured network devices ordered by target
+func GetNetworkDevices() []models.NetworkConnectionStatus {
+	networkDevicesMu.Lock()
+	defer networkDevicesMu.Unlock()
+
+	statuses := []models.NetworkConnectionStatus{}
+	for _, status := range networkDevices {
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Target < statuses[j].Target
+	})
+
+	return statuses
+}
@@ -0,0 +1,96 @@
+package devices
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"GADS/common/models"
+	"GADS/provider/logger"
+)
+
+const (
+	// Time for the device to drop off the host after the reboot was requested, fake devices never do
+	rebootDisconnectTimeout = 30 * time.Second
+	// Time for the device to be discovered again after it dropped off
+	rebootReconnectTimeout = 10 * time.Minute
+)
+
+// Reboot a live device, it is reset and set up again after it reconnected
+func RebootDevice(device *models.Device) error {
+	driver, err := GetDeviceDriver(device)
+	if err != nil {
+		return err
+	}
+	if state := device.State(); state != models.DeviceStateLive {
+		return fmt.Errorf("Only devices in `live` state can be rebooted, current state is `%s`", state)
+	}
+
+	device.Mutex.Lock()
+	defer device.Mutex.Unlock()
+	// The state might have changed since the check, the transition fails in that case
+	err = device.TransitionState(models.DeviceStateResetting, "reboot requested")
+	if err != nil {
+		return err
+	}
+	device.CtxCancel()
+	teardownDevice(device)
+
+	// Keep the device from being set up again before it went through the reboot
+	device.StateMu.Lock()
+	device.Rebooting = true
+	device.StateMu.Unlock()
+
+	rebootErr := driver.Reboot(device)
+	setDeviceState(device, models.DeviceStateInit, "device rebooted")
+	if rebootErr != nil {
+		clearRebooting(device)
+		return rebootErr
+	}
+
+	logger.ProviderLogger.LogInfo("device_reboot", fmt.Sprintf("Rebooted device `%s`, it will be set up again after it reconnects", device.UDID))
+	go waitForReboot(device)
+	return nil
+}
+
+// Wait for the device to disconnect and connect again, then allow its setup
+func waitForReboot(device *models.Device) {
+	defer clearRebooting(device)
+
+	deadline := time.Now().Add(rebootDisconnectTimeout)
+	for device.IsConnected() {
+		if time.Now().After(deadline) {
+			return
+		}
+		time.Sleep(1 * time.Second)
+	}
+
+	deadline = time.Now().Add(rebootReconnectTimeout)
+	for !device.IsConnected() {
+		if time.Now().After(deadline) {
+			logger.ProviderLogger.LogWarn("device_reboot", fmt.Sprintf("Device `%s` did not reconnect %v after the reboot", device.UDID, rebootReconnectTimeout))
+			return
+		}
+		time.Sleep(1 * time.Second)
+	}
+}
+
+func clearRebooting(device *models.Device) {
+	device.StateMu.Lock()
+	device.Rebooting = false
+	device.StateMu.Unlock()
+}
+
+func rebootAndroidDevice(device *models.Device) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "adb", "-s", device.UDID, "reboot")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("Failed executing `%s` - %s: %s", cmd.Args, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package devices
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"GADS/common/db"
+	"GADS/common/models"
+	"GADS/provider/config"
+	"GADS/provider/logger"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	deviceIdentifyTimeout     = 30 * time.Second
+	registrationRetryInterval = 60 * time.Second
+)
+
+var registrationMu sync.Mutex
+var registeringDevices = make(map[string]bool)
+var failedRegistrations = make(map[string]time.Time)
+
+// Devices registered in the DB are handed over to the device update loop which adds them to the registry
+var registeredDevices = make(chan *models.Device, 10)
+
+// Create DB records with `pending` usage for connected devices that are not registered yet
+// Pending devices are not set up and not exposed until an admin approves them by changing their usage
+func registerNewDevices(connectedDevices map[string]DeviceDriver) {
+	if !config.ProviderConfig().AutoRegisterDevices {
+		return
+	}
+
+	registrationMu.Lock()
+	defer registrationMu.Unlock()
+
+	for udid, driver := range connectedDevices {
+		if _, ok := Registry.Get(udid); ok || registeringDevices[udid] {
+			continue
+		}
+		if failedAt, ok := failedRegistrations[udid]; ok && time.Since(failedAt) < registrationRetryInterval {
+			continue
+		}
+		registeringDevices[udid] = true
+		go registerDevice(driver, udid)
+	}
+}
+
+func registerDevice(driver DeviceDriver, udid string) {
+	err := identifyAndStoreDevice(driver, udid)
+
+	registrationMu.Lock()
+	defer registrationMu.Unlock()
+	delete(registeringDevices, udid)
+	if err != nil {
+		failedRegistrations[udid] = time.Now()
+		logger.ProviderLogger.LogError("device_registration", fmt.Sprintf("Failed to register device `%s` - %s", udid, err))
+		return
+	}
+	delete(failedRegistrations, udid)
+}
+
+func identifyAndStoreDevice(driver DeviceDriver, udid string) error {
+	// Devices registered by another provider or added by an admin in the meantime are not taken over
+	existingDevice, err := getDBDevice(udid)
+	if err == nil {
+		if existingDevice.Provider != config.ProviderConfig().Nickname {
+			return fmt.Errorf("Device is already registered for provider `%s`", existingDevice.Provider)
+		}
+		registeredDevices <- existingDevice
+		return nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return fmt.Errorf("Failed to check if device exists in DB - %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), deviceIdentifyTimeout)
+	defer cancel()
+
+	device := &models.Device{
+		UDID:     udid,
+		OS:       driver.Name(),
+		Provider: config.ProviderConfig().Nickname,
+		Usage:    "pending",
+		Tags:     []string{},
+		Context:  ctx,
+	}
+	err = driver.Identify(device)
+	if err != nil {
+		return err
+	}
+	device.Context = nil
+
+	err = db.UpsertDeviceDB(device)
+	if err != nil {
+		return fmt.Errorf("Failed to upsert device in DB - %s", err)
+	}
+	logger.ProviderLogger.LogInfo("device_registration", fmt.Sprintf("Registered new %s device `%s` - %s %s, it is pending approval", device.OS, udid, device.Name, device.OSVersion))
+
+	registeredDevices <- device
+	return nil
+}
+
+// Add the devices registered since the last update to the provider devices
+func addRegisteredDevices() {
+	for {
+		select {
+		case device := <-registeredDevices:
+			if _, ok := Registry.Get(device.UDID); ok {
+				continue
+			}
+			prepareDevice(device)
+			Registry.Add(device)
+		default:
+			return
+		}
+	}
+}
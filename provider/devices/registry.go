@@ -0,0 +1,75 @@
+package devices
+
+import (
+	"slices"
+	"strings"
+	"sync"
+
+	"GADS/common/models"
+)
+
+// Devices of the provider, read by the HTTP handlers, the hub connection and the DB updates
+// while the devices update loop adds and removes them
+type DeviceRegistry struct {
+	mu      sync.RWMutex
+	devices map[string]*models.Device
+}
+
+var Registry = &DeviceRegistry{devices: make(map[string]*models.Device)}
+
+// Get a provider device by UDID
+func (r *DeviceRegistry) Get(udid string) (*models.Device, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	device, ok := r.devices[udid]
+	return device, ok
+}
+
+// All provider devices sorted by UDID
+// The slice is a copy so the registry can change while it is used, the devices themselves are shared
+func (r *DeviceRegistry) All() []*models.Device {
+	r.mu.RLock()
+	devices := make([]*models.Device, 0, len(r.devices))
+	for _, device := range r.devices {
+		devices = append(devices, device)
+	}
+	r.mu.RUnlock()
+
+	slices.SortFunc(devices, func(a, b *models.Device) int {
+		return strings.Compare(a.UDID, b.UDID)
+	})
+	return devices
+}
+
+func (r *DeviceRegistry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.devices)
+}
+
+// Add a device unless a device with the same UDID is already registered, returns false in that case
+func (r *DeviceRegistry) Add(device *models.Device) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.devices[device.UDID]; ok {
+		return false
+	}
+	r.devices[device.UDID] = device
+	return true
+}
+
+func (r *DeviceRegistry) Remove(udid string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.devices, udid)
+}
+
+// Replace all devices, used when loading the provider devices from the DB
+func (r *DeviceRegistry) Replace(devices map[string]*models.Device) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.devices = make(map[string]*models.Device, len(devices))
+	for udid, device := range devices {
+		r.devices[udid] = device
+	}
+}
@@ -0,0 +1,84 @@
+package devices
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"GADS/common/models"
+)
+
+func TestRegistryAddDuplicate(t *testing.T) {
+	registry := &DeviceRegistry{devices: make(map[string]*models.Device)}
+
+	if !registry.Add(&models.Device{UDID: "a"}) {
+		t.Fatalf("Expected adding a new device to succeed")
+	}
+	if registry.Add(&models.Device{UDID: "a"}) {
+		t.Errorf("Expected adding a device with an existing UDID to fail")
+	}
+	if registry.Len() != 1 {
+		t.Errorf("Expected 1 device, got %d", registry.Len())
+	}
+}
+
+func TestRegistryConcurrentAccess(t *testing.T) {
+	registry := &DeviceRegistry{devices: make(map[string]*models.Device)}
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				udid := fmt.Sprintf("device-%d-%d", i, j%10)
+				registry.Add(&models.Device{UDID: udid})
+				if device, ok := registry.Get(udid); ok && device.UDID != udid {
+					t.Errorf("Got device `%s` for UDID `%s`", device.UDID, udid)
+				}
+				devices := registry.All()
+				if !slices.IsSortedFunc(devices, func(a, b *models.Device) int {
+					return strings.Compare(a.UDID, b.UDID)
+				}) {
+					t.Errorf("Expected devices sorted by UDID")
+				}
+				for _, device := range devices {
+					device.State()
+				}
+				if j%3 == 0 {
+					registry.Remove(udid)
+				}
+			}
+		}(i)
+	}
+
+	// Devices shared through the registry are transitioned and serialized concurrently
+	shared := &models.Device{UDID: "shared"}
+	registry.Add(shared)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				device, ok := registry.Get("shared")
+				if !ok {
+					t.Errorf("Expected the shared device to stay registered")
+					return
+				}
+				device.TransitionState(models.DeviceStatePreparing, "test")
+				device.TransitionState(models.DeviceStateInit, "test")
+				device.SetConnected(j%2 == 0)
+				if _, err := device.MarshalJSON(); err != nil {
+					t.Errorf("Failed to marshal device - %s", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if registry.Len() != len(registry.All()) {
+		t.Errorf("Expected Len and All to agree")
+	}
+}
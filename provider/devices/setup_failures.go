@@ -0,0 +1,82 @@
+package devices
+
+import (
+	"fmt"
+	"time"
+
+	"GADS/common/models"
+	"GADS/provider/logger"
+)
+
+const (
+	setupBackoffInitial     = 10 * time.Second
+	setupBackoffMax         = 10 * time.Minute
+	quarantineAfterFailures = 5
+	// Devices that stay live this long are considered healthy again and their failed setups are forgotten
+	setupStableDuration = 5 * time.Minute
+)
+
+// Log why the device setup or one of the device processes failed and reset the device
+// The failure delays the next setup with exponential backoff and quarantines the device if it keeps failing
+func failDeviceSetup(device *models.Device, event string, message string) {
+	logger.ProviderLogger.LogError(event, message)
+	resetDevice(device, message)
+}
+
+func recordSetupFailure(device *models.Device, message string) {
+	device.FailSetupStep(message)
+
+	device.StateMu.Lock()
+	device.SetupFailures++
+	device.LastSetupError = message
+	failures := device.SetupFailures
+	if failures >= quarantineAfterFailures {
+		device.Quarantined = true
+		device.NextSetupAttempt = 0
+	} else {
+		backoff := min(setupBackoffInitial<<(failures-1), setupBackoffMax)
+		device.NextSetupAttempt = time.Now().Add(backoff).UnixMilli()
+	}
+	nextSetupAttempt := device.NextSetupAttempt
+	device.StateMu.Unlock()
+
+	if failures >= quarantineAfterFailures {
+		logger.ProviderLogger.LogError("device_setup", fmt.Sprintf("Setup of device `%s` failed %d times in a row, quarantining it until an admin clears the quarantine", device.UDID, failures))
+		return
+	}
+	logger.ProviderLogger.LogWarn("device_setup", fmt.Sprintf("Setup of device `%s` failed %d times in a row, retrying in %v", device.UDID, failures, time.Until(time.UnixMilli(nextSetupAttempt)).Round(time.Second)))
+}
+
+// Check if a device can be set up now - it is not quarantined or rebooting and its backoff after a failure passed
+func setupAllowed(device *models.Device) bool {
+	device.StateMu.RLock()
+	defer device.StateMu.RUnlock()
+	return !device.Quarantined && !device.Rebooting && time.Now().UnixMilli() >= device.NextSetupAttempt
+}
+
+// Forget the failed setups of a device that is live for a while
+func forgetStableSetupFailures(device *models.Device) {
+	if device.State() != models.DeviceStateLive || time.Since(time.UnixMilli(device.StateChangedAt())) < setupStableDuration {
+		return
+	}
+
+	device.StateMu.Lock()
+	defer device.StateMu.Unlock()
+	device.SetupFailures = 0
+	device.LastSetupError = ""
+	device.NextSetupAttempt = 0
+}
+
+// Clear the quarantine of a device so it is set up again on the next devices update
+func ClearQuarantine(device *models.Device) error {
+	device.StateMu.Lock()
+	defer device.StateMu.Unlock()
+	if !device.Quarantined {
+		return fmt.Errorf("Device `%s` is not quarantined", device.UDID)
+	}
+	device.Quarantined = false
+	device.SetupFailures = 0
+	device.NextSetupAttempt = 0
+	logger.ProviderLogger.LogInfo("device_setup", fmt.Sprintf("Cleared quarantine of device `%s`, it will be set up again", device.UDID))
+	return nil
+}
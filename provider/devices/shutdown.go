@@ -0,0 +1,134 @@
+package devices
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"GADS/common/models"
+	"GADS/provider/logger"
+	"GADS/provider/providerutil"
+)
+
+// How long device processes get to exit after being interrupted before they are killed
+const processStopTimeout = 5 * time.Second
+
+var shuttingDown atomic.Bool
+
+// Long running device processes like Appium, Selenium Grid nodes and WebDriverAgent
+var deviceProcesses sync.WaitGroup
+
+// Running processes per device UDID and the devices whose ports are released once their processes exit
+var (
+	runningProcessesMu  sync.Mutex
+	runningProcesses    = make(map[string]int)
+	pendingPortReleases = make(map[string]bool)
+)
+
+// Track a process of the device from before it is started until it exited, call the returned func after waiting for it
+func trackDeviceProcess(device *models.Device) func() {
+	runningProcessesMu.Lock()
+	defer runningProcessesMu.Unlock()
+	runningProcesses[device.UDID]++
+	deviceProcesses.Add(1)
+
+	return func() {
+		runningProcessesMu.Lock()
+		defer runningProcessesMu.Unlock()
+		runningProcesses[device.UDID]--
+		if runningProcesses[device.UDID] == 0 {
+			delete(runningProcesses, device.UDID)
+			if pendingPortReleases[device.UDID] {
+				delete(pendingPortReleases, device.UDID)
+				providerutil.ReleaseDevicePorts(device.UDID)
+			}
+		}
+		deviceProcesses.Done()
+	}
+}
+
+// Release the ports of a device right away or after its last process exited, so ports are not reused while a process still holds them
+func releaseDevicePortsWhenStopped(udid string) {
+	runningProcessesMu.Lock()
+	defer runningProcessesMu.Unlock()
+	if runningProcesses[udid] == 0 {
+		providerutil.ReleaseDevicePorts(udid)
+		return
+	}
+	pendingPortReleases[udid] = true
+}
+
+// If none of the processes of a previous setup of the device are still running
+func deviceProcessesStopped(udid string) bool {
+	runningProcessesMu.Lock()
+	defer runningProcessesMu.Unlock()
+	return runningProcesses[udid] == 0
+}
+
+// Interrupt a device process when the device context is cancelled so it can clean up, kill it if it does not exit in time
+// Windows does not support sending interrupts to processes so they are killed right away there
+func stopProcessGracefully(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		if runtime.GOOS == "windows" {
+			return cmd.Process.Kill()
+		}
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = processStopTimeout
+}
+
+// Stop all devices and release the host resources used by them before the provider exits
+// The hub is notified first so it stops using the devices right away
+func Shutdown(ctx context.Context) {
+	shuttingDown.Store(true)
+	logger.ProviderLogger.LogInfo("provider_shutdown", "Shutting down provider")
+
+	notifyHubShutdown()
+
+	for _, device := range Registry.All() {
+		if device.CtxCancel == nil {
+			continue
+		}
+		if device.State() == models.DeviceStateLive {
+			if driver, err := GetDeviceDriver(device); err == nil {
+				driver.Stop(device)
+			}
+		}
+		logger.ProviderLogger.LogInfo("provider_shutdown", fmt.Sprintf("Stopping device `%s`", device.UDID))
+		resetLocalDevice(device)
+		device.SetConnected(false)
+	}
+
+	processesDone := make(chan struct{})
+	go func() {
+		deviceProcesses.Wait()
+		close(processesDone)
+	}()
+	select {
+	case <-processesDone:
+		logger.ProviderLogger.LogInfo("provider_shutdown", "All device processes stopped")
+	case <-ctx.Done():
+		logger.ProviderLogger.LogWarn("provider_shutdown", "Timed out waiting for device processes to stop")
+	}
+
+	emulatorsMu.Lock()
+	startedEmulators := make([]string, 0, len(runningEmulators))
+	for avd := range runningEmulators {
+		startedEmulators = append(startedEmulators, avd)
+	}
+	emulatorsMu.Unlock()
+	for _, avd := range startedEmulators {
+		err := StopEmulator(avd)
+		if err != nil {
+			logger.ProviderLogger.LogWarn("provider_shutdown", fmt.Sprintf("Failed to stop emulator `%s` - %s", avd, err))
+		}
+	}
+
+	providerutil.RemoveAdbForwardedPorts()
+	logger.ProviderLogger.LogInfo("provider_shutdown", "Provider shut down")
+}
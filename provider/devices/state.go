@@ -0,0 +1,25 @@
+package devices
+
+import (
+	"fmt"
+
+	"GADS/common/models"
+	"GADS/provider/logger"
+)
+
+// Move a device to a new provider state, returns false if the transition is not allowed from the current state
+// An invalid transition usually means the device was reset or disconnected in the meantime, e.g. a setup finishing after it failed
+func setDeviceState(device *models.Device, to string, reason string) bool {
+	err := device.TransitionState(to, reason)
+	if err != nil {
+		logger.ProviderLogger.LogWarn("device_state", err.Error())
+		return false
+	}
+	logger.ProviderLogger.LogDebug("device_state", fmt.Sprintf("Device `%s` moved to `%s` - %s", device.UDID, to, reason))
+	// Every path that stops a device ends in `init` so its ports are released here instead of on each path
+	// Processes of the device may still be exiting after its context was cancelled, their ports are released once they did
+	if to == models.DeviceStateInit {
+		releaseDevicePortsWhenStopped(device.UDID)
+	}
+	return true
+}
@@ -0,0 +1,263 @@
+package devices
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"GADS/common/models"
+	"GADS/provider/config"
+	"GADS/provider/logger"
+
+	"github.com/danielpaulus/go-ios/ios"
+	"github.com/danielpaulus/go-ios/ios/diagnostics"
+)
+
+const (
+	telemetryInterval       = 30 * time.Second
+	telemetryCommandTimeout = 10 * time.Second
+)
+
+// UDIDs of the devices with a running telemetry collection so a hanging device does not pile up collections
+var telemetryRunning sync.Map
+
+// Periodically collect the telemetry of the live devices and take devices breaking the thresholds out of rotation
+func monitorDevicesTelemetry() {
+	ticker := time.NewTicker(telemetryInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if shuttingDown.Load() {
+			return
+		}
+		for _, device := range Registry.All() {
+			if device.State() != models.DeviceStateLive {
+				continue
+			}
+			if _, running := telemetryRunning.LoadOrStore(device.UDID, true); running {
+				continue
+			}
+			go func() {
+				defer telemetryRunning.Delete(device.UDID)
+				collectDeviceTelemetry(device)
+			}()
+		}
+	}
+}
+
+func collectDeviceTelemetry(device *models.Device) {
+	driver, err := GetDeviceDriver(device)
+	if err != nil {
+		logger.ProviderLogger.LogError("device_telemetry", err.Error())
+		return
+	}
+
+	sample, err := driver.Telemetry(device)
+	if err != nil {
+		device.Logger.LogWarn("device_telemetry", fmt.Sprintf("Failed to collect telemetry - %s", err))
+		return
+	}
+	sample.Timestamp = time.Now().UnixMilli()
+
+	var reasons []string
+	if thresholds := config.ProviderConfig().TelemetryThresholds; thresholds != nil {
+		reasons = thresholds.Check(sample)
+	}
+	if device.AddTelemetry(sample, reasons) {
+		if len(reasons) > 0 {
+			logger.ProviderLogger.LogWarn("device_telemetry", fmt.Sprintf("Device `%s` is taken out of rotation - %s", device.UDID, strings.Join(reasons, ", ")))
+		} else {
+			logger.ProviderLogger.LogInfo("device_telemetry", fmt.Sprintf("Device `%s` is back within the telemetry thresholds and in rotation again", device.UDID))
+		}
+	}
+}
+
+// Collect the battery, storage and memory of an Android device with adb
+// The battery data is required, storage and memory are left empty if they cannot be read
+func telemetryAndroid(device *models.Device) (models.DeviceTelemetry, error) {
+	ctx, cancel := context.WithTimeout(device.Context, telemetryCommandTimeout)
+	defer cancel()
+
+	sample := models.DeviceTelemetry{BatteryLevel: -1}
+	output, err := exec.CommandContext(ctx, "adb", "-s", device.UDID, "shell", "dumpsys", "battery").Output()
+	if err != nil {
+		return sample, fmt.Errorf("Failed to get battery state with adb - %s", err)
+	}
+	parseAndroidBattery(string(output), &sample)
+
+	output, err = exec.CommandContext(ctx, "adb", "-s", device.UDID, "shell", "df", "-k", "/data").Output()
+	if err == nil {
+		parseAndroidStorage(string(output), &sample)
+	}
+
+	output, err = exec.CommandContext(ctx, "adb", "-s", device.UDID, "shell", "cat", "/proc/meminfo").Output()
+	if err == nil {
+		parseAndroidMemory(string(output), &sample)
+	}
+
+	return sample, nil
+}
+
+// Parse the output of `dumpsys battery`, the temperature is reported in tenths of °C
+func parseAndroidBattery(output string, sample *models.DeviceTelemetry) {
+	for _, line := range strings.Split(output, "\n") {
+		key, value, found := strings.Cut(strings.TrimSpace(line), ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "level":
+			if level, err := strconv.Atoi(value); err == nil {
+				sample.BatteryLevel = level
+			}
+		case "temperature":
+			if temperature, err := strconv.Atoi(value); err == nil {
+				sample.Temperature = float64(temperature) / 10
+			}
+		case "AC powered", "USB powered", "Wireless powered", "Dock powered":
+			if value == "true" {
+				sample.Charging = true
+			}
+		}
+	}
+}
+
+// Parse the output of `df -k /data`, the second line has the size and the available space in KB
+func parseAndroidStorage(output string, sample *models.DeviceTelemetry) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) < 2 {
+		return
+	}
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) < 4 {
+		return
+	}
+	total, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return
+	}
+	available, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return
+	}
+	sample.TotalStorage = total * 1024
+	sample.FreeStorage = available * 1024
+}
+
+// Parse /proc/meminfo, the values are in KB
+func parseAndroidMemory(output string, sample *models.DeviceTelemetry) {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		value, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "MemTotal:":
+			sample.TotalMemory = value * 1024
+		case "MemAvailable:":
+			sample.FreeMemory = value * 1024
+		}
+	}
+}
+
+// Collect the battery, temperature and storage of an iOS device with go-ios lockdown values and diagnostics
+// iOS does not report the free memory of the device
+// go-ios calls cannot be cancelled so the connections are closed when the collection times out to unblock them
+func telemetryIOS(device *models.Device) (models.DeviceTelemetry, error) {
+	ctx, cancel := context.WithTimeout(device.Context, telemetryCommandTimeout)
+	defer cancel()
+
+	sample := models.DeviceTelemetry{BatteryLevel: -1}
+
+	lockdownConn, err := ios.ConnectLockdownWithSession(device.GoIOSDeviceEntry)
+	if err != nil {
+		return sample, fmt.Errorf("Failed to connect to lockdown with go-ios - %s", err)
+	}
+	stopLockdownClose := context.AfterFunc(ctx, func() { lockdownConn.Close() })
+	defer func() {
+		if stopLockdownClose() {
+			lockdownConn.Close()
+		}
+	}()
+
+	batteryValues, err := lockdownConn.GetValueForDomain("", "com.apple.mobile.battery")
+	if err != nil {
+		return sample, fmt.Errorf("Failed to get battery state with go-ios - %s", telemetryContextError(ctx, err))
+	}
+	if battery, ok := batteryValues.(map[string]interface{}); ok {
+		if level, ok := plistInt(battery["BatteryCurrentCapacity"]); ok {
+			sample.BatteryLevel = int(level)
+		}
+		sample.Charging, _ = battery["BatteryIsCharging"].(bool)
+	}
+
+	diskValues, err := lockdownConn.GetValueForDomain("", "com.apple.disk_usage")
+	if err == nil {
+		if disk, ok := diskValues.(map[string]interface{}); ok {
+			sample.TotalStorage, _ = plistInt(disk["TotalDataCapacity"])
+			sample.FreeStorage, _ = plistInt(disk["TotalDataAvailable"])
+		}
+	}
+
+	// The battery temperature is only available from the IORegistry, in hundredths of °C
+	diagnosticsConn, err := diagnostics.New(device.GoIOSDeviceEntry)
+	if err == nil {
+		stopDiagnosticsClose := context.AfterFunc(ctx, func() { diagnosticsConn.Close() })
+		defer func() {
+			if stopDiagnosticsClose() {
+				diagnosticsConn.Close()
+			}
+		}()
+		response, err := diagnosticsConn.IORegEntryQuery("AppleSmartBattery")
+		if err == nil {
+			if temperature, ok := plistInt(nestedPlistValue(response, "Diagnostics", "IORegistry", "Temperature")); ok {
+				sample.Temperature = float64(temperature) / 100
+			}
+		}
+	}
+
+	return sample, nil
+}
+
+// The error of the timed out or cancelled collection instead of the error of the connection closed because of it
+func telemetryContextError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+func nestedPlistValue(value interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = values[key]
+	}
+	return value
+}
+
+// Plist integers are decoded with different types depending on their size and sign
+func plistInt(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint64:
+		return int64(v), true
+	case float64:
+		return int64(v), true
+	}
+	return 0, false
+}
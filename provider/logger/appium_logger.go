@@ -84,9 +84,9 @@ func (logger *AppiumLogger) Log(device *models.Device, logLine string) {
 			sessionId = strings.Split(firstSplitValue, " ")[0]
 		}
 		if len(sessionId) != 36 {
-			device.AppiumSessionID = ""
+			device.SetAppiumSession("")
 		} else {
-			device.AppiumSessionID = sessionId
+			device.SetAppiumSession(sessionId)
 		}
 	}
 
@@ -95,12 +95,12 @@ func (logger *AppiumLogger) Log(device *models.Device, logLine string) {
 	if strings.Contains(logLine, "Removing session") {
 		// firstSplit := strings.Split(logLine, "Removing session ")[1]
 		// removedSessionId := strings.Split(firstSplit, " ")[0]
-		device.AppiumSessionID = ""
+		device.SetAppiumSession("")
 	}
 
 	// Set the log session ID to the local device session ID
 	// This provides additional info as well as allows us to filter Appium logs per session
-	logData.SessionID = device.AppiumSessionID
+	logData.SessionID = device.AppiumSession()
 
 	// Log to file
 	err := appiumLogToFile(logger, logData)
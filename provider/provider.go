@@ -9,12 +9,15 @@ import (
 	"GADS/provider/providerutil"
 	"GADS/provider/router"
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
-	"runtime"
+	"os/signal"
 	"sort"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/spf13/pflag"
@@ -22,136 +25,205 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func StartProvider(flags *pflag.FlagSet) {
-	logLevel, _ := flags.GetString("log-level")
-	nickname, _ := flags.GetString("nickname")
-	mongoDb, _ := flags.GetString("mongo-db")
-	providerFolder, _ := flags.GetString("provider-folder")
-	hubAddress, _ := flags.GetString("hub")
-
-	if nickname == "" {
-		log.Fatalf("Please provide valid provider instance nickname via the --nickname flag, e.g. --nickname=Provider1")
-	}
+const (
+	// How long the devices get to stop on shutdown
+	providerShutdownTimeout = 30 * time.Second
+	// How long to wait for running requests like device streams before closing the HTTP server
+	httpServerShutdownTimeout = 5 * time.Second
+)
 
-	if hubAddress == "" {
-		log.Fatalf("Please provide valid GADS hub instance address via the --hub flag, e.g. --hub=http://192.168.1.6:10000")
-	}
+func StartProvider(flags *pflag.FlagSet) {
+	// Problems with the settings, the configuration and the host are collected and reported together
+	settings, errs := config.LoadSettings(flags)
 
-	if providerFolder == "." {
-		providerFolder = fmt.Sprintf("./%s", nickname)
+	if settings.ProviderFolder == "." {
+		settings.ProviderFolder = fmt.Sprintf("./%s", settings.Nickname)
 	}
 
 	fmt.Println("Preparing...")
 
 	// Create the provider folder if needed
-	err := os.MkdirAll(providerFolder, os.ModePerm)
-	if err != nil {
-		log.Fatalf("Failed to create provider folder `%s` - %s", providerFolder, err)
+	folderErr := os.MkdirAll(settings.ProviderFolder, os.ModePerm)
+	if folderErr != nil {
+		errs = append(errs, fmt.Errorf("Failed to create provider folder `%s` - %s", settings.ProviderFolder, folderErr))
 	}
 
 	// Create a connection to Mongo
-	db.InitMongoClient(mongoDb)
+	db.InitMongoClient(settings.MongoDB)
 	defer db.MongoCtxCancel()
 	// Set up the provider configuration
-	config.SetupConfig(nickname, providerFolder, hubAddress)
-	config.ProviderConfig.OS = runtime.GOOS
+	errs = append(errs, config.SetupConfig(settings)...)
 	// Defer closing the Mongo connection on provider stopped
 	defer db.CloseMongoConn()
 
-	// Setup logging for the provider itself
-	logger.SetupLogging(logLevel)
-	logger.ProviderLogger.LogInfo("provider_setup", fmt.Sprintf("Starting provider on port `%v`", config.ProviderConfig.Port))
+	// The provider log and the host checks need the provider folder
+	if folderErr == nil {
+		// Setup logging for the provider itself
+		logger.SetupLogging(settings.LogLevel)
+		logger.ProviderLogger.LogInfo("provider_setup", fmt.Sprintf("Starting provider on port `%v`", config.ProviderConfig().Port))
+		if overridden := config.OverriddenKeys(); len(overridden) > 0 {
+			logger.ProviderLogger.LogInfo("provider_setup", fmt.Sprintf("Provider configuration values %v are taken from the config file or env vars instead of the DB", overridden))
+		}
+
+		// Check the host dependencies of the configuration and report all problems together with the configuration errors
+		errs = append(errs, checkHostDependencies()...)
+		for _, err := range errs {
+			logger.ProviderLogger.LogError("provider_setup", err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		exitWithErrors(errs)
+	}
+
+	err := config.RegisterProvider()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if config.ProviderConfig().ProvideAndroid {
+		// Try to remove potentially hanging ports forwarded by adb
+		providerutil.RemoveAdbForwardedPorts()
+	}
+
+	// Stop the provider gracefully on Ctrl+C or when its service is stopped
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Start a goroutine that will start updating devices on provider start
+	go devices.Listener()
+
+	// Start the provider server
+	server := startHTTPServer(ctx)
+
+	<-ctx.Done()
+	// A second signal stops the provider right away
+	stop()
+	shutdownProvider(server)
+}
+
+// Stop all devices, notify the hub and stop the HTTP server
+func shutdownProvider(server *http.Server) {
+	fmt.Println("Shutting down, press Ctrl+C again to stop immediately...")
 
-	logger.ProviderLogger.LogInfo("provider_setup", "Checking if Appium is installed and available on the host")
-	if !providerutil.AppiumAvailable() {
-		log.Fatal("Appium is not available, set it up on the host as explained in the readme")
+	ctx, cancel := context.WithTimeout(context.Background(), providerShutdownTimeout)
+	defer cancel()
+	devices.Shutdown(ctx)
+
+	// Device streams are long lived requests, don't wait for them for long
+	serverCtx, serverCancel := context.WithTimeout(context.Background(), httpServerShutdownTimeout)
+	defer serverCancel()
+	err := server.Shutdown(serverCtx)
+	if err != nil {
+		server.Close()
+	}
+	fmt.Println("Provider stopped")
+}
+
+// Check that the host has everything needed for the provider configuration
+// All problems are returned at once so they can be fixed together
+func checkHostDependencies() []error {
+	var errs []error
+
+	// Fake devices serve their own Appium-like endpoint, Appium is needed only for real devices
+	if config.ProviderConfig().ProvideAndroid || config.ProviderConfig().ProvideIOS {
+		logger.ProviderLogger.LogInfo("provider_setup", "Checking if Appium is installed and available on the host")
+		if !providerutil.AppiumAvailable() {
+			errs = append(errs, fmt.Errorf("Appium is not available, set it up on the host as explained in the readme"))
+		}
 	}
 
 	// Finalize grid configuration if Selenium Grid usage enabled
-	if config.ProviderConfig.UseSeleniumGrid {
-		err = config.SetupSeleniumJar()
+	if config.ProviderConfig().UseSeleniumGrid {
+		err := config.SetupSeleniumJar()
 		if err != nil {
-			log.Fatalf("Selenium Grid connection is enabled but there is something wrong with providing the selenium jar file from MongoDB - %s", err)
+			errs = append(errs, fmt.Errorf("Selenium Grid connection is enabled but there is something wrong with providing the selenium jar file from MongoDB - %s", err))
 		}
 	}
 
 	// If running on macOS and iOS device provisioning is enabled
-	if config.ProviderConfig.OS == "darwin" && config.ProviderConfig.ProvideIOS {
+	if config.ProviderConfig().OS == "darwin" && config.ProviderConfig().ProvideIOS {
 		logger.ProviderLogger.LogInfo("provider_setup", "Provider runs on macOS and is set up to provide iOS devices")
 		// Add a trailing slash to WDA repo folder if its missing
 		// To avoid issues with the configuration
 		logger.ProviderLogger.LogDebug("provider_setup", "Handling trailing slash of provided WebDriverAgent repo path if needed")
-		if !strings.HasSuffix(config.ProviderConfig.WdaRepoPath, "/") {
+		if !strings.HasSuffix(config.ProviderConfig().WdaRepoPath, "/") {
 			logger.ProviderLogger.LogDebug("provider_setup", "Provided WebDriverAgent repo path has no trailing slash, adding it")
-			config.ProviderConfig.WdaRepoPath = fmt.Sprintf("%s/", config.ProviderConfig.WdaRepoPath)
+			config.ProviderConfig().WdaRepoPath = fmt.Sprintf("%s/", config.ProviderConfig().WdaRepoPath)
 		}
 
 		// Check if the provided WebDriverAgent repo path exists
 		logger.ProviderLogger.LogDebug("provider_setup", "Checking if provided WebDriverAgent repo path exists on the host")
-		_, err := os.Stat(config.ProviderConfig.WdaRepoPath)
-		if err != nil {
-			log.Fatalf("`%s` does not exist, you need to provide valid path to the WebDriverAgent repo in the provider configuration", config.ProviderConfig.WdaRepoPath)
+		_, err := os.Stat(config.ProviderConfig().WdaRepoPath)
+		repoExists := err == nil
+		if !repoExists {
+			errs = append(errs, fmt.Errorf("`%s` does not exist, you need to provide valid path to the WebDriverAgent repo in the provider configuration", config.ProviderConfig().WdaRepoPath))
 		}
 
 		// Check if xcodebuild is available - Xcode and command line tools should be installed
-		if !providerutil.XcodebuildAvailable() {
-			log.Fatal("xcodebuild is not available, you need to set it up on the host as explained in the readme")
+		xcodebuildAvailable := providerutil.XcodebuildAvailable()
+		if !xcodebuildAvailable {
+			errs = append(errs, fmt.Errorf("xcodebuild is not available, you need to set it up on the host as explained in the readme"))
 		}
 
 		// Build the WebDriverAgent using xcodebuild from the provided repo path
-		err = providerutil.BuildWebDriverAgent()
-		if err != nil {
-			log.Fatalf("Could not build WebDriverAgent for testing - %s", err)
+		if repoExists && xcodebuildAvailable {
+			err = providerutil.BuildWebDriverAgent()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("Could not build WebDriverAgent for testing - %s", err))
+			}
 		}
 	}
 
-	if config.ProviderConfig.ProvideIOS {
+	if config.ProviderConfig().ProvideIOS {
 		// If on Linux or Windows and iOS devices provision enabled check for WebDriverAgent.ipa/app
-		if config.ProviderConfig.OS != "darwin" {
+		if config.ProviderConfig().OS != "darwin" {
 			logger.ProviderLogger.LogInfo(
 				"provider_setup",
 				"Provider runs on Linux/Windows and is set up to provide iOS devices, checking if prepared WebDriverAgent binary exists in the provider folder as explained in the readme")
-			err := configureWebDriverBinary(providerFolder)
+			err := configureWebDriverBinary(config.ProviderConfig().ProviderFolder)
 			if err != nil {
-				log.Fatalf("You should put signed WebDriverAgent.ipa/app file in the provider folder `%s` as explained in the readme", providerFolder)
+				errs = append(errs, fmt.Errorf("You should put signed WebDriverAgent.ipa/app file in the provider folder `%s` as explained in the readme", config.ProviderConfig().ProviderFolder))
 			}
 		}
 	}
 
 	// If we want to provide Android devices check if adb is available on PATH
-	if config.ProviderConfig.ProvideAndroid {
+	if config.ProviderConfig().ProvideAndroid {
 		if !providerutil.AdbAvailable() {
-			logger.ProviderLogger.LogError("provider", "adb is not available, you need to set up the host as explained in the readme")
-			fmt.Println("adb is not available, you need to set up the host as explained in the readme")
-			os.Exit(1)
+			errs = append(errs, fmt.Errorf("adb is not available, you need to set up the host as explained in the readme"))
 		}
-
-		// Try to remove potentially hanging ports forwarded by adb
-		providerutil.RemoveAdbForwardedPorts()
 	}
 
-	// Start a goroutine that will start updating devices on provider start
-	go devices.Listener()
+	return errs
+}
 
-	// Start the provider server
-	err = startHTTPServer()
-	if err != nil {
-		log.Fatal("HTTP server stopped")
+// Print all startup problems and exit
+func exitWithErrors(errs []error) {
+	fmt.Printf("Provider configuration has %d problem(s):\n", len(errs))
+	for _, err := range errs {
+		fmt.Printf("  - %s\n", err)
 	}
+	os.Exit(1)
 }
 
-func startHTTPServer() error {
+func startHTTPServer(ctx context.Context) *http.Server {
 	// Handle the endpoints
 	r := router.HandleRequests()
 	// Start periodically updating the provider data in the DB
-	go updateProviderInDB()
+	go updateProviderInDB(ctx)
 	// Start the provider
-	address := fmt.Sprintf("%s:%v", config.ProviderConfig.HostAddress, config.ProviderConfig.Port)
-	err := r.Run(address)
-	if err != nil {
-		return err
+	address := fmt.Sprintf("%s:%v", config.ProviderConfig().HostAddress, config.ProviderConfig().Port)
+	server := &http.Server{
+		Addr:    address,
+		Handler: r,
 	}
-	return fmt.Errorf("HTTP server stopped due to an unknown reason")
+	go func() {
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("HTTP server stopped - %s", err)
+		}
+	}()
+	return server
 }
 
 // Check for and set up WebDriverAgent.ipa/app binary in config
@@ -165,27 +237,29 @@ func configureWebDriverBinary(providerFolder string) error {
 		if os.IsNotExist(err) {
 			return err
 		}
-		config.ProviderConfig.WebDriverBinary = "WebDriverAgent.app"
+		config.ProviderConfig().WebDriverBinary = "WebDriverAgent.app"
 	} else {
-		config.ProviderConfig.WebDriverBinary = "WebDriverAgent.ipa"
+		config.ProviderConfig().WebDriverBinary = "WebDriverAgent.ipa"
 	}
 	return nil
 }
 
 // Periodically send current provider data updates to MongoDB
-func updateProviderInDB() {
+func updateProviderInDB(stopCtx context.Context) {
 	ctx, cancel := context.WithCancel(db.MongoCtx())
 	defer cancel()
 
-	for {
+	for stopCtx.Err() == nil {
 		coll := db.MongoClient().Database("gads").Collection("providers")
-		filter := bson.D{{Key: "nickname", Value: config.ProviderConfig.Nickname}}
+		filter := bson.D{{Key: "nickname", Value: config.ProviderConfig().Nickname}}
 
-		var providedDevices []models.Device
-		for _, mapDevice := range devices.DBDeviceMap {
-			providedDevices = append(providedDevices, *mapDevice)
+		var providedDevices []*models.Device
+		for _, mapDevice := range devices.Registry.All() {
+			providedDevices = append(providedDevices, mapDevice.Snapshot())
 		}
-		sort.Sort(models.ByUDID(providedDevices))
+		sort.Slice(providedDevices, func(i, j int) bool {
+			return providedDevices[i].UDID < providedDevices[j].UDID
+		})
 
 		update := bson.M{
 			"$set": bson.M{
@@ -0,0 +1,135 @@
+package providerutil
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"GADS/common/models"
+	"GADS/provider/config"
+)
+
+// How many random ports to try when the purpose has no configured range before giving up
+const ephemeralPortAttempts = 10
+
+type PortAllocation struct {
+	Port        string `json:"port"`
+	Purpose     string `json:"purpose"`
+	Owner       string `json:"owner"` // UDID of the device using the port
+	AllocatedAt int64  `json:"allocated_at"`
+}
+
+var portsMu sync.Mutex
+var allocatedPorts = make(map[string]PortAllocation)
+
+// Next port to try in each range so released ports are not reused right away
+var portRangeCursors = make(map[string]int)
+
+// Allocate a free host port for a device
+// Ports come from the range configured for the purpose in `port_ranges` or are random ports picked by the OS if there is no range
+// The port stays allocated to the device until it is released with ReleaseDevicePorts
+func AllocatePort(purpose string, owner string) (string, error) {
+	portsMu.Lock()
+	defer portsMu.Unlock()
+
+	var port int
+	var err error
+	portRange, ok := config.ProviderConfig().PortRanges[purpose]
+	if ok {
+		port, err = allocateRangePort(purpose, portRange)
+	} else {
+		port, err = allocateEphemeralPort()
+	}
+	if err != nil {
+		return "", err
+	}
+
+	portString := strconv.Itoa(port)
+	allocatedPorts[portString] = PortAllocation{
+		Port:        portString,
+		Purpose:     purpose,
+		Owner:       owner,
+		AllocatedAt: time.Now().UnixMilli(),
+	}
+	return portString, nil
+}
+
+// Must be called with portsMu locked
+func allocateRangePort(purpose string, portRange string) (int, error) {
+	start, end, err := models.ParsePortRange(portRange)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to allocate %s port - %s", purpose, err)
+	}
+
+	size := end - start + 1
+	cursor := max(portRangeCursors[purpose], start)
+	for i := 0; i < size; i++ {
+		port := start + (cursor-start+i)%size
+		if _, ok := allocatedPorts[strconv.Itoa(port)]; ok {
+			continue
+		}
+		if !portAvailable(port) {
+			continue
+		}
+		portRangeCursors[purpose] = port + 1
+		return port, nil
+	}
+	return 0, fmt.Errorf("Failed to allocate %s port - no free port left in range `%s`", purpose, portRange)
+}
+
+// Must be called with portsMu locked
+func allocateEphemeralPort() (int, error) {
+	for i := 0; i < ephemeralPortAttempts; i++ {
+		l, err := net.Listen("tcp", "localhost:0")
+		if err != nil {
+			return 0, fmt.Errorf("Failed to listen tcp trying to get new port - %s", err)
+		}
+		port := l.Addr().(*net.TCPAddr).Port
+		l.Close()
+		if _, ok := allocatedPorts[strconv.Itoa(port)]; !ok {
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("Failed to get a free port after %d attempts", ephemeralPortAttempts)
+}
+
+// Ports in a range might be used by other processes on the host so they are checked before they are allocated
+func portAvailable(port int) bool {
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return false
+	}
+	l.Close()
+	return true
+}
+
+// Release all ports allocated to a device, called whenever the device goes back to `init`
+func ReleaseDevicePorts(owner string) {
+	portsMu.Lock()
+	defer portsMu.Unlock()
+	for port, allocation := range allocatedPorts {
+		if allocation.Owner == owner {
+			delete(allocatedPorts, port)
+		}
+	}
+}
+
+// Currently allocated ports sorted by port number
+func PortAllocations() []PortAllocation {
+	portsMu.Lock()
+	allocations := make([]PortAllocation, 0, len(allocatedPorts))
+	for _, allocation := range allocatedPorts {
+		allocations = append(allocations, allocation)
+	}
+	portsMu.Unlock()
+
+	slices.SortFunc(allocations, func(a, b PortAllocation) int {
+		portA, _ := strconv.Atoi(a.Port)
+		portB, _ := strconv.Atoi(b.Port)
+		return portA - portB
+	})
+	return allocations
+}
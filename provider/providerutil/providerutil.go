@@ -4,57 +4,39 @@ import (
 	"bufio"
 	"fmt"
 	"io"
-	"net"
 	"net/http"
 	"os"
 	"os/exec"
-	"strconv"
-	"sync"
 	"time"
 
 	"GADS/provider/config"
 	"GADS/provider/logger"
 )
 
-var mu sync.RWMutex
-var UsedPorts = make(map[string]bool)
 var gadsStreamURL = "https://github.com/shamanec/GADS-Android-stream/releases/latest/download/gads-stream.apk"
 
-// Use this function to get a free port on the host for any service that might need one
-// We keep a map of used ports so we don't allocate same ports to different services
-func GetFreePort() (string, error) {
-	mu.Lock()
-	defer mu.Unlock()
-
-	a, err := net.ResolveTCPAddr("tcp", "localhost:0")
-	if err != nil {
-		return "", fmt.Errorf("Failed to resolve tcp address trying to get new port - %s", err)
-	}
+// Check if adb is available on the host by starting the server
+func AdbAvailable() bool {
+	logger.ProviderLogger.LogInfo("provider_setup", "Checking if adb is set up and available on the host PATH")
 
-	var l *net.TCPListener
-	l, err = net.ListenTCP("tcp", a)
+	cmd := exec.Command("adb", "start-server")
+	err := cmd.Run()
 	if err != nil {
-		return "", fmt.Errorf("Failed to listen tcp trying to get new port - %s", err)
+		logger.ProviderLogger.LogDebug("provider_setup", fmt.Sprintf("adbAvailable: Error executing `adb start-server`, `adb` is not available on host or command failed - %s", err))
+		return false
 	}
-	defer l.Close()
 
-	portInt := l.Addr().(*net.TCPAddr).Port
-	portString := strconv.Itoa(portInt)
-	if _, ok := UsedPorts[portString]; ok {
-		return GetFreePort()
-	}
-	UsedPorts[portString] = true
-	return portString, nil
+	return true
 }
 
-// Check if adb is available on the host by starting the server
-func AdbAvailable() bool {
-	logger.ProviderLogger.LogInfo("provider_setup", "Checking if adb is set up and available on the host PATH")
+// Check if the Android SDK emulator is available on the host by listing the AVDs
+func EmulatorAvailable() bool {
+	logger.ProviderLogger.LogInfo("provider_setup", "Checking if the Android emulator is set up and available on the host PATH")
 
-	cmd := exec.Command("adb", "start-server")
+	cmd := exec.Command("emulator", "-list-avds")
 	err := cmd.Run()
 	if err != nil {
-		logger.ProviderLogger.LogDebug("provider_setup", fmt.Sprintf("adbAvailable: Error executing `adb start-server`, `adb` is not available on host or command failed - %s", err))
+		logger.ProviderLogger.LogDebug("provider_setup", fmt.Sprintf("EmulatorAvailable: Error executing `emulator -list-avds`, `emulator` is not available on host or command failed - %s", err))
 		return false
 	}
 
@@ -90,14 +72,14 @@ func AppiumAvailable() bool {
 // Build WebDriverAgent for testing with `xcodebuild`
 func BuildWebDriverAgent() error {
 	cmd := exec.Command("xcodebuild", "-project", "WebDriverAgent.xcodeproj", "-scheme", "WebDriverAgentRunner", "-destination", "generic/platform=iOS", "build-for-testing", "-derivedDataPath", "./build")
-	cmd.Dir = config.ProviderConfig.WdaRepoPath
+	cmd.Dir = config.ProviderConfig().WdaRepoPath
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		return err
 	}
 
-	logger.ProviderLogger.LogInfo("provider_setup", fmt.Sprintf("Building WebDriverAgent for testing using xcodebuild in path `%s` with command `%s` ", config.ProviderConfig.WdaRepoPath, cmd.String()))
+	logger.ProviderLogger.LogInfo("provider_setup", fmt.Sprintf("Building WebDriverAgent for testing using xcodebuild in path `%s` with command `%s` ", config.ProviderConfig().WdaRepoPath, cmd.String()))
 	if err := cmd.Start(); err != nil {
 		return err
 	}
@@ -121,7 +103,7 @@ func BuildWebDriverAgent() error {
 
 // Remove all adb forwarded ports(if any) on provider start
 func RemoveAdbForwardedPorts() {
-	logger.ProviderLogger.LogInfo("provider_setup", "Attempting to remove all `adb` forwarded ports on provider start")
+	logger.ProviderLogger.LogInfo("provider_setup", "Attempting to remove all `adb` forwarded ports")
 
 	cmd := exec.Command("adb", "forward", "--remove-all")
 	err := cmd.Run()
@@ -152,7 +134,7 @@ func CheckGadsStreamAndDownload() error {
 
 // Check if the gads-stream.apk file is located in the provider folder
 func isGadsStreamApkAvailable() bool {
-	_, err := os.Stat(fmt.Sprintf("%s/gads-stream.apk", config.ProviderConfig.ProviderFolder))
+	_, err := os.Stat(fmt.Sprintf("%s/gads-stream.apk", config.ProviderConfig().ProviderFolder))
 	if os.IsNotExist(err) {
 		return false
 	}
@@ -162,9 +144,9 @@ func isGadsStreamApkAvailable() bool {
 // Download the latest release of GADS-Android-stream and put the apk in the provider folder
 func downloadGadsStreamApk() error {
 	logger.ProviderLogger.LogInfo("provider", "Downloading latest GADS-stream release apk file")
-	outFile, err := os.Create(fmt.Sprintf("%s/gads-stream.apk", config.ProviderConfig.ProviderFolder))
+	outFile, err := os.Create(fmt.Sprintf("%s/gads-stream.apk", config.ProviderConfig().ProviderFolder))
 	if err != nil {
-		return fmt.Errorf("Could not create file at %s/gads-stream.apk - %s", config.ProviderConfig.ProviderFolder, err)
+		return fmt.Errorf("Could not create file at %s/gads-stream.apk - %s", config.ProviderConfig().ProviderFolder, err)
 	}
 	defer outFile.Close()
 
@@ -0,0 +1,264 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"GADS/common/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+)
+
+// Pointer events of a single gesture that are buffered before it is performed
+const maxGestureEvents = 500
+
+// Perform an arbitrary W3C action sequence on the device in a single call
+func DeviceActions(c *gin.Context) {
+	device, _, ok := deviceAndDriver(c)
+	if !ok {
+		return
+	}
+
+	var requestBody models.W3CActions
+	if err := json.NewDecoder(c.Request.Body).Decode(&requestBody); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := models.ValidateW3CActions(requestBody); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	defaultPointerType(&requestBody)
+
+	device.Logger.LogInfo("appium_interact", fmt.Sprintf("Performing %d action sequences", len(requestBody.Actions)))
+	actionsResp, err := performActions(device, requestBody)
+	if err != nil {
+		interactionError(c, device, "perform actions", err)
+		return
+	}
+	writeInteractionResponse(c, device, actionsResp, "perform actions")
+}
+
+// Pointer sequences without parameters are touches, Appium would treat them as mouse pointers
+func defaultPointerType(actions *models.W3CActions) {
+	for i := range actions.Actions {
+		if actions.Actions[i].Type == "pointer" && actions.Actions[i].Parameters == nil {
+			actions.Actions[i].Parameters = &models.DeviceActionParameters{PointerType: "touch"}
+		}
+	}
+}
+
+// Message sent by websocket clients, either a complete action sequence or a single pointer event
+type actionsWSMessage struct {
+	Type      string                     `json:"type"` // actions or pointer
+	Actions   []models.W3CActionSequence `json:"actions,omitempty"`
+	Event     string                     `json:"event,omitempty"`   // down, move, up or cancel
+	Pointer   string                     `json:"pointer,omitempty"` // defaults to finger1, use different IDs for multi-touch
+	X         float64                    `json:"x"`
+	Y         float64                    `json:"y"`
+	Timestamp int64                      `json:"ts,omitempty"` // milliseconds, the time the provider received the event if missing
+}
+
+type actionsWSResult struct {
+	Type   string `json:"type"` // result or error
+	Status int    `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Websocket variant of the actions endpoint that keeps one connection open for the whole remote control session
+// Pointer events are collected until all pointers are lifted and the gesture is performed in one call with the original timing
+func DeviceActionsWS(c *gin.Context) {
+	device, _, ok := deviceAndDriver(c)
+	if !ok {
+		return
+	}
+
+	conn, _, _, err := ws.UpgradeHTTP(c.Request, c.Writer)
+	if err != nil {
+		device.Logger.LogError("appium_interact", fmt.Sprintf("Failed upgrading http to ws for actions - %s", err))
+		return
+	}
+	defer conn.Close()
+
+	writeResult := func(result actionsWSResult) error {
+		data, _ := json.Marshal(result)
+		return wsutil.WriteServerText(conn, data)
+	}
+
+	var gesture pointerGesture
+	for {
+		data, op, err := wsutil.ReadClientData(conn)
+		if err != nil {
+			return
+		}
+		if op != ws.OpText {
+			continue
+		}
+
+		var message actionsWSMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			if writeResult(actionsWSResult{Type: "error", Error: fmt.Sprintf("Invalid message - %s", err)}) != nil {
+				return
+			}
+			continue
+		}
+
+		var actions models.W3CActions
+		switch message.Type {
+		case "actions":
+			actions = models.W3CActions{Actions: message.Actions}
+		case "pointer":
+			if message.Timestamp == 0 {
+				message.Timestamp = time.Now().UnixMilli()
+			}
+			complete, err := gesture.add(message)
+			if err != nil {
+				gesture = pointerGesture{}
+				if writeResult(actionsWSResult{Type: "error", Error: err.Error()}) != nil {
+					return
+				}
+				continue
+			}
+			if !complete {
+				continue
+			}
+			actions = gesture.actions()
+			gesture = pointerGesture{}
+		default:
+			if writeResult(actionsWSResult{Type: "error", Error: fmt.Sprintf("Unknown message type `%s`, should be actions or pointer", message.Type)}) != nil {
+				return
+			}
+			continue
+		}
+
+		if err := writeResult(performWSActions(device, actions)); err != nil {
+			return
+		}
+	}
+}
+
+func performWSActions(device *models.Device, actions models.W3CActions) actionsWSResult {
+	if err := models.ValidateW3CActions(actions); err != nil {
+		return actionsWSResult{Type: "error", Status: http.StatusBadRequest, Error: err.Error()}
+	}
+	defaultPointerType(&actions)
+
+	actionsResp, err := performActions(device, actions)
+	if err != nil {
+		device.Logger.LogError("appium_interact", fmt.Sprintf("Failed to perform actions - %s", err))
+		return actionsWSResult{Type: "error", Status: http.StatusInternalServerError, Error: err.Error()}
+	}
+	defer actionsResp.Body.Close()
+
+	if actionsResp.StatusCode >= 300 {
+		body, _ := io.ReadAll(actionsResp.Body)
+		return actionsWSResult{Type: "error", Status: actionsResp.StatusCode, Error: string(body)}
+	}
+	return actionsWSResult{Type: "result", Status: actionsResp.StatusCode}
+}
+
+// Pointer events of a gesture from the first pointer going down until the last one is lifted
+type pointerGesture struct {
+	events   []actionsWSMessage
+	pointers []string
+	down     map[string]bool
+	ticks    int // every tick adds an action to the sequence of each pointer
+}
+
+// Add a pointer event, returns true when all pointers are lifted and the gesture can be performed
+func (g *pointerGesture) add(event actionsWSMessage) (bool, error) {
+	if event.Pointer == "" {
+		event.Pointer = "finger1"
+	}
+	if g.down == nil {
+		g.down = make(map[string]bool)
+	}
+
+	switch event.Event {
+	case "down":
+		if g.down[event.Pointer] {
+			return false, fmt.Errorf("Pointer `%s` is already down", event.Pointer)
+		}
+		if !slices.Contains(g.pointers, event.Pointer) {
+			if len(g.pointers) >= models.MaxW3CActionSequences {
+				return false, fmt.Errorf("Too many pointers, at most %d are allowed", models.MaxW3CActionSequences)
+			}
+			g.pointers = append(g.pointers, event.Pointer)
+		}
+		g.down[event.Pointer] = true
+	case "move", "up", "cancel":
+		if !g.down[event.Pointer] {
+			return false, fmt.Errorf("Pointer `%s` is not down", event.Pointer)
+		}
+		if event.Event != "move" {
+			delete(g.down, event.Pointer)
+		}
+	default:
+		return false, fmt.Errorf("Unknown pointer event `%s`, should be down, move, up or cancel", event.Event)
+	}
+
+	g.events = append(g.events, event)
+	if len(g.events) > maxGestureEvents {
+		return false, fmt.Errorf("Gesture has more than %d pointer events", maxGestureEvents)
+	}
+	// Down, up and cancel events are a move or pause followed by the pointer going down or up
+	if event.Event == "move" {
+		g.ticks++
+	} else {
+		g.ticks += 2
+	}
+	if g.ticks*len(g.pointers) > models.MaxW3CActions {
+		return false, fmt.Errorf("Gesture would need %d actions, at most %d are allowed", g.ticks*len(g.pointers), models.MaxW3CActions)
+	}
+	return len(g.down) == 0, nil
+}
+
+// Build one touch sequence per pointer, every event is a tick in which the other pointers pause for the same time
+func (g *pointerGesture) actions() models.W3CActions {
+	sequences := make([]models.W3CActionSequence, len(g.pointers))
+	for i, pointer := range g.pointers {
+		sequences[i] = models.W3CActionSequence{
+			Type:       "pointer",
+			ID:         pointer,
+			Parameters: &models.DeviceActionParameters{PointerType: "touch"},
+		}
+	}
+
+	tick := func(owner string, action models.W3CAction) {
+		for i := range sequences {
+			if sequences[i].ID == owner {
+				sequences[i].Actions = append(sequences[i].Actions, action)
+			} else {
+				sequences[i].Actions = append(sequences[i].Actions, models.W3CAction{Type: "pause", Duration: action.Duration})
+			}
+		}
+	}
+
+	lastTimestamp := g.events[0].Timestamp
+	for _, event := range g.events {
+		duration := int(min(max(event.Timestamp-lastTimestamp, 0), models.MaxW3CActionDuration))
+		lastTimestamp = event.Timestamp
+		x, y := event.X, event.Y
+
+		switch event.Event {
+		case "down":
+			tick(event.Pointer, models.W3CAction{Type: "pointerMove", Duration: duration, X: &x, Y: &y, Origin: "viewport"})
+			tick(event.Pointer, models.W3CAction{Type: "pointerDown"})
+		case "move":
+			tick(event.Pointer, models.W3CAction{Type: "pointerMove", Duration: duration, X: &x, Y: &y, Origin: "viewport"})
+		case "up":
+			tick(event.Pointer, models.W3CAction{Type: "pointerMove", Duration: duration, X: &x, Y: &y, Origin: "viewport"})
+			tick(event.Pointer, models.W3CAction{Type: "pointerUp"})
+		case "cancel":
+			tick(event.Pointer, models.W3CAction{Type: "pause", Duration: duration})
+			tick(event.Pointer, models.W3CAction{Type: "pointerUp"})
+		}
+	}
+	return models.W3CActions{Actions: sequences}
+}
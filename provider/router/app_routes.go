@@ -0,0 +1,125 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+
+	"GADS/common/models"
+	"GADS/provider/devices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Android package names, iOS bundle IDs can also contain hyphens
+var appIdentifierRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)+$`)
+
+type OpenURLPayload struct {
+	URL string `json:"url"`
+}
+
+// Get the device and its driver, writes the error response and returns false if either is missing
+func deviceAndDriver(c *gin.Context) (*models.Device, devices.DeviceDriver, bool) {
+	udid := c.Param("udid")
+	device, ok := devices.Registry.Get(udid)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Device with udid `%s` does not exist", udid)})
+		return nil, nil, false
+	}
+	driver, err := devices.GetDeviceDriver(device)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, nil, false
+	}
+	return device, driver, true
+}
+
+// Launch an installed app by package name or bundle ID, a running app is brought to the foreground
+func LaunchApp(c *gin.Context) {
+	device, driver, ok := deviceAndDriver(c)
+	if !ok {
+		return
+	}
+
+	var payload ProcessApp
+	if err := json.NewDecoder(c.Request.Body).Decode(&payload); err != nil || !appIdentifierRegex.MatchString(payload.App) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload, provide a valid package name or bundle ID in `app`"})
+		return
+	}
+
+	device.Logger.LogInfo("app_interact", fmt.Sprintf("Launching app `%s`", payload.App))
+	err := driver.LaunchApp(device, payload.App)
+	if err != nil {
+		device.Logger.LogError("app_interact", fmt.Sprintf("Failed to launch app `%s` - %s", payload.App, err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Launched app `%s`", payload.App)})
+}
+
+func TerminateApp(c *gin.Context) {
+	device, driver, ok := deviceAndDriver(c)
+	if !ok {
+		return
+	}
+
+	var payload ProcessApp
+	if err := json.NewDecoder(c.Request.Body).Decode(&payload); err != nil || !appIdentifierRegex.MatchString(payload.App) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload, provide a valid package name or bundle ID in `app`"})
+		return
+	}
+
+	device.Logger.LogInfo("app_interact", fmt.Sprintf("Terminating app `%s`", payload.App))
+	err := driver.TerminateApp(device, payload.App)
+	if err != nil {
+		device.Logger.LogError("app_interact", fmt.Sprintf("Failed to terminate app `%s` - %s", payload.App, err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Terminated app `%s`", payload.App)})
+}
+
+// Open a web URL or a deep link like `myapp://profile/1` with the app that handles it
+func OpenURL(c *gin.Context) {
+	device, driver, ok := deviceAndDriver(c)
+	if !ok {
+		return
+	}
+
+	var payload OpenURLPayload
+	if err := json.NewDecoder(c.Request.Body).Decode(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return
+	}
+	parsedURL, err := url.Parse(payload.URL)
+	if err != nil || parsedURL.Scheme == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing or invalid `url`, provide an absolute URL or deep link with a scheme"})
+		return
+	}
+
+	device.Logger.LogInfo("app_interact", fmt.Sprintf("Opening URL `%s`", payload.URL))
+	err = driver.OpenURL(device, payload.URL)
+	if err != nil {
+		device.Logger.LogError("app_interact", fmt.Sprintf("Failed to open URL `%s` - %s", payload.URL, err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Opened URL `%s`", payload.URL)})
+}
+
+func ForegroundApp(c *gin.Context) {
+	device, driver, ok := deviceAndDriver(c)
+	if !ok {
+		return
+	}
+
+	app, err := driver.ForegroundApp(device)
+	if err != nil {
+		device.Logger.LogError("app_interact", fmt.Sprintf("Failed to get the foreground app - %s", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"app": app})
+}
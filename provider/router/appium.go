@@ -2,11 +2,16 @@ package router
 
 import (
 	"GADS/provider/config"
+	"GADS/provider/devices"
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"GADS/common/models"
@@ -17,7 +22,7 @@ var netClient = &http.Client{
 }
 
 func appiumRequest(device *models.Device, method, endpoint string, requestBody io.Reader) (*http.Response, error) {
-	url := fmt.Sprintf("http://localhost:%s/session/%s/%s", device.AppiumPort, device.AppiumSessionID, endpoint)
+	url := fmt.Sprintf("http://localhost:%s/session/%s/%s", device.AppiumPort, device.AppiumSession(), endpoint)
 	device.Logger.LogDebug("appium_interact", fmt.Sprintf("Calling `%s` for device `%s`", url, device.UDID))
 	req, err := http.NewRequest(method, url, requestBody)
 	if err != nil {
@@ -52,7 +57,7 @@ func appiumLockUnlock(device *models.Device, lock string) (*http.Response, error
 }
 
 func appiumTap(device *models.Device, x float64, y float64) (*http.Response, error) {
-	if config.ProviderConfig.UseCustomWDA && device.OS == "ios" {
+	if config.ProviderConfig().UseCustomWDA && device.OS == "ios" {
 		requestBody := struct {
 			X float64 `json:"x"`
 			Y float64 `json:"y"`
@@ -109,7 +114,7 @@ func appiumTap(device *models.Device, x float64, y float64) (*http.Response, err
 
 func appiumTouchAndHold(device *models.Device, x float64, y float64) (*http.Response, error) {
 	// Generate the struct object for the Appium actions JSON request
-	if config.ProviderConfig.UseCustomWDA && device.OS == "ios" {
+	if config.ProviderConfig().UseCustomWDA && device.OS == "ios" {
 		requestBody := struct {
 			X     float64 `json:"x"`
 			Y     float64 `json:"y"`
@@ -166,8 +171,13 @@ func appiumTouchAndHold(device *models.Device, x float64, y float64) (*http.Resp
 	}
 }
 
-func appiumSwipe(device *models.Device, x, y, endX, endY float64) (*http.Response, error) {
-	if config.ProviderConfig.UseCustomWDA && device.OS == "ios" {
+// Swipe between two points, a zero duration keeps the default swipe speed
+func appiumSwipe(device *models.Device, x, y, endX, endY float64, duration int) (*http.Response, error) {
+	if config.ProviderConfig().UseCustomWDA && device.OS == "ios" {
+		delay := 1.0
+		if duration > 0 {
+			delay = float64(duration) / 1000
+		}
 		requestBody := struct {
 			X     float64 `json:"startX"`
 			Y     float64 `json:"startY"`
@@ -179,7 +189,7 @@ func appiumSwipe(device *models.Device, x, y, endX, endY float64) (*http.Respons
 			Y:     y,
 			EndX:  endX,
 			EndY:  endY,
-			Delay: 1,
+			Delay: delay,
 		}
 		actionJSON, err := json.MarshalIndent(requestBody, "", "  ")
 		if err != nil {
@@ -187,6 +197,9 @@ func appiumSwipe(device *models.Device, x, y, endX, endY float64) (*http.Respons
 		}
 		return wdaRequest(device, http.MethodPost, "wda/swipe", bytes.NewReader(actionJSON))
 	} else {
+		if duration <= 0 {
+			duration = 500
+		}
 		// Generate the struct object for the Appium actions JSON request
 		action := models.DevicePointerActions{
 			Actions: []models.DevicePointerAction{
@@ -210,7 +223,7 @@ func appiumSwipe(device *models.Device, x, y, endX, endY float64) (*http.Respons
 						},
 						{
 							Type:     "pointerMove",
-							Duration: 500,
+							Duration: duration,
 							Origin:   "viewport",
 							X:        endX,
 							Y:        endY,
@@ -298,7 +311,7 @@ func appiumClearText(device *models.Device) (*http.Response, error) {
 
 func appiumHome(device *models.Device) (*http.Response, error) {
 	switch device.OS {
-	case "android":
+	case "android", "fake":
 		requestBody := models.AndroidKeycodePayload{
 			Keycode: 3,
 		}
@@ -331,7 +344,7 @@ func appiumActivateApp(device *models.Device, appIdentifier string) (*http.Respo
 		}
 
 		return appiumRequest(device, http.MethodPost, "appium/device/activate_app", bytes.NewReader(reqJson))
-	case "android":
+	case "android", "fake":
 		requestBody := struct {
 			AppId string `json:"appId"`
 		}{
@@ -353,7 +366,7 @@ func appiumGetClipboard(device *models.Device) (*http.Response, error) {
 	requestBody := struct {
 		ContentType string `json:"contentType"`
 	}{
-		ContentType: "plaintext",
+		ContentType: models.ClipboardPlaintext,
 	}
 	reqJson, err := json.MarshalIndent(requestBody, "", "  ")
 	if err != nil {
@@ -362,26 +375,295 @@ func appiumGetClipboard(device *models.Device) (*http.Response, error) {
 
 	switch device.OS {
 	case "ios":
-		activateAppResp, err := appiumActivateApp(device, config.ProviderConfig.WdaBundleID)
-		if err != nil {
-			return activateAppResp, fmt.Errorf("appiumGetClipboard: Failed to activate app - %s", err)
+		return withWDAForeground(device, func() (*http.Response, error) {
+			return appiumRequest(device, http.MethodPost, "appium/device/get_clipboard", bytes.NewReader(reqJson))
+		})
+	case "android", "fake":
+		return appiumRequest(device, http.MethodPost, "appium/device/get_clipboard", bytes.NewReader(reqJson))
+	default:
+		return nil, fmt.Errorf("appiumGetClipboard: Bad device OS for device `%s` - %s", device.UDID, device.OS)
+	}
+}
+
+// Set the device clipboard, plain text content is encoded by the provider, image content should already be a base64 encoded PNG
+func appiumSetClipboard(device *models.Device, content, contentType string) (*http.Response, error) {
+	switch contentType {
+	case models.ClipboardPlaintext:
+		content = base64.StdEncoding.EncodeToString([]byte(content))
+	case models.ClipboardImage:
+		if device.OS != "ios" {
+			return nil, errActionNotSupported
 		}
-		defer activateAppResp.Body.Close()
+		if _, err := base64.StdEncoding.DecodeString(content); err != nil {
+			return nil, fmt.Errorf("appiumSetClipboard: Image content is not valid base64 - %s", err)
+		}
+	default:
+		return nil, fmt.Errorf("appiumSetClipboard: Invalid content type `%s`", contentType)
+	}
+
+	requestBody := struct {
+		Content     string `json:"content"`
+		ContentType string `json:"contentType"`
+	}{
+		Content:     content,
+		ContentType: contentType,
+	}
+	reqJson, err := json.MarshalIndent(requestBody, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("appiumSetClipboard: Failed to marshal request body json when setting clipboard for device `%s` - %s", device.UDID, err)
+	}
 
-		clipboardResp, err := appiumRequest(device, http.MethodPost, "appium/device/get_clipboard", bytes.NewReader(reqJson))
+	switch device.OS {
+	case "ios":
+		return withWDAForeground(device, func() (*http.Response, error) {
+			return appiumRequest(device, http.MethodPost, "appium/device/set_clipboard", bytes.NewReader(reqJson))
+		})
+	case "android", "fake":
+		return appiumRequest(device, http.MethodPost, "appium/device/set_clipboard", bytes.NewReader(reqJson))
+	default:
+		return nil, fmt.Errorf("appiumSetClipboard: Bad device OS for device `%s` - %s", device.UDID, device.OS)
+	}
+}
+
+// iOS only allows the foreground app to access the clipboard so WebDriverAgent is activated for the request
+// The app that was in the foreground before is restored afterwards, or the home screen if it was already there
+func withWDAForeground(device *models.Device, request func() (*http.Response, error)) (*http.Response, error) {
+	previousApp := ""
+	driver, err := devices.GetDeviceDriver(device)
+	if err == nil {
+		previousApp, err = driver.ForegroundApp(device)
+	}
+	if err != nil {
+		device.Logger.LogWarn("appium_interact", fmt.Sprintf("Failed to get the foreground app before activating WebDriverAgent, will return to the home screen - %s", err))
+	}
+
+	// Return to the previous app even if WebDriverAgent could not be activated or the request failed
+	defer restoreForegroundApp(device, previousApp)
+
+	activateAppResp, err := appiumActivateApp(device, config.ProviderConfig().WdaBundleID)
+	if err != nil {
+		return activateAppResp, fmt.Errorf("withWDAForeground: Failed to activate WebDriverAgent - %s", err)
+	}
+	activateAppResp.Body.Close()
+
+	resp, err := request()
+	if err != nil {
+		return resp, fmt.Errorf("withWDAForeground: Failed to execute Appium request for device `%s` - %s", device.UDID, err)
+	}
+	return resp, nil
+}
+
+// Bring back the app that was in the foreground before WebDriverAgent was activated, or the home screen if there was none
+func restoreForegroundApp(device *models.Device, previousApp string) {
+	if previousApp == "" || previousApp == "com.apple.springboard" || previousApp == config.ProviderConfig().WdaBundleID {
+		restoreResp, err := appiumHome(device)
 		if err != nil {
-			return clipboardResp, fmt.Errorf("appiumGetClipboard: Failed to execute Appium request for device `%s` - %s", device.UDID, err)
+			device.Logger.LogWarn("appium_interact", fmt.Sprintf("Failed to navigate to Home/Springboard after the clipboard request - %s", err))
+			return
 		}
+		restoreResp.Body.Close()
+		return
+	}
+
+	restoreResp, err := appiumActivateApp(device, previousApp)
+	if err != nil {
+		device.Logger.LogWarn("appium_interact", fmt.Sprintf("Failed to restore app `%s` after the clipboard request - %s", previousApp, err))
+		return
+	}
+	restoreResp.Body.Close()
+}
+
+var errActionNotSupported = errors.New("action is not supported")
 
-		_, err = appiumHome(device)
+// Android keycodes of the hardware keys
+var androidKeycodes = map[string]int{
+	models.HardwareKeyHome:       3,
+	models.HardwareKeyBack:       4,
+	models.HardwareKeyVolumeUp:   24,
+	models.HardwareKeyVolumeDown: 25,
+	models.HardwareKeyPower:      26,
+	models.HardwareKeyAppSwitch:  187,
+}
+
+// Button names of the WebDriverAgent `pressButton` endpoint
+var iosButtons = map[string]string{
+	models.HardwareKeyVolumeUp:   "volumeUp",
+	models.HardwareKeyVolumeDown: "volumeDown",
+}
+
+func appiumPressKey(device *models.Device, key string) (*http.Response, error) {
+	switch device.OS {
+	case "android", "fake":
+		requestBody := models.AndroidKeycodePayload{
+			Keycode: androidKeycodes[key],
+		}
+
+		keyJSON, err := json.MarshalIndent(requestBody, "", "  ")
 		if err != nil {
-			device.Logger.LogWarn("appium_interact", "appiumGetClipboard: Failed to navigate to Home/Springboard using Appium")
+			return nil, err
 		}
 
-		return clipboardResp, nil
-	case "android":
-		return appiumRequest(device, http.MethodPost, "appium/device/get_clipboard", bytes.NewReader(reqJson))
+		return appiumRequest(device, http.MethodPost, "appium/device/press_keycode", bytes.NewReader(keyJSON))
+	case "ios":
+		switch key {
+		case models.HardwareKeyHome:
+			return wdaRequest(device, http.MethodPost, "wda/homescreen", nil)
+		case models.HardwareKeyVolumeUp, models.HardwareKeyVolumeDown:
+			requestBody := struct {
+				Name string `json:"name"`
+			}{
+				Name: iosButtons[key],
+			}
+
+			keyJSON, err := json.MarshalIndent(requestBody, "", "  ")
+			if err != nil {
+				return nil, err
+			}
+
+			return wdaRequest(device, http.MethodPost, fmt.Sprintf("session/%s/wda/pressButton", device.WDASessionID), bytes.NewReader(keyJSON))
+		case models.HardwareKeyPower:
+			return wdaTogglePower(device)
+		case models.HardwareKeyAppSwitch:
+			return wdaAppSwitcher(device)
+		default:
+			return nil, fmt.Errorf("%w on iOS - `%s` key", errActionNotSupported, key)
+		}
 	default:
-		return nil, fmt.Errorf("appiumGetClipboard: Bad device OS for device `%s` - %s", device.UDID, device.OS)
+		return nil, fmt.Errorf("Unsupported device OS: %s", device.OS)
+	}
+}
+
+// iOS has no power key event, lock the device or unlock it if it is already locked
+func wdaTogglePower(device *models.Device) (*http.Response, error) {
+	lockedResp, err := wdaRequest(device, http.MethodGet, "wda/locked", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer lockedResp.Body.Close()
+
+	var locked struct {
+		Value bool `json:"value"`
+	}
+	err = json.NewDecoder(lockedResp.Body).Decode(&locked)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode WebDriverAgent lock state - %s", err)
+	}
+
+	if locked.Value {
+		return wdaRequest(device, http.MethodPost, "wda/unlock", nil)
+	}
+	return wdaRequest(device, http.MethodPost, "wda/lock", nil)
+}
+
+// Open the app switcher on iOS by swiping up from the bottom edge and holding halfway
+func wdaAppSwitcher(device *models.Device) (*http.Response, error) {
+	width, err := strconv.ParseFloat(device.ScreenWidth, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse screen width `%s` - %s", device.ScreenWidth, err)
+	}
+	height, err := strconv.ParseFloat(device.ScreenHeight, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse screen height `%s` - %s", device.ScreenHeight, err)
+	}
+
+	return pointerActions(device, touchPointer("finger1",
+		models.DeviceAction{Type: "pointerMove", X: width / 2, Y: height - 2},
+		models.DeviceAction{Type: "pointerDown"},
+		models.DeviceAction{Type: "pointerMove", Duration: 300, Origin: "viewport", X: width / 2, Y: height * 0.6},
+		models.DeviceAction{Type: "pause", Duration: 800},
+		models.DeviceAction{Type: "pointerUp"},
+	))
+}
+
+func appiumSetOrientation(device *models.Device, orientation string) (*http.Response, error) {
+	requestBody := struct {
+		Orientation string `json:"orientation"`
+	}{
+		Orientation: strings.ToUpper(orientation),
+	}
+
+	orientationJSON, err := json.MarshalIndent(requestBody, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	switch device.OS {
+	case "android", "fake":
+		return appiumRequest(device, http.MethodPost, "orientation", bytes.NewReader(orientationJSON))
+	case "ios":
+		return wdaRequest(device, http.MethodPost, fmt.Sprintf("session/%s/orientation", device.WDASessionID), bytes.NewReader(orientationJSON))
+	default:
+		return nil, fmt.Errorf("Unsupported device OS: %s", device.OS)
+	}
+}
+
+// Move two fingers horizontally around the center point - towards it for a pinch, away from it for a zoom
+func appiumPinch(device *models.Device, data models.PinchData, zoom bool) (*http.Response, error) {
+	duration := data.Duration
+	if duration <= 0 {
+		duration = 500
+	}
+	outer := data.Distance / 2
+	inner := data.Distance / 10
+	from, to := outer, inner
+	if zoom {
+		from, to = inner, outer
+	}
+
+	fingerPath := func(id string, direction float64) models.DevicePointerAction {
+		return touchPointer(id,
+			models.DeviceAction{Type: "pointerMove", X: data.X + direction*from, Y: data.Y},
+			models.DeviceAction{Type: "pointerDown"},
+			models.DeviceAction{Type: "pointerMove", Duration: duration, Origin: "viewport", X: data.X + direction*to, Y: data.Y},
+			models.DeviceAction{Type: "pointerUp"},
+		)
+	}
+	return pointerActions(device, fingerPath("finger1", -1), fingerPath("finger2", 1))
+}
+
+// Press and hold on the start point to pick up what is there, then move it to the end point and release it
+func appiumDrag(device *models.Device, x, y, endX, endY float64, duration int) (*http.Response, error) {
+	if duration <= 0 {
+		duration = 1000
+	}
+	return pointerActions(device, touchPointer("finger1",
+		models.DeviceAction{Type: "pointerMove", X: x, Y: y},
+		models.DeviceAction{Type: "pointerDown"},
+		models.DeviceAction{Type: "pause", Duration: 1000},
+		models.DeviceAction{Type: "pointerMove", Duration: duration, Origin: "viewport", X: endX, Y: endY},
+		models.DeviceAction{Type: "pause", Duration: 200},
+		models.DeviceAction{Type: "pointerUp"},
+	))
+}
+
+func touchPointer(id string, actions ...models.DeviceAction) models.DevicePointerAction {
+	return models.DevicePointerAction{
+		Type: "pointer",
+		ID:   id,
+		Parameters: models.DeviceActionParameters{
+			PointerType: "touch",
+		},
+		Actions: actions,
+	}
+}
+
+func pointerActions(device *models.Device, pointers ...models.DevicePointerAction) (*http.Response, error) {
+	return performActions(device, models.DevicePointerActions{Actions: pointers})
+}
+
+// Perform W3C actions through Appium on Android and directly through WebDriverAgent on iOS
+func performActions(device *models.Device, actions interface{}) (*http.Response, error) {
+	actionJSON, err := json.Marshal(actions)
+	if err != nil {
+		return nil, err
+	}
+
+	switch device.OS {
+	case "android", "fake":
+		return appiumRequest(device, http.MethodPost, "actions", bytes.NewReader(actionJSON))
+	case "ios":
+		return wdaRequest(device, http.MethodPost, fmt.Sprintf("session/%s/actions", device.WDASessionID), bytes.NewReader(actionJSON))
+	default:
+		return nil, fmt.Errorf("Unsupported device OS: %s", device.OS)
 	}
 }
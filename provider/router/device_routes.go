@@ -3,9 +3,12 @@ package router
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
+	"strings"
 
 	"GADS/common/models"
 	"GADS/provider/devices"
@@ -25,7 +28,7 @@ func copyHeaders(destination, source http.Header) {
 // Check the device health by checking Appium and WDA(for iOS)
 func DeviceHealth(c *gin.Context) {
 	udid := c.Param("udid")
-	dev := devices.DBDeviceMap[udid]
+	dev, _ := devices.Registry.Get(udid)
 	bool, err := devices.GetDeviceHealth(dev)
 	if err != nil {
 		dev.Logger.LogInfo("device", fmt.Sprintf("Could not check device health - %s", err))
@@ -46,7 +49,7 @@ func DeviceHealth(c *gin.Context) {
 // Call the respective Appium/WDA endpoint to go to Homescreen
 func DeviceHome(c *gin.Context) {
 	udid := c.Param("udid")
-	device := devices.DBDeviceMap[udid]
+	device, _ := devices.Registry.Get(udid)
 	device.Logger.LogInfo("appium_interact", "Navigating to Home/Springboard")
 
 	// Send the request
@@ -73,7 +76,7 @@ func DeviceHome(c *gin.Context) {
 
 func DeviceGetClipboard(c *gin.Context) {
 	udid := c.Param("udid")
-	device := devices.DBDeviceMap[udid]
+	device, _ := devices.Registry.Get(udid)
 	device.Logger.LogInfo("appium_interact", "Getting device clipboard value")
 
 	// Send the request
@@ -108,10 +111,90 @@ func DeviceGetClipboard(c *gin.Context) {
 	c.String(http.StatusOK, string(decoded))
 }
 
+// Set the device clipboard to plain text or a base64 encoded PNG image (iOS only)
+func DeviceSetClipboard(c *gin.Context) {
+	device, _, ok := deviceAndDriver(c)
+	if !ok {
+		return
+	}
+
+	var requestBody models.ClipboardData
+	if err := json.NewDecoder(c.Request.Body).Decode(&requestBody); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if requestBody.ContentType == "" {
+		requestBody.ContentType = models.ClipboardPlaintext
+	}
+	switch requestBody.ContentType {
+	case models.ClipboardPlaintext:
+	case models.ClipboardImage:
+		if _, err := base64.StdEncoding.DecodeString(requestBody.Content); err != nil || requestBody.Content == "" {
+			c.String(http.StatusBadRequest, "Image content should be a base64 encoded PNG")
+			return
+		}
+	default:
+		c.String(http.StatusBadRequest, fmt.Sprintf("Invalid content type `%s`, should be %s or %s", requestBody.ContentType, models.ClipboardPlaintext, models.ClipboardImage))
+		return
+	}
+
+	action := fmt.Sprintf("set the %s clipboard", requestBody.ContentType)
+	device.Logger.LogInfo("appium_interact", fmt.Sprintf("Setting the device clipboard with %s content", requestBody.ContentType))
+
+	clipboardResp, err := appiumSetClipboard(device, requestBody.Content, requestBody.ContentType)
+	if err != nil {
+		interactionError(c, device, action, err)
+		return
+	}
+	writeInteractionResponse(c, device, clipboardResp, action)
+}
+
+// Set the clipboard to the text and type it into the active element
+func DevicePaste(c *gin.Context) {
+	device, _, ok := deviceAndDriver(c)
+	if !ok {
+		return
+	}
+
+	var requestBody models.ClipboardData
+	if err := json.NewDecoder(c.Request.Body).Decode(&requestBody); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if requestBody.ContentType != "" && requestBody.ContentType != models.ClipboardPlaintext {
+		c.String(http.StatusBadRequest, "Only plaintext content can be pasted")
+		return
+	}
+	if requestBody.Content == "" {
+		c.String(http.StatusBadRequest, "Missing `content` to paste")
+		return
+	}
+
+	device.Logger.LogInfo("appium_interact", "Pasting text into the active element")
+
+	clipboardResp, err := appiumSetClipboard(device, requestBody.Content, models.ClipboardPlaintext)
+	if err != nil {
+		interactionError(c, device, "set the clipboard for pasting", err)
+		return
+	}
+	if clipboardResp.StatusCode >= 300 {
+		writeInteractionResponse(c, device, clipboardResp, "set the clipboard for pasting")
+		return
+	}
+	clipboardResp.Body.Close()
+
+	typeResp, err := appiumTypeText(device, requestBody.Content)
+	if err != nil {
+		interactionError(c, device, "paste text", err)
+		return
+	}
+	writeInteractionResponse(c, device, typeResp, "paste text")
+}
+
 // Call respective Appium/WDA endpoint to lock the device
 func DeviceLock(c *gin.Context) {
 	udid := c.Param("udid")
-	device := devices.DBDeviceMap[udid]
+	device, _ := devices.Registry.Get(udid)
 	device.Logger.LogInfo("appium_interact", "Locking device")
 
 	lockResponse, err := appiumLockUnlock(device, "lock")
@@ -138,7 +221,7 @@ func DeviceLock(c *gin.Context) {
 // Call the respective Appium/WDA endpoint to unlock the device
 func DeviceUnlock(c *gin.Context) {
 	udid := c.Param("udid")
-	device := devices.DBDeviceMap[udid]
+	device, _ := devices.Registry.Get(udid)
 	device.Logger.LogInfo("appium_interact", "Unlocking device")
 
 	lockResponse, err := appiumLockUnlock(device, "unlock")
@@ -165,7 +248,7 @@ func DeviceUnlock(c *gin.Context) {
 // Call the respective Appium/WDA endpoint to take a screenshot of the device screen
 func DeviceScreenshot(c *gin.Context) {
 	udid := c.Param("udid")
-	device := devices.DBDeviceMap[udid]
+	device, _ := devices.Registry.Get(udid)
 	device.Logger.LogInfo("appium_interact", "Getting screenshot from device")
 
 	screenshotResp, err := appiumScreenshot(device)
@@ -184,12 +267,37 @@ func DeviceScreenshot(c *gin.Context) {
 	fmt.Fprintf(c.Writer, string(screenshotRespBody))
 }
 
+// Take a screenshot of the device screen with its driver, works without a running Appium server
+func DeviceDriverScreenshot(c *gin.Context) {
+	udid := c.Param("udid")
+	device, ok := devices.Registry.Get(udid)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Did not find device with udid `%s`", udid)})
+		return
+	}
+
+	driver, err := devices.GetDeviceDriver(device)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	screenshot, err := driver.Screenshot(device)
+	if err != nil {
+		device.Logger.LogError("screenshot", fmt.Sprintf("Failed to get screenshot from device - %s", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Data(http.StatusOK, http.DetectContentType(screenshot), screenshot)
+}
+
 //======================================
 // Appium source
 
 func DeviceAppiumSource(c *gin.Context) {
 	udid := c.Param("udid")
-	device := devices.DBDeviceMap[udid]
+	device, _ := devices.Registry.Get(udid)
 	device.Logger.LogInfo("appium_interact", "Getting Appium source from device")
 
 	sourceResp, err := appiumSource(device)
@@ -218,7 +326,7 @@ func DeviceAppiumSource(c *gin.Context) {
 
 func DeviceTypeText(c *gin.Context) {
 	udid := c.Param("udid")
-	device := devices.DBDeviceMap[udid]
+	device, _ := devices.Registry.Get(udid)
 
 	var requestBody models.ActionData
 	if err := json.NewDecoder(c.Request.Body).Decode(&requestBody); err != nil {
@@ -251,7 +359,7 @@ func DeviceTypeText(c *gin.Context) {
 
 func DeviceClearText(c *gin.Context) {
 	udid := c.Param("udid")
-	device := devices.DBDeviceMap[udid]
+	device, _ := devices.Registry.Get(udid)
 	device.Logger.LogInfo("appium_interact", "Clearing text from active element")
 
 	clearResp, err := appiumClearText(device)
@@ -276,7 +384,7 @@ func DeviceClearText(c *gin.Context) {
 
 func DeviceTap(c *gin.Context) {
 	udid := c.Param("udid")
-	device := devices.DBDeviceMap[udid]
+	device, _ := devices.Registry.Get(udid)
 
 	var requestBody models.ActionData
 	if err := json.NewDecoder(c.Request.Body).Decode(&requestBody); err != nil {
@@ -309,7 +417,7 @@ func DeviceTap(c *gin.Context) {
 
 func DeviceTouchAndHold(c *gin.Context) {
 	udid := c.Param("udid")
-	device := devices.DBDeviceMap[udid]
+	device, _ := devices.Registry.Get(udid)
 
 	var requestBody models.ActionData
 	if err := json.NewDecoder(c.Request.Body).Decode(&requestBody); err != nil {
@@ -342,7 +450,7 @@ func DeviceTouchAndHold(c *gin.Context) {
 
 func DeviceSwipe(c *gin.Context) {
 	udid := c.Param("udid")
-	device := devices.DBDeviceMap[udid]
+	device, _ := devices.Registry.Get(udid)
 
 	var requestBody models.ActionData
 	if err := json.NewDecoder(c.Request.Body).Decode(&requestBody); err != nil {
@@ -353,7 +461,7 @@ func DeviceSwipe(c *gin.Context) {
 
 	device.Logger.LogInfo("appium_interact", fmt.Sprintf("Swiping from X:%v Y:%v to X:%v Y:%v", fmt.Sprintf("%.3f", requestBody.X), fmt.Sprintf("%.3f", requestBody.Y), fmt.Sprintf("%.3f", requestBody.EndX), fmt.Sprintf("%.3f", requestBody.EndY)))
 
-	swipeResp, err := appiumSwipe(device, requestBody.X, requestBody.Y, requestBody.EndX, requestBody.EndY)
+	swipeResp, err := appiumSwipe(device, requestBody.X, requestBody.Y, requestBody.EndX, requestBody.EndY, requestBody.Duration)
 	if err != nil {
 		device.Logger.LogError("appium_interact", fmt.Sprintf("Failed to swipe from X:%v Y:%v to X:%v Y:%v - %s", fmt.Sprintf("%.3f", requestBody.X), fmt.Sprintf("%.3f", requestBody.Y), fmt.Sprintf("%.3f", requestBody.EndX), fmt.Sprintf("%.3f", requestBody.EndY), err))
 		c.String(http.StatusInternalServerError, err.Error())
@@ -373,3 +481,149 @@ func DeviceSwipe(c *gin.Context) {
 	copyHeaders(c.Writer.Header(), swipeResp.Header)
 	fmt.Fprint(c.Writer, string(body))
 }
+
+// Write the Appium/WDA response of a device interaction back to the client
+func writeInteractionResponse(c *gin.Context, device *models.Device, response *http.Response, action string) {
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		device.Logger.LogError("appium_interact", fmt.Sprintf("Failed to read response body of %s - %s", action, err))
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	copyHeaders(c.Writer.Header(), response.Header)
+	c.Writer.WriteHeader(response.StatusCode)
+	c.Writer.Write(body)
+}
+
+// Actions the device OS does not have are bad requests, other failures are internal errors
+func interactionError(c *gin.Context, device *models.Device, action string, err error) {
+	if errors.Is(err, errActionNotSupported) {
+		device.Logger.LogWarn("appium_interact", fmt.Sprintf("Failed to %s - %s", action, err))
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	device.Logger.LogError("appium_interact", fmt.Sprintf("Failed to %s - %s", action, err))
+	c.String(http.StatusInternalServerError, err.Error())
+}
+
+// Press a hardware key - home, back, app switcher, volume up/down or power
+func DevicePressKey(c *gin.Context) {
+	device, _, ok := deviceAndDriver(c)
+	if !ok {
+		return
+	}
+
+	var requestBody models.HardwareKeyData
+	if err := json.NewDecoder(c.Request.Body).Decode(&requestBody); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if !slices.Contains(models.HardwareKeys, requestBody.Key) {
+		c.String(http.StatusBadRequest, fmt.Sprintf("Unknown key `%s`, should be one of %s", requestBody.Key, strings.Join(models.HardwareKeys, ", ")))
+		return
+	}
+
+	action := fmt.Sprintf("press the `%s` key", requestBody.Key)
+	device.Logger.LogInfo("appium_interact", fmt.Sprintf("Pressing the `%s` key", requestBody.Key))
+
+	keyResp, err := appiumPressKey(device, requestBody.Key)
+	if err != nil {
+		interactionError(c, device, action, err)
+		return
+	}
+	writeInteractionResponse(c, device, keyResp, action)
+}
+
+// Rotate the device to portrait or landscape
+func DeviceSetOrientation(c *gin.Context) {
+	device, _, ok := deviceAndDriver(c)
+	if !ok {
+		return
+	}
+
+	var requestBody models.OrientationData
+	if err := json.NewDecoder(c.Request.Body).Decode(&requestBody); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if requestBody.Orientation != models.OrientationPortrait && requestBody.Orientation != models.OrientationLandscape {
+		c.String(http.StatusBadRequest, fmt.Sprintf("Unknown orientation `%s`, should be %s or %s", requestBody.Orientation, models.OrientationPortrait, models.OrientationLandscape))
+		return
+	}
+
+	action := fmt.Sprintf("rotate to %s", requestBody.Orientation)
+	device.Logger.LogInfo("appium_interact", fmt.Sprintf("Rotating to %s", requestBody.Orientation))
+
+	orientationResp, err := appiumSetOrientation(device, requestBody.Orientation)
+	if err != nil {
+		interactionError(c, device, action, err)
+		return
+	}
+	writeInteractionResponse(c, device, orientationResp, action)
+}
+
+func DevicePinch(c *gin.Context) {
+	devicePinchOrZoom(c, false)
+}
+
+func DeviceZoom(c *gin.Context) {
+	devicePinchOrZoom(c, true)
+}
+
+func devicePinchOrZoom(c *gin.Context, zoom bool) {
+	device, _, ok := deviceAndDriver(c)
+	if !ok {
+		return
+	}
+
+	var requestBody models.PinchData
+	if err := json.NewDecoder(c.Request.Body).Decode(&requestBody); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if requestBody.Distance <= 0 {
+		c.String(http.StatusBadRequest, "Missing or invalid `distance`, provide the distance between the fingers")
+		return
+	}
+
+	gesture := "pinch"
+	if zoom {
+		gesture = "zoom"
+	}
+	action := fmt.Sprintf("%s at coordinates X:%.2f Y:%.2f", gesture, requestBody.X, requestBody.Y)
+	device.Logger.LogInfo("appium_interact", fmt.Sprintf("Performing %s with distance %.2f", action, requestBody.Distance))
+
+	pinchResp, err := appiumPinch(device, requestBody, zoom)
+	if err != nil {
+		interactionError(c, device, action, err)
+		return
+	}
+	writeInteractionResponse(c, device, pinchResp, action)
+}
+
+// Drag what is at the start coordinates and drop it at the end coordinates
+func DeviceDrag(c *gin.Context) {
+	device, _, ok := deviceAndDriver(c)
+	if !ok {
+		return
+	}
+
+	var requestBody models.ActionData
+	if err := json.NewDecoder(c.Request.Body).Decode(&requestBody); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	action := fmt.Sprintf("drag from X:%.3f Y:%.3f to X:%.3f Y:%.3f", requestBody.X, requestBody.Y, requestBody.EndX, requestBody.EndY)
+	device.Logger.LogInfo("appium_interact", fmt.Sprintf("Dragging from X:%.3f Y:%.3f to X:%.3f Y:%.3f", requestBody.X, requestBody.Y, requestBody.EndX, requestBody.EndY))
+
+	dragResp, err := appiumDrag(device, requestBody.X, requestBody.Y, requestBody.EndX, requestBody.EndY, requestBody.Duration)
+	if err != nil {
+		interactionError(c, device, action, err)
+		return
+	}
+	writeInteractionResponse(c, device, dragResp, action)
+}
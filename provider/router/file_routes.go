@@ -0,0 +1,148 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"GADS/common/models"
+	"GADS/provider/config"
+	"GADS/provider/devices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Respond to a failed file operation with the status that matches the error
+func fileError(c *gin.Context, device *models.Device, action string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, devices.ErrDeviceFileNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, devices.ErrDeviceFileTooLarge):
+		status = http.StatusRequestEntityTooLarge
+	case errors.Is(err, devices.ErrDeviceFileIsDir), errors.Is(err, devices.ErrAppFilesNotSupported):
+		status = http.StatusBadRequest
+	}
+	if status == http.StatusInternalServerError {
+		device.Logger.LogError("file_transfer", fmt.Sprintf("Failed to %s - %s", action, err))
+	} else {
+		device.Logger.LogWarn("file_transfer", fmt.Sprintf("Failed to %s - %s", action, err))
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
+
+// List a directory of the device storage, `path` defaults to the storage root and `app` selects an iOS app container
+func ListDeviceFiles(c *gin.Context) {
+	device, driver, ok := deviceAndDriver(c)
+	if !ok {
+		return
+	}
+
+	dir, err := devices.CleanDeviceFilePath(c.Query("path"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	app := c.Query("app")
+
+	files, err := driver.ListFiles(device, app, dir)
+	if err != nil {
+		fileError(c, device, fmt.Sprintf("list directory `%s`", dir), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"path": dir, "files": files})
+}
+
+// Upload a file from the `file` form field to `path` on the device
+// A `path` ending with a slash is a directory and the file keeps its name
+func PushDeviceFile(c *gin.Context) {
+	device, driver, ok := deviceAndDriver(c)
+	if !ok {
+		return
+	}
+
+	// Leave some room for the multipart encoding around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, devices.MaxDeviceFileSize+1<<20)
+	file, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": devices.ErrDeviceFileTooLarge.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("No file provided in form data - %s", err)})
+		return
+	}
+	if file.Size > devices.MaxDeviceFileSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": devices.ErrDeviceFileTooLarge.Error()})
+		return
+	}
+
+	targetPath := c.PostForm("path")
+	if targetPath == "" || strings.HasSuffix(targetPath, "/") {
+		targetPath += filepath.Base(file.Filename)
+	}
+	devicePath, err := devices.CleanDeviceFilePath(targetPath)
+	if err != nil || devicePath == "/" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid path `%s`", targetPath)})
+		return
+	}
+	app := c.PostForm("app")
+
+	localFile, err := os.CreateTemp(config.ProviderConfig().ProviderFolder, "push-*")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create temporary file - %s", err)})
+		return
+	}
+	localFile.Close()
+	defer os.Remove(localFile.Name())
+
+	err = c.SaveUploadedFile(file, localFile.Name())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to save uploaded file - %s", err)})
+		return
+	}
+
+	device.Logger.LogInfo("file_transfer", fmt.Sprintf("Pushing file `%s` (%d bytes)", devicePath, file.Size))
+	err = driver.PushFile(device, app, localFile.Name(), devicePath)
+	if err != nil {
+		fileError(c, device, fmt.Sprintf("push file `%s`", devicePath), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Pushed file `%s`", devicePath), "path": devicePath})
+}
+
+// Download the file at `path` from the device
+func PullDeviceFile(c *gin.Context) {
+	device, driver, ok := deviceAndDriver(c)
+	if !ok {
+		return
+	}
+
+	devicePath, err := devices.CleanDeviceFilePath(c.Query("path"))
+	if err != nil || devicePath == "/" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing or invalid `path`"})
+		return
+	}
+	app := c.Query("app")
+
+	localFile, err := os.CreateTemp(config.ProviderConfig().ProviderFolder, "pull-*")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create temporary file - %s", err)})
+		return
+	}
+	localFile.Close()
+	defer os.Remove(localFile.Name())
+
+	device.Logger.LogInfo("file_transfer", fmt.Sprintf("Pulling file `%s`", devicePath))
+	err = driver.PullFile(device, app, devicePath, localFile.Name())
+	if err != nil {
+		fileError(c, device, fmt.Sprintf("pull file `%s`", devicePath), err)
+		return
+	}
+	c.FileAttachment(localFile.Name(), path.Base(devicePath))
+}
@@ -17,6 +17,12 @@ func HandleRequests() *gin.Engine {
 	r.GET("/info", GetProviderData)
 	r.GET("/devices", DevicesInfo)
 	r.POST("/uploadFile", UploadAndInstallApp)
+	r.GET("/emulators", GetEmulators)
+	r.GET("/network-devices", GetNetworkDevices)
+	r.POST("/emulators/:avd/start", StartEmulator)
+	r.POST("/emulators/:avd/stop", StopEmulator)
+
+	r.GET("/debug/ports", GetPortAllocations)
 
 	pprofGroup := r.Group("/debug/pprof")
 	{
@@ -36,6 +42,8 @@ func HandleRequests() *gin.Engine {
 
 	deviceGroup := r.Group("/device/:udid")
 	deviceGroup.GET("/info", DeviceInfo)
+	deviceGroup.GET("/state", DeviceState)
+	deviceGroup.GET("/telemetry", DeviceTelemetry)
 	deviceGroup.GET("/apps", DeviceInstalledApps)
 	deviceGroup.GET("/health", DeviceHealth)
 	deviceGroup.POST("/tap", DeviceTap)
@@ -44,15 +52,25 @@ func HandleRequests() *gin.Engine {
 	deviceGroup.POST("/lock", DeviceLock)
 	deviceGroup.POST("/unlock", DeviceUnlock)
 	deviceGroup.POST("/screenshot", DeviceScreenshot)
+	deviceGroup.GET("/screenshot", DeviceDriverScreenshot)
 	deviceGroup.POST("/swipe", DeviceSwipe)
+	deviceGroup.POST("/actions", DeviceActions)
+	deviceGroup.GET("/actions/ws", DeviceActionsWS)
+	deviceGroup.POST("/drag", DeviceDrag)
+	deviceGroup.POST("/pinch", DevicePinch)
+	deviceGroup.POST("/zoom", DeviceZoom)
+	deviceGroup.POST("/pressKey", DevicePressKey)
+	deviceGroup.POST("/orientation", DeviceSetOrientation)
 	deviceGroup.GET("/appiumSource", DeviceAppiumSource)
 	deviceGroup.POST("/typeText", DeviceTypeText)
 	deviceGroup.POST("/clearText", DeviceClearText)
 	deviceGroup.GET("/getClipboard", DeviceGetClipboard)
+	deviceGroup.POST("/setClipboard", DeviceSetClipboard)
+	deviceGroup.POST("/paste", DevicePaste)
 	deviceGroup.Any("/appium/*proxyPath", AppiumReverseProxy)
 	deviceGroup.GET("/android-stream", AndroidStreamProxy)
 	deviceGroup.GET("/android-stream-mjpeg", AndroidStreamMJPEG)
-	if config.ProviderConfig.UseGadsIosStream {
+	if config.ProviderConfig().UseGadsIosStream {
 		deviceGroup.GET("/ios-stream", IosStreamProxyGADS)
 		deviceGroup.GET("/ios-stream-mjpeg", IOSStreamMJPEG)
 	} else {
@@ -60,6 +78,13 @@ func HandleRequests() *gin.Engine {
 		deviceGroup.GET("/ios-stream-mjpeg", IOSStreamMJPEGWda)
 	}
 	deviceGroup.POST("/uninstallApp", UninstallApp)
+	deviceGroup.POST("/app/launch", LaunchApp)
+	deviceGroup.POST("/app/terminate", TerminateApp)
+	deviceGroup.POST("/app/openUrl", OpenURL)
+	deviceGroup.GET("/app/foreground", ForegroundApp)
+	deviceGroup.GET("/files", ListDeviceFiles)
+	deviceGroup.POST("/files/push", PushDeviceFile)
+	deviceGroup.GET("/files/pull", PullDeviceFile)
 	deviceGroup.POST("/reset", ResetDevice)
 	deviceGroup.POST("/uploadAndInstallApp", UploadAndInstallApp)
 
@@ -6,6 +6,7 @@ import (
 	"GADS/provider/config"
 	"GADS/provider/devices"
 	"GADS/provider/logger"
+	"GADS/provider/providerutil"
 	"bytes"
 	"encoding/json"
 	"fmt"
@@ -15,6 +16,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
 	"slices"
 	"strings"
 
@@ -46,15 +48,27 @@ func AppiumReverseProxy(c *gin.Context) {
 	}()
 
 	udid := c.Param("udid")
-	device := devices.DBDeviceMap[udid]
+	device, _ := devices.Registry.Get(udid)
 
 	target := "http://localhost:" + device.AppiumPort
 	path := c.Param("proxyPath")
 
 	proxy := newAppiumProxy(target, path)
+	// Deleting the session ends it so the device can be cleaned up and the post-session hooks can run
+	// The hub keeps the device unavailable while the provider reports it is cleaning up, the header covers the time until the next device update
+	if c.Request.Method == http.MethodDelete && appiumSessionPath.MatchString(path) {
+		proxy.ModifyResponse = func(response *http.Response) error {
+			if response.StatusCode < 300 && devices.SessionEnded(device) {
+				response.Header.Set(models.DeviceCleanupHeader, "true")
+			}
+			return nil
+		}
+	}
 	proxy.ServeHTTP(c.Writer, c.Request)
 }
 
+var appiumSessionPath = regexp.MustCompile(`^/session/[^/]+/?$`)
+
 func newAppiumProxy(target string, path string) *httputil.ReverseProxy {
 	targetURL, _ := url.Parse(target)
 
@@ -71,7 +85,7 @@ func newAppiumProxy(target string, path string) *httputil.ReverseProxy {
 
 func UploadAndInstallApp(c *gin.Context) {
 	// Specify the upload directory
-	uploadDir := fmt.Sprintf("%s/", config.ProviderConfig.ProviderFolder)
+	uploadDir := fmt.Sprintf("%s/", config.ProviderConfig().ProviderFolder)
 
 	// Read the file from the form data
 	file, err := c.FormFile("file")
@@ -98,7 +112,7 @@ func UploadAndInstallApp(c *gin.Context) {
 
 	udid := c.Param("udid")
 	// Check if the target device is currently provisioned
-	if dev, ok := devices.DBDeviceMap[udid]; ok {
+	if dev, ok := devices.Registry.Get(udid); ok {
 		// If the uploaded file is not a zip archive
 		if ext != ".zip" {
 			// Create file destination based on the provider dir and file name
@@ -222,12 +236,12 @@ func UploadAndInstallApp(c *gin.Context) {
 func GetProviderData(c *gin.Context) {
 	var providerData models.ProviderData
 
-	deviceData := []models.Device{}
-	for _, device := range devices.DBDeviceMap {
-		deviceData = append(deviceData, *device)
+	deviceData := []*models.Device{}
+	for _, device := range devices.Registry.All() {
+		deviceData = append(deviceData, device.Snapshot())
 	}
 
-	providerData.ProviderData = *config.ProviderConfig
+	providerData.ProviderData = *config.ProviderConfig()
 	providerData.DeviceData = deviceData
 
 	c.JSON(http.StatusOK, providerData)
@@ -236,9 +250,9 @@ func GetProviderData(c *gin.Context) {
 func DeviceInfo(c *gin.Context) {
 	udid := c.Param("udid")
 
-	if dev, ok := devices.DBDeviceMap[udid]; ok {
+	if dev, ok := devices.Registry.Get(udid); ok {
 		devices.UpdateInstalledApps(dev)
-		dev.UsesCustomWDA = config.ProviderConfig.UseCustomWDA
+		dev.UsesCustomWDA = config.ProviderConfig().UseCustomWDA
 		c.JSON(http.StatusOK, dev)
 		return
 	}
@@ -246,30 +260,62 @@ func DeviceInfo(c *gin.Context) {
 	c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Did not find device with udid `%s`", udid)})
 }
 
-func DeviceInstalledApps(c *gin.Context) {
+// Latest telemetry of a device, its telemetry history and if it is out of rotation because of the thresholds
+func DeviceTelemetry(c *gin.Context) {
 	udid := c.Param("udid")
-	var installedApps []string
 
-	if dev, ok := devices.DBDeviceMap[udid]; ok {
-		if dev.OS == "ios" {
-			installedApps = devices.GetInstalledAppsIOS(dev)
-		} else {
-			installedApps = devices.GetInstalledAppsAndroid(dev)
-		}
-		c.JSON(http.StatusOK, installedApps)
+	if dev, ok := devices.Registry.Get(udid); ok {
+		dev.StateMu.RLock()
+		latest := dev.Telemetry
+		unhealthy := dev.Unhealthy
+		reasons := dev.UnhealthyReasons
+		dev.StateMu.RUnlock()
+
+		c.JSON(http.StatusOK, gin.H{
+			"latest":            latest,
+			"history":           dev.TelemetryHistory(),
+			"unhealthy":         unhealthy,
+			"unhealthy_reasons": reasons,
+			"thresholds":        config.ProviderConfig().TelemetryThresholds,
+		})
 		return
 	}
+
 	c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Did not find device with udid `%s`", udid)})
 }
 
-func DevicesInfo(c *gin.Context) {
-	deviceList := []*models.Device{}
+// Current provider state of a device and its latest state transitions
+func DeviceState(c *gin.Context) {
+	udid := c.Param("udid")
+
+	if dev, ok := devices.Registry.Get(udid); ok {
+		c.JSON(http.StatusOK, gin.H{
+			"state":   dev.State(),
+			"history": dev.StateHistory(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Did not find device with udid `%s`", udid)})
+}
 
-	for _, device := range devices.DBDeviceMap {
-		deviceList = append(deviceList, device)
+func DeviceInstalledApps(c *gin.Context) {
+	udid := c.Param("udid")
+
+	if dev, ok := devices.Registry.Get(udid); ok {
+		driver, err := devices.GetDeviceDriver(dev)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, driver.InstalledApps(dev))
+		return
 	}
+	c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Did not find device with udid `%s`", udid)})
+}
 
-	c.JSON(http.StatusOK, deviceList)
+func DevicesInfo(c *gin.Context) {
+	c.JSON(http.StatusOK, devices.Registry.All())
 }
 
 type ProcessApp struct {
@@ -279,7 +325,7 @@ type ProcessApp struct {
 func UninstallApp(c *gin.Context) {
 	udid := c.Param("udid")
 
-	if dev, ok := devices.DBDeviceMap[udid]; ok {
+	if dev, ok := devices.Registry.Get(udid); ok {
 		payload, err := io.ReadAll(c.Request.Body)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
@@ -293,14 +339,13 @@ func UninstallApp(c *gin.Context) {
 			return
 		}
 
-		var installedApps []string
-		if dev.OS == "ios" {
-			installedApps = devices.GetInstalledAppsIOS(dev)
-		} else {
-			installedApps = devices.GetInstalledAppsAndroid(dev)
+		driver, err := devices.GetDeviceDriver(dev)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
 		}
 
-		if slices.Contains(installedApps, payloadJson.App) {
+		if slices.Contains(driver.InstalledApps(dev), payloadJson.App) {
 			err = devices.UninstallApp(dev, payloadJson.App)
 			if err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to uninstall app `%s`", payloadJson.App)})
@@ -319,19 +364,12 @@ func UninstallApp(c *gin.Context) {
 func ResetDevice(c *gin.Context) {
 	udid := c.Param("udid")
 
-	if device, ok := devices.DBDeviceMap[udid]; ok {
-		if device.IsResetting {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Device setup is already being reset"})
-			return
-		}
-		if device.ProviderState != "live" {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Only devices in `live` state can be reset, current state is `" + device.ProviderState + "`"})
+	if device, ok := devices.Registry.Get(udid); ok {
+		err := devices.ResetDeviceSetup(device)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		device.IsResetting = true
-		device.CtxCancel()
-		device.ProviderState = "init"
-		device.IsResetting = false
 
 		c.JSON(http.StatusOK, gin.H{"message": "Initiate setup reset on device"})
 		return
@@ -339,3 +377,57 @@ func ResetDevice(c *gin.Context) {
 
 	c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Device with udid `%s` does not exist", udid)})
 }
+
+func GetEmulators(c *gin.Context) {
+	c.JSON(http.StatusOK, devices.GetEmulators())
+}
+
+func StartEmulator(c *gin.Context) {
+	avd := c.Param("avd")
+
+	err := devices.StartEmulator(avd)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Started emulator `%s`", avd)})
+}
+
+func StopEmulator(c *gin.Context) {
+	avd := c.Param("avd")
+
+	err := devices.StopEmulator(avd)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Stopped emulator `%s`", avd)})
+}
+
+func GetNetworkDevices(c *gin.Context) {
+	c.JSON(http.StatusOK, devices.GetNetworkDevices())
+}
+
+type portAllocationInfo struct {
+	providerutil.PortAllocation
+	Leaked bool `json:"leaked"` // the owner device is not set up or not provided anymore so the port should have been released
+}
+
+// Host ports allocated to devices with the configured port ranges
+func GetPortAllocations(c *gin.Context) {
+	allocations := []portAllocationInfo{}
+	for _, allocation := range providerutil.PortAllocations() {
+		device, ok := devices.Registry.Get(allocation.Owner)
+		allocations = append(allocations, portAllocationInfo{
+			PortAllocation: allocation,
+			Leaked:         !ok || device.State() == models.DeviceStateInit,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"port_ranges": config.ProviderConfig().PortRanges,
+		"allocations": allocations,
+	})
+}
@@ -0,0 +1,68 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"GADS/common/models"
+	"GADS/provider/config"
+	"GADS/provider/devices"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetProviderDataConcurrentWithStateChanges(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config.SetProviderConfig(&models.Provider{Nickname: "test"})
+	device := &models.Device{UDID: "provider-data-test", OS: "android"}
+	devices.Registry.Add(device)
+	defer devices.Registry.Remove(device.UDID)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	writers := []func(i int){
+		func(i int) {
+			device.TransitionState(models.DeviceStatePreparing, "test")
+			device.TransitionState(models.DeviceStateInit, "test")
+		},
+		func(i int) { device.StartSetupStep(fmt.Sprintf("step-%d", i)) },
+		func(i int) { device.AddTelemetry(models.DeviceTelemetry{BatteryLevel: i % 100}, nil) },
+		func(i int) { device.SetInstalledApps([]string{fmt.Sprintf("app-%d", i)}) },
+	}
+	for _, write := range writers {
+		wg.Add(1)
+		go func(write func(i int)) {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				default:
+					write(i)
+				}
+			}
+		}(write)
+	}
+
+	for i := 0; i < 200; i++ {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		GetProviderData(c)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", recorder.Code)
+		}
+		var providerData models.ProviderData
+		if err := json.Unmarshal(recorder.Body.Bytes(), &providerData); err != nil {
+			t.Fatalf("Provider data is not valid JSON - %s", err)
+		}
+		if len(providerData.DeviceData) != 1 || providerData.DeviceData[0].UDID != device.UDID {
+			t.Fatalf("Expected the registered device in the provider data, got %+v", providerData.DeviceData)
+		}
+	}
+	close(done)
+	wg.Wait()
+}
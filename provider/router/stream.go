@@ -10,10 +10,10 @@ import (
 	"mime/multipart"
 	"net"
 	"net/http"
-	"net/url"
 	"os"
 	"strings"
 
+	"GADS/common/models"
 	"GADS/provider/devices"
 
 	"github.com/gin-gonic/gin"
@@ -21,9 +21,18 @@ import (
 	"github.com/gobwas/ws/wsutil"
 )
 
+// Get the local URL the device screen stream is served on by its driver
+func deviceStreamSource(device *models.Device) (string, error) {
+	driver, err := devices.GetDeviceDriver(device)
+	if err != nil {
+		return "", err
+	}
+	return driver.StreamSource(device), nil
+}
+
 func AndroidStreamProxy(c *gin.Context) {
 	udid := c.Param("udid")
-	device := devices.DBDeviceMap[udid]
+	device, _ := devices.Registry.Get(udid)
 
 	conn, _, _, err := ws.UpgradeHTTP(c.Request, c.Writer)
 	if err != nil {
@@ -32,8 +41,12 @@ func AndroidStreamProxy(c *gin.Context) {
 	}
 	defer conn.Close()
 
-	u := url.URL{Scheme: "ws", Host: "localhost:" + device.StreamPort, Path: ""}
-	destConn, _, _, err := ws.DefaultDialer.Dial(context.Background(), u.String())
+	streamSource, err := deviceStreamSource(device)
+	if err != nil {
+		logger.ProviderLogger.LogError("AndroidStreamProxy", err.Error())
+		return
+	}
+	destConn, _, _, err := ws.DefaultDialer.Dial(context.Background(), streamSource)
 	if err != nil {
 		logger.ProviderLogger.LogError("AndroidStreamProxy", fmt.Sprintf("Failed connecting to device `%s` stream port - %s", device.UDID, err))
 		return
@@ -63,10 +76,14 @@ func AndroidStreamMJPEG(c *gin.Context) {
 	c.Deadline()
 
 	udid := c.Param("udid")
-	device := devices.DBDeviceMap[udid]
+	device, _ := devices.Registry.Get(udid)
 
-	u := url.URL{Scheme: "ws", Host: "localhost:" + device.StreamPort, Path: ""}
-	conn, _, _, err := ws.DefaultDialer.Dial(context.Background(), u.String())
+	streamSource, err := deviceStreamSource(device)
+	if err != nil {
+		logger.ProviderLogger.LogError("AndroidStreamProxy", err.Error())
+		return
+	}
+	conn, _, _, err := ws.DefaultDialer.Dial(context.Background(), streamSource)
 	if err != nil {
 		logger.ProviderLogger.LogError("AndroidStreamProxy", fmt.Sprintf("Failed connecting to device `%s` stream port - %s", device.UDID, err))
 		return
@@ -113,10 +130,15 @@ func IOSStreamMJPEG(c *gin.Context) {
 	c.Deadline()
 
 	udid := c.Param("udid")
-	device := devices.DBDeviceMap[udid]
+	device, _ := devices.Registry.Get(udid)
 
 	// Read data from device
-	server := "localhost:" + device.StreamPort
+	streamSource, err := deviceStreamSource(device)
+	if err != nil {
+		logger.ProviderLogger.LogError("ios_stream", err.Error())
+		return
+	}
+	server := strings.TrimPrefix(streamSource, "tcp://")
 	// Connect to the server
 	conn, err := net.Dial("tcp", server)
 	if err != nil {
@@ -168,7 +190,7 @@ func IOSStreamMJPEG(c *gin.Context) {
 
 func IOSStreamMJPEGWda(c *gin.Context) {
 	udid := c.Param("udid")
-	device := devices.DBDeviceMap[udid]
+	device, _ := devices.Registry.Get(udid)
 
 	// Set the necessary headers for MJPEG streaming
 	// Note: The "boundary" is arbitrary but must be unique and consistent.
@@ -176,7 +198,11 @@ func IOSStreamMJPEGWda(c *gin.Context) {
 	c.Writer.WriteHeader(http.StatusOK)
 	c.Deadline()
 
-	streamUrl := "http://localhost:" + device.WDAStreamPort
+	streamUrl, err := deviceStreamSource(device)
+	if err != nil {
+		logger.ProviderLogger.LogError("ios_stream", err.Error())
+		return
+	}
 
 	req, err := http.NewRequest("GET", streamUrl, nil)
 	if err != nil {
@@ -240,7 +266,7 @@ func IOSStreamMJPEGWda(c *gin.Context) {
 
 func IosStreamProxyGADS(c *gin.Context) {
 	udid := c.Param("udid")
-	device := devices.DBDeviceMap[udid]
+	device, _ := devices.Registry.Get(udid)
 	jpegChannel := make(chan []byte, 15)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -253,7 +279,12 @@ func IosStreamProxyGADS(c *gin.Context) {
 	}
 
 	// Read data from device
-	server := "localhost:" + device.StreamPort
+	streamSource, err := deviceStreamSource(device)
+	if err != nil {
+		logger.ProviderLogger.LogError("ios_stream", err.Error())
+		return
+	}
+	server := strings.TrimPrefix(streamSource, "tcp://")
 	// Connect to the server
 	conn, err := net.Dial("tcp", server)
 	if err != nil {
@@ -327,7 +358,7 @@ func IosStreamProxyGADS(c *gin.Context) {
 
 func IosStreamProxyWDA(c *gin.Context) {
 	udid := c.Param("udid")
-	device := devices.DBDeviceMap[udid]
+	device, _ := devices.Registry.Get(udid)
 
 	conn, _, _, err := ws.UpgradeHTTP(c.Request, c.Writer)
 	if err != nil {
@@ -335,7 +366,11 @@ func IosStreamProxyWDA(c *gin.Context) {
 	}
 	defer conn.Close()
 
-	streamUrl := "http://localhost:" + device.WDAStreamPort
+	streamUrl, err := deviceStreamSource(device)
+	if err != nil {
+		logger.ProviderLogger.LogError("ios_stream", err.Error())
+		return
+	}
 
 	req, err := http.NewRequest("GET", streamUrl, nil)
 	if err != nil {